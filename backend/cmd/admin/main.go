@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/internal/service"
+	"github.com/45ai/backend/pkg/database"
+	"github.com/45ai/backend/pkg/fieldcrypt"
+	"github.com/45ai/backend/pkg/jwtkeys"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  create-admin     Create an admin API account: -username <name> -roles <role,...>
+                   The password is read from ADMIN_PASSWORD or stdin.
+                   Roles: superadmin, operator, finance, moderator
+  revoke-sessions  Log a user out of every device: -user <id>
+  ban              Ban a user and revoke all of their sessions: -user <id>
+  unban            Lift a ban; the user has to log in again: -user <id>
+
+Day-to-day moderation should go through the admin API. These commands are for
+bootstrapping the first admin and for emergencies, and are audited as "cli.<command>".
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	userID := flags.Int64("user", 0, "ID of the user to act on")
+	username := flags.String("username", "", "username of the admin to create")
+	roles := flags.String("roles", "", "comma separated roles of the admin to create")
+	flags.Parse(os.Args[2:])
+
+	switch command {
+	case "create-admin":
+		if *username == "" || *roles == "" {
+			fmt.Print(usage)
+			os.Exit(2)
+		}
+	case "revoke-sessions", "ban", "unban":
+		if *userID <= 0 {
+			fmt.Print(usage)
+			os.Exit(2)
+		}
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	// Connect to database
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	piiCipher, err := fieldcrypt.New(cfg.PII)
+	if err != nil {
+		log.Fatal("Failed to initialize PII encryption:", err)
+	}
+	keySet, err := jwtkeys.NewKeySet(cfg.JWT.Keys, cfg.JWT.SigningKeyID)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+
+	// Initialize services
+	userRepo := repository.NewUserRepository(db.DB, piiCipher)
+	revocationRepo := repository.NewRevocationRepository(db.DB)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
+	sessionService := service.NewSessionService(cfg.Session, revocationRepo, refreshTokenRepo, userRepo)
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(db.DB))
+	adminService := service.NewAdminService(cfg.Admin, cfg.JWT, keySet, repository.NewAdminRepository(db.DB), auditService)
+
+	ctx := context.Background()
+	entry := &model.AuditLog{Action: "cli." + command, StatusCode: http.StatusOK, IP: "cli"}
+
+	if command == "create-admin" {
+		req := &model.AdminCreateRequest{Username: *username, Password: readPassword()}
+		for _, role := range strings.Split(*roles, ",") {
+			req.Roles = append(req.Roles, model.AdminRole(strings.TrimSpace(role)))
+		}
+		admin, err := adminService.CreateAdmin(ctx, req)
+		if err != nil {
+			log.Fatalf("Failed to create admin %q: %v", *username, err)
+		}
+
+		entry.TargetType, entry.TargetID = "admins", strconv.FormatInt(admin.ID, 10)
+		entry.Details, _ = json.Marshal(map[string]interface{}{"username": admin.Username, "roles": admin.Roles})
+		audit(ctx, auditService, entry)
+		log.Printf("Created admin %q with ID %d", admin.Username, admin.ID)
+		return
+	}
+
+	if _, err := userRepo.GetByID(ctx, *userID); err != nil {
+		log.Fatalf("User %d not found: %v", *userID, err)
+	}
+
+	switch command {
+	case "revoke-sessions":
+		err = sessionService.RevokeAllForUser(ctx, *userID)
+	case "ban":
+		err = sessionService.BanUser(ctx, *userID)
+	case "unban":
+		err = sessionService.UnbanUser(ctx, *userID)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s user %d: %v", command, *userID, err)
+	}
+
+	entry.TargetType, entry.TargetID = "users", strconv.FormatInt(*userID, 10)
+	audit(ctx, auditService, entry)
+	log.Printf("Completed %s for user %d", command, *userID)
+}
+
+// readPassword takes the password from ADMIN_PASSWORD, falling back to the first line of stdin
+func readPassword() string {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal("Failed to read password:", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func audit(ctx context.Context, auditService service.AuditService, entry *model.AuditLog) {
+	if err := auditService.Record(ctx, entry); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -13,9 +13,15 @@ import (
 	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/handler"
 	"github.com/45ai/backend/internal/middleware"
+	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
 	"github.com/45ai/backend/internal/service"
+	"github.com/45ai/backend/pkg/blobstore"
 	"github.com/45ai/backend/pkg/database"
+	"github.com/45ai/backend/pkg/fieldcrypt"
+	"github.com/45ai/backend/pkg/jwtkeys"
+	"github.com/45ai/backend/pkg/ratelimit"
+	"github.com/45ai/backend/pkg/secretbox"
 	"github.com/gin-gonic/gin"
 )
 
@@ -39,8 +45,39 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Load JWT signing and verification keys
+	keySet, err := jwtkeys.NewKeySet(cfg.JWT.Keys, cfg.JWT.SigningKeyID)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+
+	// WeChat session keys are stored sealed, and not at all without WeChat login
+	var sessionKeyBox *secretbox.Box
+	if cfg.WeChat.SessionKeySecret != "" {
+		sessionKeyBox, err = secretbox.New(cfg.WeChat.SessionKeySecret)
+		if err != nil {
+			log.Fatal("Failed to initialize session key encryption:", err)
+		}
+	}
+
+	// PII columns are encrypted at rest
+	piiCipher, err := fieldcrypt.New(cfg.PII)
+	if err != nil {
+		log.Fatal("Failed to initialize PII encryption:", err)
+	}
+
+	// Uploaded and generated media
+	blobStore, err := blobstore.NewLocalStore(cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	// Background jobs run until the server shuts down
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	// Initialize Gin router with dependencies
-	router := setupRouter(cfg, db)
+	router := setupRouter(bgCtx, cfg, db, keySet, sessionKeyBox, piiCipher, blobStore)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -64,6 +101,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopBackground()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -76,13 +114,16 @@ func main() {
 	log.Println("Server exiting")
 }
 
-func setupRouter(cfg *config.Config, db *database.DB) *gin.Engine {
+func setupRouter(ctx context.Context, cfg *config.Config, db *database.DB, keySet *jwtkeys.KeySet, sessionKeyBox *secretbox.Box, piiCipher *fieldcrypt.Cipher, blobStore blobstore.Store) *gin.Engine {
 	// Set Gin mode based on environment
 	if cfg.App.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Apply global middleware
 	router.Use(gin.Recovery())
@@ -112,59 +153,262 @@ func setupRouter(cfg *config.Config, db *database.DB) *gin.Engine {
 		})
 	})
 
+	// Media stored on local disk; per-user files need a signed URL
+	mediaHandler := handler.NewMediaHandler(blobStore, blobstore.NewSigner(cfg.Storage))
+	router.GET("/media/*key", mediaHandler.Serve)
+	router.HEAD("/media/*key", mediaHandler.Serve)
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.DB)
-	wechatRepo := repository.NewWechatRepository(cfg.WeChat)
+	userRepo := repository.NewUserRepository(db.DB, piiCipher)
+	wechatTokenRepo := repository.NewWechatAccessTokenRepository(db.DB)
+	wechatRepo := repository.NewWechatRepository(cfg.WeChat, nil, wechatTokenRepo)
 	templateRepo := repository.NewTemplateRepository(db.DB)
+	categoryRepo := repository.NewTemplateCategoryRepository(db.DB)
+	templateStatsRepo := repository.NewTemplateStatsRepository(db.DB)
 	transactionRepo := repository.NewTransactionRepository(db.DB)
-	comfyuiRepo := repository.NewMockComfyUIRepository()
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
+	revocationRepo := repository.NewRevocationRepository(db.DB)
+	subscriptionRepo := repository.NewSubscriptionRepository(db.DB)
+	pricingRuleRepo := repository.NewPricingRuleRepository(db.DB)
+	generationJobRepo := repository.NewGenerationJobRepository(db.DB)
+	idempotencyRepo := repository.NewIdempotencyRepository(db.DB)
+	adminRepo := repository.NewAdminRepository(db.DB)
+	auditLogRepo := repository.NewAuditLogRepository(db.DB)
+	comfyuiRepo := repository.NewComfyUIRepository(cfg.ComfyUI, nil)
+	comfyuiNodeReportRepo := repository.NewComfyUINodeReportRepository(db.DB)
+
+	// Rate limits are per instance unless they are shared through MySQL
+	rateLimitStore := ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Backend == "mysql" {
+		mysqlStore := ratelimit.NewMySQLStore(db.DB)
+		rateLimitStore = mysqlStore
+		go purgeRateLimitBuckets(ctx, mysqlStore, longestPeriod(cfg.RateLimit.Auth, cfg.RateLimit.Generate))
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(cfg.JWT, userRepo, wechatRepo)
-	templateService := service.NewTemplateService(templateRepo)
-	userService := service.NewUserService(userRepo)
+	authService := service.NewAuthService(cfg.JWT, keySet, sessionKeyBox, userRepo, wechatRepo, refreshTokenRepo)
+	sessionService := service.NewSessionService(cfg.Session, revocationRepo, refreshTokenRepo, userRepo)
+	pricingService := service.NewPricingService(cfg.Pricing, pricingRuleRepo, templateRepo, userRepo, transactionRepo)
+	templateService := service.NewTemplateService(cfg.Template, templateRepo, categoryRepo, templateStatsRepo, userRepo, generationJobRepo, pricingService, blobStore)
+	categoryService := service.NewCategoryService(categoryRepo)
 	transactionService := service.NewTransactionService(transactionRepo)
+	subscriptionService := service.NewSubscriptionService(cfg.WeChat, subscriptionRepo)
 	contentSafetyService := service.NewMockContentSafetyService()
-	queueService := service.NewInMemoryQueueService()
-	generationService := service.NewGenerationService(contentSafetyService, userRepo, transactionRepo, templateRepo, comfyuiRepo)
+	if cfg.WeChat.AppID != "" {
+		contentSafetyService = service.NewWechatContentSafetyService(wechatRepo, contentSafetyService)
+	}
+	userService := service.NewUserService(userRepo, cfg.User, cfg.WeChat, sessionKeyBox, contentSafetyService)
+	accountService := service.NewAccountService(userRepo, transactionRepo, subscriptionRepo, subscriptionService, sessionService, blobStore)
+	auditService := service.NewAuditService(auditLogRepo)
+	adminService := service.NewAdminService(cfg.Admin, cfg.JWT, keySet, adminRepo, auditService)
+	queueService := service.NewQueueService(cfg.Queue, generationJobRepo)
+	idempotencyService := service.NewIdempotencyService(cfg.Idempotency, idempotencyRepo)
+	generationService := service.NewGenerationService(cfg.Queue, cfg.Timeouts, cfg.ComfyUI, contentSafetyService, templateService, queueService, userRepo, transactionRepo, templateRepo, generationJobRepo, comfyuiRepo, comfyuiNodeReportRepo, templateStatsRepo, blobStore)
+
+	// Start background jobs
+	go purgeRevokedTokens(ctx, sessionService)
+	go purgeIdempotencyKeys(ctx, idempotencyService)
+	go refreshTemplateStats(ctx, templateService, cfg.Template.StatsRefreshInterval)
+	if cfg.WeChat.AppID != "" {
+		go wechatRepo.RunTokenRefresher(ctx)
+	}
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, sessionService)
 	templateHandler := handler.NewTemplateHandler(templateService)
-	userHandler := handler.NewUserHandler(userService, transactionService)
-	generationHandler := handler.NewGenerationHandler(generationService, queueService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	pricingHandler := handler.NewPricingHandler(pricingService)
+	userHandler := handler.NewUserHandler(userService, transactionService, accountService)
+	generationHandler := handler.NewGenerationHandler(generationService)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	adminHandler := handler.NewAdminHandler(adminService, auditService, userService, sessionService)
 
 	// Initialize middleware
-	authMiddleware := middleware.AuthMiddleware(authService)
+	authMiddleware := middleware.AuthMiddleware(authService, sessionService)
+	optionalAuthMiddleware := middleware.OptionalAuthMiddleware(authService, sessionService)
+	idempotencyMiddleware := middleware.IdempotencyMiddleware(idempotencyService)
+	authRateLimit := middleware.RateLimitMiddleware(rateLimitStore, cfg.RateLimit.Auth)
+	generateRateLimit := middleware.RateLimitMiddleware(rateLimitStore, cfg.RateLimit.Generate)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/login", authRateLimit, authHandler.Login)
+			auth.POST("/refresh", authRateLimit, authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
 		}
 
 		templates := v1.Group("/templates")
 		{
-			templates.GET("", templateHandler.GetAll)
-			templates.GET("/:id", templateHandler.GetByID)
+			templates.GET("", optionalAuthMiddleware, templateHandler.GetAll)
+			templates.GET("/:id", optionalAuthMiddleware, templateHandler.GetByID)
+			templates.GET("/:id/eligibility", authMiddleware, templateHandler.GetEligibility)
 		}
+		v1.GET("/template-categories", categoryHandler.List)
 
 		me := v1.Group("/me")
 		me.Use(authMiddleware)
 		{
 			me.GET("", userHandler.GetProfile)
 			me.PUT("", userHandler.UpdateProfile)
+			me.DELETE("", userHandler.DeleteAccount)
+			me.GET("/export", userHandler.ExportData)
+			me.POST("/wechat-profile", userHandler.SyncWechatProfile)
 			me.GET("/transactions", userHandler.GetTransactions)
+			me.GET("/subscriptions", subscriptionHandler.GetSubscriptions)
+			me.POST("/subscriptions", subscriptionHandler.RecordGrants)
 		}
 
 		generation := v1.Group("/generate")
 		generation.Use(authMiddleware)
 		{
-			generation.POST("", generationHandler.GenerateImage)
+			generation.POST("", generateRateLimit, idempotencyMiddleware, generationHandler.GenerateImage)
+			generation.GET("/:request_id", generationHandler.GetStatus)
+			generation.DELETE("/:request_id", generationHandler.Cancel)
+			generation.POST("/:request_id/regenerate", generateRateLimit, idempotencyMiddleware, generationHandler.Regenerate)
+		}
+
+		// Admin API, for back-office accounts only; WeChat user tokens are rejected
+		v1.POST("/admin/auth/login", authRateLimit, adminHandler.Login)
+
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AdminAuthMiddleware(adminService), middleware.AuditMiddleware(auditService))
+		{
+			admin.GET("/me", adminHandler.Me)
+
+			admins := admin.Group("/admins", middleware.RequireRole(model.AdminRoleSuperAdmin))
+			{
+				admins.GET("", adminHandler.ListAdmins)
+				admins.POST("", adminHandler.CreateAdmin)
+				admins.PATCH("/:id", adminHandler.UpdateAdmin)
+			}
+
+			users := admin.Group("/users", middleware.RequireRole(model.AdminRoleModerator))
+			{
+				users.GET("/:id", adminHandler.GetUser)
+				users.POST("/:id/ban", adminHandler.BanUser)
+				users.POST("/:id/unban", adminHandler.UnbanUser)
+				users.POST("/:id/revoke-sessions", adminHandler.RevokeUserSessions)
+			}
+
+			adminTemplates := admin.Group("/templates", middleware.RequireRole(model.AdminRoleOperator))
+			{
+				adminTemplates.GET("", templateHandler.AdminList)
+				adminTemplates.POST("", templateHandler.Create)
+				adminTemplates.PUT("/order", templateHandler.Reorder)
+				adminTemplates.PATCH("/:id", templateHandler.Update)
+				adminTemplates.DELETE("/:id", templateHandler.Delete)
+				adminTemplates.POST("/:id/activate", templateHandler.Activate)
+				adminTemplates.POST("/:id/deactivate", templateHandler.Deactivate)
+				adminTemplates.PUT("/:id/schedule", templateHandler.SetSchedule)
+				adminTemplates.POST("/:id/preview", templateHandler.UploadPreview)
+			}
+
+			categories := admin.Group("/template-categories", middleware.RequireRole(model.AdminRoleOperator))
+			{
+				categories.GET("", categoryHandler.List)
+				categories.POST("", categoryHandler.Create)
+				categories.PUT("/:id", categoryHandler.Update)
+				categories.DELETE("/:id", categoryHandler.Delete)
+			}
+
+			pricingRules := admin.Group("/pricing-rules", middleware.RequireRole(model.AdminRoleFinance))
+			{
+				pricingRules.GET("", pricingHandler.ListRules)
+				pricingRules.POST("", pricingHandler.CreateRule)
+				pricingRules.PUT("/:id", pricingHandler.UpdateRule)
+				pricingRules.DELETE("/:id", pricingHandler.DeleteRule)
+			}
+			admin.PUT("/users/:id/membership", middleware.RequireRole(model.AdminRoleFinance), pricingHandler.SetMembership)
+
+			admin.GET("/comfyui/nodes", middleware.RequireRole(model.AdminRoleOperator), generationHandler.ListBackends)
+			admin.GET("/generations/stats", middleware.RequireRole(model.AdminRoleOperator), generationHandler.GetLatencyStats)
+
+			admin.GET("/audit-logs", middleware.RequireRole(model.AdminRoleSuperAdmin), adminHandler.ListAuditLogs)
 		}
 	}
 
 	return router
-} 
\ No newline at end of file
+} 
+
+// purgeRevokedTokens periodically drops denylist entries for tokens that have expired anyway
+func purgeRevokedTokens(ctx context.Context, sessionService service.SessionService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sessionService.PurgeExpired(ctx); err != nil {
+				log.Printf("Failed to purge revoked tokens: %v", err)
+			}
+		}
+	}
+}
+
+// purgeIdempotencyKeys periodically drops Idempotency-Key responses past their TTL
+func purgeIdempotencyKeys(ctx context.Context, idempotencyService service.IdempotencyService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := idempotencyService.PurgeExpired(ctx); err != nil {
+				log.Printf("Failed to purge idempotency keys: %v", err)
+			}
+		}
+	}
+}
+
+// purgeRateLimitBuckets periodically drops shared rate limit buckets idle for
+// longer than idle; such buckets have refilled and would start full anyway
+func purgeRateLimitBuckets(ctx context.Context, store *ratelimit.MySQLStore, idle time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.PurgeIdle(ctx, idle); err != nil {
+				log.Printf("Failed to purge rate limit buckets: %v", err)
+			}
+		}
+	}
+}
+
+// longestPeriod returns the longest refill period of the given policies
+func longestPeriod(policies ...ratelimit.Policy) time.Duration {
+	var longest time.Duration
+	for _, policy := range policies {
+		if policy.Limit.Period > longest {
+			longest = policy.Limit.Period
+		}
+	}
+	return longest
+}
+
+// refreshTemplateStats periodically rolls up template usage for popular and trending sorting
+func refreshTemplateStats(ctx context.Context, templateService service.TemplateService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := templateService.RefreshUsageStats(ctx); err != nil {
+				log.Printf("Failed to refresh template stats: %v", err)
+			}
+		}
+	}
+}
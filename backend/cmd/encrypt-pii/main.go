@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/database"
+	"github.com/45ai/backend/pkg/fieldcrypt"
+)
+
+// encrypt-pii encrypts user PII written before field encryption was enabled and
+// re-encrypts values under retired master keys. It is safe to run repeatedly and
+// alongside the API: rows changed while a batch is in flight are left for the next run.
+func main() {
+	batchSize := flag.Int("batch", 500, "rows per batch")
+	pause := flag.Duration("pause", 100*time.Millisecond, "pause between batches")
+	decrypt := flag.Bool("decrypt", false, "write plaintext back, e.g. before rolling back migration 012")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	// Connect to database
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	piiCipher, err := fieldcrypt.New(cfg.PII)
+	if err != nil {
+		log.Fatal("Failed to initialize PII encryption:", err)
+	}
+	userRepo := repository.NewUserRepository(db.DB, piiCipher)
+
+	ctx := context.Background()
+	var afterID int64
+	total := 0
+	for {
+		lastID, updated, err := userRepo.MigratePIIBatch(ctx, afterID, *batchSize, *decrypt)
+		if err != nil {
+			log.Fatalf("Failed after user %d: %v", afterID, err)
+		}
+		if lastID == afterID {
+			break
+		}
+		total += updated
+		log.Printf("Processed users %d-%d, rewrote %d", afterID+1, lastID, updated)
+		afterID = lastID
+		time.Sleep(*pause)
+	}
+
+	log.Printf("Done, rewrote %d users", total)
+}
@@ -50,7 +50,7 @@ func seedTemplates(repo repository.TemplateRepository) {
 			continue
 		}
 
-		if err := repo.Create(ctx, &t); err != nil {
+		if err := repo.Create(ctx, &t, nil, nil); err != nil {
 			log.Printf("Failed to seed template %s: %v", t.Name, err)
 		} else {
 			log.Printf("Seeded template: %s", t.Name)
@@ -4,46 +4,190 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/45ai/backend/pkg/blobstore"
 	"github.com/45ai/backend/pkg/database"
+	"github.com/45ai/backend/pkg/fieldcrypt"
+	"github.com/45ai/backend/pkg/jwtkeys"
+	"github.com/45ai/backend/pkg/ratelimit"
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration
 type Config struct {
-	App      AppConfig
-	Database database.Config
-	JWT      JWTConfig
-	WeChat   WeChatConfig
-	External ExternalConfig
-	Payment  PaymentConfig
+	App         AppConfig
+	Database    database.Config
+	JWT         JWTConfig
+	Session     SessionConfig
+	Admin       AdminConfig
+	User        UserConfig
+	Template    TemplateConfig
+	Pricing     PricingConfig
+	Queue       QueueConfig
+	Timeouts    GenerationTimeoutConfig
+	Idempotency IdempotencyConfig
+	RateLimit   RateLimitConfig
+	Storage     blobstore.Config
+	PII         fieldcrypt.Config
+	WeChat      WeChatConfig
+	ComfyUI     ComfyUIConfig
+	External    ExternalConfig
+	Payment     PaymentConfig
 }
 
 // AppConfig holds application-specific configuration
 type AppConfig struct {
 	Environment string
 	Port        int
+	// TrustedProxies may set X-Forwarded-For; the client IP of other requests
+	// is their remote address, so IP rate limits cannot be bypassed
+	TrustedProxies []string
 }
 
 // JWTConfig holds JWT-related configuration
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Keys          []jwtkeys.KeyConfig
+	SigningKeyID  string
+	Issuer        string
+	Audience      string
+	Leeway        time.Duration
+	Expiry        time.Duration
+	RefreshExpiry time.Duration
+}
+
+// SessionConfig holds session revocation configuration
+type SessionConfig struct {
+	RevocationCacheTTL  time.Duration
+	RevocationCacheSize int
+}
+
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	// Audience of admin tokens; it must differ from JWT_AUDIENCE so user
+	// tokens are never accepted by the admin API and vice versa
+	Audience    string
+	TokenExpiry time.Duration
+}
+
+// UserConfig holds user profile configuration
+type UserConfig struct {
+	// AvatarAllowedHosts lists hosts avatar URLs may point at. A leading dot
+	// also allows subdomains, e.g. ".qlogo.cn".
+	AvatarAllowedHosts []string
+}
+
+// TemplateConfig holds template catalog configuration
+type TemplateConfig struct {
+	// StatsRefreshInterval is how often usage counters and trending scores are recomputed
+	StatsRefreshInterval time.Duration
+	// TrendingHalfLife is how long it takes a use to count half as much towards trending
+	TrendingHalfLife time.Duration
+	// UsageRetention is how long hourly usage is kept; at least 7 days
+	UsageRetention time.Duration
+	// DailyGenerationLimit caps generations per user per day; 0 disables the limit
+	DailyGenerationLimit int
+}
+
+// PricingConfig holds template pricing configuration
+type PricingConfig struct {
+	// NewUserPeriod is how long after sign-up new user pricing rules apply
+	NewUserPeriod time.Duration
+}
+
+// QueueConfig holds generation queue configuration
+type QueueConfig struct {
+	// MaxInFlightPerUser caps the generations one user has processing at once; 0 disables the cap
+	MaxInFlightPerUser int
+	// MaxAttempts is how many times a job is tried before it fails for good
+	MaxAttempts int
+	// RetryBaseDelay is the wait before the first retry; it doubles with each attempt up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// CancelPollInterval is how often a worker checks whether its job was cancelled
+	CancelPollInterval time.Duration
+	// JobLease is how long a claimed job belongs to its worker without being
+	// renewed. Workers renew it while they process the job; jobs of a worker
+	// that stopped are retried or failed once it runs out.
+	JobLease time.Duration
+}
+
+// GenerationTimeoutConfig bounds each stage of a generation. Together they
+// should fit within SLATarget, the end-to-end latency jobs are measured against.
+type GenerationTimeoutConfig struct {
+	Validation time.Duration
+	Safety     time.Duration
+	Upload     time.Duration
+	// QueueWait is how long a job may wait for a worker before it fails and is refunded
+	QueueWait time.Duration
+	// Render bounds the ComfyUI render of one variant
+	Render    time.Duration
+	SLATarget time.Duration
+}
+
+// IdempotencyConfig holds Idempotency-Key configuration
+type IdempotencyConfig struct {
+	// TTL is how long a key and its response are kept for replay
+	TTL time.Duration
+	// LockTimeout is how long a request may hold a key before a retry can take
+	// it over, in case the request never finished. It must outlast the slowest request.
+	LockTimeout time.Duration
+}
+
+// RateLimitConfig holds request rate limiting configuration
+type RateLimitConfig struct {
+	// Backend is "memory" for per-instance limits or "mysql" to share limits between instances
+	Backend string
+	// Auth limits login and token refresh per client IP
+	Auth ratelimit.Policy
+	// Generate limits generation requests per user
+	Generate ratelimit.Policy
 }
 
 // WeChatConfig holds WeChat-related configuration
 type WeChatConfig struct {
-	AppID     string
-	AppSecret string
+	AppID              string
+	AppSecret          string
+	SessionKeySecret   string
+	APIBaseURL         string
+	Timeout            time.Duration
+	TokenRefreshBefore time.Duration
+
+	// Subscribe message templates sent when a generation finishes
+	NotifyTemplateCompleted string
+	NotifyTemplateFailed    string
+	NotifyPage              string
+	NotifyState             string
+}
+
+// ComfyUIConfig holds the ComfyUI backend pool configuration
+type ComfyUIConfig struct {
+	// Mock renders placeholder images instead of calling the nodes. It is
+	// required until templates carry ComfyUI workflows.
+	Mock   bool
+	Nodes  []ComfyUINodeConfig
+	APIKey string
+	// Timeout bounds health probes and interrupts, not renders
+	Timeout             time.Duration
+	HealthCheckInterval time.Duration
+	// A node that fails FailureThreshold times in a row is ejected for EjectDuration
+	FailureThreshold int
+	EjectDuration    time.Duration
+	// Workers publish their view of the nodes every ReportInterval for the admin API
+	ReportInterval time.Duration
+}
+
+// ComfyUINodeConfig is a ComfyUI backend and how many renders it runs at once
+type ComfyUINodeConfig struct {
+	URL      string
+	Capacity int
 }
 
 // ExternalConfig holds external service configuration
 type ExternalConfig struct {
 	ContentSafetyAPIKey string
 	ContentSafetyAPIURL string
-	ComfyUIAPIURL       string
-	ComfyUIAPIKey       string
 }
 
 // PaymentConfig holds payment-related configuration
@@ -70,6 +214,7 @@ func Load() (*Config, error) {
 	// App configuration
 	cfg.App.Environment = getEnv("APP_ENV", "development")
 	cfg.App.Port = getEnvInt("PORT", 8080)
+	cfg.App.TrustedProxies = getEnvList("TRUSTED_PROXIES", []string{"127.0.0.1", "::1"})
 
 	// Database configuration
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
@@ -83,21 +228,184 @@ func Load() (*Config, error) {
 	cfg.Database.ConnMaxIdleTime = getEnvDuration("DB_CONN_MAX_IDLE_TIME", 10*time.Minute)
 
 	// JWT configuration
-	cfg.JWT.Secret = getEnv("JWT_SECRET", "")
-	if cfg.JWT.Secret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
+	// JWT_SECRET is kept as the "default" HS256 key so existing deployments keep working
+	if secret := getEnv("JWT_SECRET", ""); secret != "" {
+		cfg.JWT.Keys = append(cfg.JWT.Keys, jwtkeys.KeyConfig{ID: "default", Algorithm: jwtkeys.AlgorithmHS256, Secret: secret})
+	}
+	keys, err := parseJWTKeys(getEnv("JWT_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.JWT.Keys = append(cfg.JWT.Keys, keys...)
+	if len(cfg.JWT.Keys) == 0 {
+		return nil, fmt.Errorf("JWT_SECRET or JWT_KEYS is required")
+	}
+	cfg.JWT.SigningKeyID = getEnv("JWT_SIGNING_KEY_ID", cfg.JWT.Keys[0].ID)
+	cfg.JWT.Issuer = getEnv("JWT_ISSUER", "45ai")
+	cfg.JWT.Audience = getEnv("JWT_AUDIENCE", "45ai-api")
+	cfg.JWT.Leeway = getEnvDuration("JWT_LEEWAY", 30*time.Second)
+	cfg.JWT.Expiry = getEnvDuration("JWT_EXPIRY", 15*time.Minute)
+	cfg.JWT.RefreshExpiry = getEnvDuration("JWT_REFRESH_EXPIRY", 30*24*time.Hour)
+
+	// Session configuration
+	cfg.Session.RevocationCacheTTL = getEnvDuration("REVOCATION_CACHE_TTL", 5*time.Second)
+	cfg.Session.RevocationCacheSize = getEnvInt("REVOCATION_CACHE_SIZE", 10000)
+
+	// Admin configuration
+	cfg.Admin.Audience = getEnv("ADMIN_JWT_AUDIENCE", "45ai-admin")
+	if cfg.Admin.Audience == cfg.JWT.Audience {
+		return nil, fmt.Errorf("ADMIN_JWT_AUDIENCE must differ from JWT_AUDIENCE")
+	}
+	cfg.Admin.TokenExpiry = getEnvDuration("ADMIN_TOKEN_EXPIRY", 8*time.Hour)
+
+	// User profile configuration
+	cfg.User.AvatarAllowedHosts = getEnvList("AVATAR_ALLOWED_HOSTS", []string{"thirdwx.qlogo.cn", "wx.qlogo.cn"})
+
+	// Template configuration
+	cfg.Template.StatsRefreshInterval = getEnvDuration("TEMPLATE_STATS_REFRESH_INTERVAL", 5*time.Minute)
+	cfg.Template.TrendingHalfLife = getEnvDuration("TEMPLATE_TRENDING_HALF_LIFE", 24*time.Hour)
+	if cfg.Template.TrendingHalfLife <= 0 {
+		return nil, fmt.Errorf("TEMPLATE_TRENDING_HALF_LIFE must be positive")
+	}
+	cfg.Template.UsageRetention = getEnvDuration("TEMPLATE_USAGE_RETENTION", 30*24*time.Hour)
+	if cfg.Template.UsageRetention < 7*24*time.Hour {
+		return nil, fmt.Errorf("TEMPLATE_USAGE_RETENTION must be at least 168h")
+	}
+	cfg.Template.DailyGenerationLimit = getEnvInt("GENERATION_DAILY_LIMIT", 50)
+	if cfg.Template.DailyGenerationLimit < 0 {
+		return nil, fmt.Errorf("GENERATION_DAILY_LIMIT must not be negative")
+	}
+
+	// Pricing configuration
+	cfg.Pricing.NewUserPeriod = getEnvDuration("PRICING_NEW_USER_PERIOD", 7*24*time.Hour)
+
+	// Queue configuration
+	cfg.Queue.MaxInFlightPerUser = getEnvInt("GENERATION_MAX_IN_FLIGHT_PER_USER", 1)
+	if cfg.Queue.MaxInFlightPerUser < 0 {
+		return nil, fmt.Errorf("GENERATION_MAX_IN_FLIGHT_PER_USER must not be negative")
+	}
+	cfg.Queue.MaxAttempts = getEnvInt("GENERATION_MAX_ATTEMPTS", 3)
+	if cfg.Queue.MaxAttempts < 1 {
+		return nil, fmt.Errorf("GENERATION_MAX_ATTEMPTS must be at least 1")
+	}
+	cfg.Queue.RetryBaseDelay = getEnvDuration("GENERATION_RETRY_BASE_DELAY", 10*time.Second)
+	cfg.Queue.RetryMaxDelay = getEnvDuration("GENERATION_RETRY_MAX_DELAY", 5*time.Minute)
+	if cfg.Queue.RetryBaseDelay <= 0 || cfg.Queue.RetryMaxDelay < cfg.Queue.RetryBaseDelay {
+		return nil, fmt.Errorf("GENERATION_RETRY_BASE_DELAY must be positive and at most GENERATION_RETRY_MAX_DELAY")
+	}
+	cfg.Queue.CancelPollInterval = getEnvDuration("GENERATION_CANCEL_POLL_INTERVAL", 2*time.Second)
+	if cfg.Queue.CancelPollInterval <= 0 {
+		return nil, fmt.Errorf("GENERATION_CANCEL_POLL_INTERVAL must be positive")
+	}
+	cfg.Queue.JobLease = getEnvDuration("GENERATION_JOB_LEASE", time.Minute)
+	if cfg.Queue.JobLease < 3*time.Second {
+		return nil, fmt.Errorf("GENERATION_JOB_LEASE must be at least 3s")
+	}
+
+	// Generation timeout configuration
+	cfg.Timeouts.Validation = getEnvDuration("GENERATION_TIMEOUT_VALIDATION", 2*time.Second)
+	cfg.Timeouts.Safety = getEnvDuration("GENERATION_TIMEOUT_SAFETY", 5*time.Second)
+	cfg.Timeouts.Upload = getEnvDuration("GENERATION_TIMEOUT_UPLOAD", 5*time.Second)
+	cfg.Timeouts.QueueWait = getEnvDuration("GENERATION_TIMEOUT_QUEUE_WAIT", 2*time.Minute)
+	cfg.Timeouts.Render = getEnvDuration("GENERATION_TIMEOUT_RENDER", 25*time.Second)
+	cfg.Timeouts.SLATarget = getEnvDuration("GENERATION_SLA_TARGET", 30*time.Second)
+	if cfg.Timeouts.Validation <= 0 || cfg.Timeouts.Safety <= 0 || cfg.Timeouts.Upload <= 0 ||
+		cfg.Timeouts.QueueWait <= 0 || cfg.Timeouts.Render <= 0 || cfg.Timeouts.SLATarget <= 0 {
+		return nil, fmt.Errorf("GENERATION_TIMEOUT_* and GENERATION_SLA_TARGET must be positive")
+	}
+
+	// Idempotency configuration
+	cfg.Idempotency.TTL = getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	cfg.Idempotency.LockTimeout = getEnvDuration("IDEMPOTENCY_LOCK_TIMEOUT", 5*time.Minute)
+	if cfg.Idempotency.TTL <= 0 || cfg.Idempotency.LockTimeout <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_TTL and IDEMPOTENCY_LOCK_TIMEOUT must be positive")
+	}
+
+	// Rate limit configuration
+	cfg.RateLimit.Backend = getEnv("RATE_LIMIT_BACKEND", "memory")
+	if cfg.RateLimit.Backend != "memory" && cfg.RateLimit.Backend != "mysql" {
+		return nil, fmt.Errorf("RATE_LIMIT_BACKEND must be memory or mysql")
+	}
+	cfg.RateLimit.Auth, err = getEnvPolicy("RATE_LIMIT_AUTH", "auth", ratelimit.KeyByIP, "20/1m")
+	if err != nil {
+		return nil, err
+	}
+	cfg.RateLimit.Generate, err = getEnvPolicy("RATE_LIMIT_GENERATE", "generate", ratelimit.KeyByUser, "10/1m")
+	if err != nil {
+		return nil, err
+	}
+	// Behind a load balancer the default would put every client in one auth bucket
+	if cfg.App.Environment == "production" && os.Getenv("TRUSTED_PROXIES") == "" && !cfg.RateLimit.Auth.Limit.IsZero() {
+		return nil, fmt.Errorf("TRUSTED_PROXIES must be set in production when RATE_LIMIT_AUTH is enabled")
+	}
+
+	// PII encryption configuration
+	cfg.PII.MasterKeys, err = parseMasterKeys(getEnv("PII_MASTER_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.PII.MasterKeys) == 0 {
+		return nil, fmt.Errorf("PII_MASTER_KEYS is required")
+	}
+	cfg.PII.CurrentVersion = getEnvInt("PII_MASTER_KEY_VERSION", cfg.PII.MasterKeys[len(cfg.PII.MasterKeys)-1].Version)
+	cfg.PII.BlindIndexKey = getEnv("PII_BLIND_INDEX_KEY", "")
+	if cfg.PII.BlindIndexKey == "" {
+		return nil, fmt.Errorf("PII_BLIND_INDEX_KEY is required")
 	}
-	cfg.JWT.Expiry = getEnvDuration("JWT_EXPIRY", 24*time.Hour)
+
+	// Storage configuration
+	cfg.Storage.LocalDir = getEnv("STORAGE_LOCAL_DIR", "./storage")
+	cfg.Storage.PublicURL = getEnv("STORAGE_PUBLIC_URL", fmt.Sprintf("http://localhost:%d/media", cfg.App.Port))
+	cfg.Storage.SigningKey = getEnv("STORAGE_URL_SIGNING_KEY", "")
+	cfg.Storage.SignedURLTTL = getEnvDuration("STORAGE_SIGNED_URL_TTL", time.Hour)
 
 	// WeChat configuration
 	cfg.WeChat.AppID = getEnv("WECHAT_APP_ID", "")
 	cfg.WeChat.AppSecret = getEnv("WECHAT_APP_SECRET", "")
+	cfg.WeChat.SessionKeySecret = getEnv("WECHAT_SESSION_KEY_SECRET", "")
+	if cfg.WeChat.AppID != "" && cfg.WeChat.SessionKeySecret == "" {
+		return nil, fmt.Errorf("WECHAT_SESSION_KEY_SECRET is required when WECHAT_APP_ID is set")
+	}
+	cfg.WeChat.APIBaseURL = getEnv("WECHAT_API_BASE_URL", "https://api.weixin.qq.com")
+	cfg.WeChat.Timeout = getEnvDuration("WECHAT_API_TIMEOUT", 5*time.Second)
+	cfg.WeChat.TokenRefreshBefore = getEnvDuration("WECHAT_TOKEN_REFRESH_BEFORE", 5*time.Minute)
+	cfg.WeChat.NotifyTemplateCompleted = getEnv("WECHAT_NOTIFY_TEMPLATE_COMPLETED", "")
+	cfg.WeChat.NotifyTemplateFailed = getEnv("WECHAT_NOTIFY_TEMPLATE_FAILED", "")
+	cfg.WeChat.NotifyPage = getEnv("WECHAT_NOTIFY_PAGE", "pages/generate/index")
+	cfg.WeChat.NotifyState = getEnv("WECHAT_NOTIFY_STATE", "formal")
 
 	// External services
 	cfg.External.ContentSafetyAPIKey = getEnv("CONTENT_SAFETY_API_KEY", "")
 	cfg.External.ContentSafetyAPIURL = getEnv("CONTENT_SAFETY_API_URL", "")
-	cfg.External.ComfyUIAPIURL = getEnv("COMFYUI_API_URL", "http://localhost:8188")
-	cfg.External.ComfyUIAPIKey = getEnv("COMFYUI_API_KEY", "")
+
+	// ComfyUI configuration
+	cfg.ComfyUI.Mock = getEnv("COMFYUI_MOCK", "true") == "true"
+	if !cfg.ComfyUI.Mock {
+		// Every render would fail, and be charged and refunded, until they do
+		return nil, fmt.Errorf("COMFYUI_MOCK must be true: templates have no ComfyUI workflows to render yet")
+	}
+	cfg.ComfyUI.Nodes, err = parseComfyUINodes(
+		getEnv("COMFYUI_NODES", getEnv("COMFYUI_API_URL", "http://localhost:8188")),
+		getEnvInt("COMFYUI_NODE_CAPACITY", 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ComfyUI.APIKey = getEnv("COMFYUI_API_KEY", "")
+	cfg.ComfyUI.Timeout = getEnvDuration("COMFYUI_TIMEOUT", 5*time.Second)
+	cfg.ComfyUI.HealthCheckInterval = getEnvDuration("COMFYUI_HEALTH_CHECK_INTERVAL", 10*time.Second)
+	if cfg.ComfyUI.HealthCheckInterval <= 0 {
+		return nil, fmt.Errorf("COMFYUI_HEALTH_CHECK_INTERVAL must be positive")
+	}
+	cfg.ComfyUI.FailureThreshold = getEnvInt("COMFYUI_FAILURE_THRESHOLD", 3)
+	if cfg.ComfyUI.FailureThreshold < 1 {
+		return nil, fmt.Errorf("COMFYUI_FAILURE_THRESHOLD must be at least 1")
+	}
+	cfg.ComfyUI.EjectDuration = getEnvDuration("COMFYUI_EJECT_DURATION", 30*time.Second)
+	cfg.ComfyUI.ReportInterval = getEnvDuration("COMFYUI_REPORT_INTERVAL", 10*time.Second)
+	if cfg.ComfyUI.ReportInterval <= 0 {
+		return nil, fmt.Errorf("COMFYUI_REPORT_INTERVAL must be positive")
+	}
 
 	// Payment configuration
 	cfg.Payment.WeChatPayMerchantID = getEnv("WECHAT_PAY_MERCHANT_ID", "")
@@ -107,6 +415,79 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// parseJWTKeys parses a comma separated list of kid:algorithm:value entries.
+// The value is the shared secret for HS256 and a PEM file path otherwise.
+func parseJWTKeys(value string) ([]jwtkeys.KeyConfig, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var keys []jwtkeys.KeyConfig
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:algorithm:value", entry)
+		}
+		key := jwtkeys.KeyConfig{ID: parts[0], Algorithm: parts[1]}
+		if key.Algorithm == jwtkeys.AlgorithmHS256 {
+			key.Secret = parts[2]
+		} else {
+			key.File = parts[2]
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseMasterKeys parses a comma separated list of version:secret entries
+func parseMasterKeys(value string) ([]fieldcrypt.MasterKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var keys []fieldcrypt.MasterKey
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid PII_MASTER_KEYS entry, expected version:secret")
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII_MASTER_KEYS version %q", parts[0])
+		}
+		keys = append(keys, fieldcrypt.MasterKey{Version: version, Secret: parts[1]})
+	}
+	return keys, nil
+}
+
+// parseComfyUINodes parses a comma separated list of url or url=capacity entries.
+// Entries without a capacity get defaultCapacity.
+func parseComfyUINodes(value string, defaultCapacity int) ([]ComfyUINodeConfig, error) {
+	var nodes []ComfyUINodeConfig
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		node := ComfyUINodeConfig{URL: entry, Capacity: defaultCapacity}
+		if i := strings.LastIndex(entry, "="); i >= 0 {
+			capacity, err := strconv.Atoi(entry[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid COMFYUI_NODES capacity in %q", entry)
+			}
+			node.URL, node.Capacity = entry[:i], capacity
+		}
+		if node.URL == "" || node.Capacity < 1 {
+			return nil, fmt.Errorf("invalid COMFYUI_NODES entry %q, expected url=capacity", entry)
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("COMFYUI_NODES must list at least one node")
+	}
+	return nodes, nil
+}
+
 // Helper functions for environment variables
 
 func getEnv(key, defaultValue string) string {
@@ -125,6 +506,19 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -132,4 +526,12 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 		}
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+func getEnvPolicy(key, name, keyBy, defaultValue string) (ratelimit.Policy, error) {
+	limit, err := ratelimit.ParseLimit(getEnv(key, defaultValue))
+	if err != nil {
+		return ratelimit.Policy{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return ratelimit.Policy{Name: name, KeyBy: keyBy, Limit: limit}, nil
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler interface {
+	Login(c *gin.Context)
+	Me(c *gin.Context)
+	ListAdmins(c *gin.Context)
+	CreateAdmin(c *gin.Context)
+	UpdateAdmin(c *gin.Context)
+	GetUser(c *gin.Context)
+	BanUser(c *gin.Context)
+	UnbanUser(c *gin.Context)
+	RevokeUserSessions(c *gin.Context)
+	ListAuditLogs(c *gin.Context)
+}
+
+type adminHandlerImpl struct {
+	adminService   service.AdminService
+	auditService   service.AuditService
+	userService    service.UserService
+	sessionService service.SessionService
+}
+
+// NewAdminHandler creates a new instance of AdminHandler
+func NewAdminHandler(adminService service.AdminService, auditService service.AuditService, userService service.UserService, sessionService service.SessionService) AdminHandler {
+	return &adminHandlerImpl{
+		adminService:   adminService,
+		auditService:   auditService,
+		userService:    userService,
+		sessionService: sessionService,
+	}
+}
+
+func (h *adminHandlerImpl) Login(c *gin.Context) {
+	var req model.AdminLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.adminService.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAdminCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAdminDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *adminHandlerImpl) Me(c *gin.Context) {
+	admin, _ := c.Get("admin")
+	c.JSON(http.StatusOK, admin)
+}
+
+func (h *adminHandlerImpl) ListAdmins(c *gin.Context) {
+	admins, err := h.adminService.ListAdmins(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list admins"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"admins": admins})
+}
+
+func (h *adminHandlerImpl) CreateAdmin(c *gin.Context) {
+	var req model.AdminCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := h.adminService.CreateAdmin(c.Request.Context(), &req)
+	if err != nil {
+		h.adminError(c, err, "failed to create admin")
+		return
+	}
+	c.JSON(http.StatusCreated, admin)
+}
+
+func (h *adminHandlerImpl) UpdateAdmin(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin ID"})
+		return
+	}
+
+	var req model.AdminUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := h.adminService.UpdateAdmin(c.Request.Context(), c.GetInt64("adminID"), id, &req)
+	if err != nil {
+		h.adminError(c, err, "failed to update admin")
+		return
+	}
+	c.JSON(http.StatusOK, admin)
+}
+
+func (h *adminHandlerImpl) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve user"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *adminHandlerImpl) BanUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok || !h.userExists(c, userID) {
+		return
+	}
+	if err := h.sessionService.BanUser(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to ban user"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *adminHandlerImpl) UnbanUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok || !h.userExists(c, userID) {
+		return
+	}
+	if err := h.sessionService.UnbanUser(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unban user"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *adminHandlerImpl) RevokeUserSessions(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok || !h.userExists(c, userID) {
+		return
+	}
+	if err := h.sessionService.RevokeAllForUser(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *adminHandlerImpl) ListAuditLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	filter := model.AuditLogFilter{
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	if value := c.Query("admin_id"); value != "" {
+		adminID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin_id"})
+			return
+		}
+		filter.AdminID = &adminID
+	}
+
+	entries, err := h.auditService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit logs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audit_logs": entries})
+}
+
+// adminError maps admin service errors to responses
+func (h *adminHandlerImpl) adminError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+	case errors.Is(err, service.ErrAdminExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAdminSelfLockout):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "admin not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// userExists checks that the user exists, writing a 404 if it does not
+func (h *adminHandlerImpl) userExists(c *gin.Context, userID int64) bool {
+	if _, err := h.userService.GetUserByID(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve user"})
+		return false
+	}
+	return true
+}
+
+// userIDParam parses the :id route parameter as a user ID, writing a 400 if it is invalid
+func userIDParam(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return 0, false
+	}
+	return userID, true
+}
@@ -1,20 +1,26 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/45ai/backend/internal/repository"
 	"github.com/45ai/backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 type authHandlerImpl struct {
-	authService service.AuthService
+	authService    service.AuthService
+	sessionService service.SessionService
 }
 
 // NewAuthHandler creates a new instance of AuthHandler
-func NewAuthHandler(authService service.AuthService) AuthHandler {
+func NewAuthHandler(authService service.AuthService, sessionService service.SessionService) AuthHandler {
 	return &authHandlerImpl{
-		authService: authService,
+		authService:    authService,
+		sessionService: sessionService,
 	}
 }
 
@@ -25,26 +31,78 @@ func (h *authHandlerImpl) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.authService.LoginWithWechat(c.Request.Context(), req.Code)
+	user, tokens, err := h.authService.LoginWithWechat(c.Request.Context(), req.Code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrUserBanned) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if status, ok := wechatErrorStatus(err); ok {
+			if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+				c.Header("Retry-After", "1")
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
 		return
 	}
 
 	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
 	})
 }
 
 func (h *authHandlerImpl) Refresh(c *gin.Context) {
-	// Implementation for a future task
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUserBanned) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (h *authHandlerImpl) Logout(c *gin.Context) {
-	// Implementation for a future task
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	// Also deny the access token the client is still holding, if it sent one
+	if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "Bearer" {
+		if claims, err := h.authService.ParseToken(c.Request.Context(), parts[1]); err == nil {
+			if err := h.sessionService.RevokeToken(c.Request.Context(), claims); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+				return
+			}
+		}
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (h *authHandlerImpl) GetProfile(c *gin.Context) {
@@ -52,6 +110,27 @@ func (h *authHandlerImpl) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
 }
 
+// wechatErrorStatus maps WeChat API failures to the HTTP status returned to the client
+func wechatErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, repository.ErrWechatInvalidCode):
+		return http.StatusUnauthorized, true
+	case errors.Is(err, repository.ErrWechatRateLimited):
+		return http.StatusTooManyRequests, true
+	case errors.Is(err, repository.ErrWechatBusy):
+		return http.StatusServiceUnavailable, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, true
+	case errors.Is(err, repository.ErrWechatUnavailable):
+		return http.StatusBadGateway, true
+	}
+	var wechatErr *repository.WechatError
+	if errors.As(err, &wechatErr) {
+		return http.StatusBadGateway, true
+	}
+	return 0, false
+}
+
 // AuthHandler defines the interface for authentication HTTP handlers
 type AuthHandler interface {
 	// Login handles user login requests
@@ -74,11 +153,13 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  interface{} `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    int64       `json:"expires_in"`
+	User         interface{} `json:"user"`
 }
 
-// RefreshRequest represents the token refresh request
+// RefreshRequest represents the token refresh and logout request
 type RefreshRequest struct {
-	Token string `json:"token" binding:"required"`
+	RefreshToken string `json:"refresh_token" binding:"required"`
 } 
\ No newline at end of file
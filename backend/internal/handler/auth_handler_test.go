@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/45ai/backend/internal/repository"
+)
+
+func TestWechatErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		ok     bool
+	}{
+		{name: "invalid code", err: &repository.WechatError{Code: 40029}, status: http.StatusUnauthorized, ok: true},
+		{name: "used code", err: &repository.WechatError{Code: 40163}, status: http.StatusUnauthorized, ok: true},
+		{name: "rate limited", err: &repository.WechatError{Code: 45011}, status: http.StatusTooManyRequests, ok: true},
+		{name: "busy", err: &repository.WechatError{Code: -1}, status: http.StatusServiceUnavailable, ok: true},
+		{name: "other errcode", err: &repository.WechatError{Code: 40125}, status: http.StatusBadGateway, ok: true},
+		{
+			name:   "client timeout",
+			err:    fmt.Errorf("%w: %w", repository.ErrWechatUnavailable, context.DeadlineExceeded),
+			status: http.StatusGatewayTimeout,
+			ok:     true,
+		},
+		{name: "unreachable", err: fmt.Errorf("%w: connection refused", repository.ErrWechatUnavailable), status: http.StatusBadGateway, ok: true},
+		{name: "wrapped", err: fmt.Errorf("failed to log in: %w", &repository.WechatError{Code: 45011}), status: http.StatusTooManyRequests, ok: true},
+		{name: "not a wechat error", err: errors.New("database is down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ok := wechatErrorStatus(tt.err)
+			if status != tt.status || ok != tt.ok {
+				t.Errorf("wechatErrorStatus() = %d, %v, want %d, %v", status, ok, tt.status, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type categoryHandlerImpl struct {
+	service service.CategoryService
+}
+
+// NewCategoryHandler creates a new instance of CategoryHandler
+func NewCategoryHandler(service service.CategoryService) CategoryHandler {
+	return &categoryHandlerImpl{service: service}
+}
+
+func (h *categoryHandlerImpl) List(c *gin.Context) {
+	categories, err := h.service.ListCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list categories"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+func (h *categoryHandlerImpl) Create(c *gin.Context) {
+	var req model.TemplateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.CreateCategory(c.Request.Context(), &req)
+	if err != nil {
+		categoryError(c, err, "failed to create category")
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+func (h *categoryHandlerImpl) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	var req model.TemplateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.service.UpdateCategory(c.Request.Context(), id, &req)
+	if err != nil {
+		categoryError(c, err, "failed to update category")
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *categoryHandlerImpl) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	if err := h.service.DeleteCategory(c.Request.Context(), id); err != nil {
+		categoryError(c, err, "failed to delete category")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// categoryError maps category service errors to responses
+func categoryError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+	case errors.Is(err, service.ErrCategorySlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,25 +1,33 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
-	"io"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
 )
 
 type GenerationHandler interface {
 	GenerateImage(c *gin.Context)
+	GetStatus(c *gin.Context)
+	Regenerate(c *gin.Context)
+	Cancel(c *gin.Context)
+	ListBackends(c *gin.Context)
+	GetLatencyStats(c *gin.Context)
 }
 
 type generationHandlerImpl struct {
-	service      service.GenerationService
-	queueService service.QueueService
+	service service.GenerationService
 }
 
-func NewGenerationHandler(service service.GenerationService, queueService service.QueueService) GenerationHandler {
-	return &generationHandlerImpl{service: service, queueService: queueService}
+func NewGenerationHandler(service service.GenerationService) GenerationHandler {
+	return &generationHandlerImpl{service: service}
 }
 
 func (h *generationHandlerImpl) GenerateImage(c *gin.Context) {
@@ -35,6 +43,23 @@ func (h *generationHandlerImpl) GenerateImage(c *gin.Context) {
 		return
 	}
 
+	// variants defaults to the number of seeds, or 1; seeds may be repeated once per variant
+	var opts model.GenerationOptions
+	if value := c.PostForm("variants"); value != "" {
+		if opts.Variants, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variants", "field": "variants"})
+			return
+		}
+	}
+	for _, value := range c.PostFormArray("seeds") {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid seeds", "field": "seeds"})
+			return
+		}
+		opts.Seeds = append(opts.Seeds, seed)
+	}
+
 	file, err := c.FormFile("image")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "image file is required"})
@@ -55,16 +80,113 @@ func (h *generationHandlerImpl) GenerateImage(c *gin.Context) {
 		return
 	}
 
-	job := &service.Job{
-		UserID:     userID.(int64),
-		TemplateID: templateID,
-		ImageData:  imageDataBytes,
+	job, err := h.service.Submit(c.Request.Context(), userID.(int64), templateID, imageDataBytes, opts)
+	if err != nil {
+		generationError(c, err, "failed to add job to queue")
+		return
 	}
+	c.JSON(http.StatusAccepted, job)
+}
 
-	if err := h.queueService.AddJob(c.Request.Context(), job); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add job to queue"})
+func (h *generationHandlerImpl) GetStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{"request_id": "some-request-id"}) // Placeholder
-} 
\ No newline at end of file
+	job, err := h.service.GetGenerationStatus(c.Request.Context(), userID.(int64), c.Param("request_id"))
+	if err != nil {
+		generationError(c, err, "failed to get generation")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *generationHandlerImpl) Regenerate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	// An empty body renders one variant with a new random seed
+	var opts model.GenerationOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	job, err := h.service.Regenerate(c.Request.Context(), userID.(int64), c.Param("request_id"), opts)
+	if err != nil {
+		generationError(c, err, "failed to add job to queue")
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *generationHandlerImpl) Cancel(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	job, err := h.service.Cancel(c.Request.Context(), userID.(int64), c.Param("request_id"))
+	if err != nil {
+		generationError(c, err, "failed to cancel generation")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *generationHandlerImpl) ListBackends(c *gin.Context) {
+	nodes, err := h.service.ListBackends(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list comfyui nodes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes})
+}
+
+func (h *generationHandlerImpl) GetLatencyStats(c *gin.Context) {
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil || window <= 0 || window > 30*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+		return
+	}
+
+	report, err := h.service.GetLatencyStats(c.Request.Context(), window)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get latency stats"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// generationError maps generation service errors to responses
+func generationError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	var ineligibleErr *service.IneligibleError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+	case errors.As(err, &ineligibleErr):
+		c.JSON(http.StatusForbidden, gin.H{"error": "template cannot be used", "reasons": ineligibleErr.Eligibility.Reasons})
+	case errors.Is(err, service.ErrImageNotSafe):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrGenerationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrGenerationFinished), errors.Is(err, service.ErrPriceChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	case service.IsRetryable(err):
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service is busy, please try again"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
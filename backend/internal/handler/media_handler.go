@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/45ai/backend/pkg/blobstore"
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler interface {
+	Serve(c *gin.Context)
+}
+
+type mediaHandlerImpl struct {
+	store  blobstore.Store
+	signer *blobstore.Signer
+}
+
+// NewMediaHandler serves stored objects. Template media is public; files under
+// a user's prefix need the signature from their URL.
+func NewMediaHandler(store blobstore.Store, signer *blobstore.Signer) MediaHandler {
+	return &mediaHandlerImpl{store: store, signer: signer}
+}
+
+func (h *mediaHandlerImpl) Serve(c *gin.Context) {
+	// Clean the key the way the store will, so "./users/..." is not taken for public
+	key := strings.TrimPrefix(path.Clean("/"+c.Param("key")), "/")
+	if blobstore.IsPrivate(key) && !h.signer.Verify(key, c.Request.URL.Query()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired media url"})
+		return
+	}
+
+	object, err := h.store.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid media key"})
+		return
+	}
+	defer object.Close()
+
+	// Local objects are files, which can be served with ranges and modification times
+	var modTime time.Time
+	if stater, ok := object.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		info, err := stater.Stat()
+		if err != nil || info.IsDir() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+			return
+		}
+		modTime = info.ModTime()
+	}
+
+	if blobstore.IsPrivate(key) {
+		c.Header("Cache-Control", "private, no-store")
+	}
+	seeker, ok := object.(io.ReadSeeker)
+	if !ok {
+		c.Status(http.StatusOK)
+		io.Copy(c.Writer, object)
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, path.Base(key), modTime, seeker)
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PricingHandler interface {
+	ListRules(c *gin.Context)
+	CreateRule(c *gin.Context)
+	UpdateRule(c *gin.Context)
+	DeleteRule(c *gin.Context)
+	SetMembership(c *gin.Context)
+}
+
+type pricingHandlerImpl struct {
+	service service.PricingService
+}
+
+// NewPricingHandler creates a new instance of PricingHandler
+func NewPricingHandler(service service.PricingService) PricingHandler {
+	return &pricingHandlerImpl{service: service}
+}
+
+func (h *pricingHandlerImpl) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pricing rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *pricingHandlerImpl) CreateRule(c *gin.Context) {
+	var req model.PricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), &req)
+	if err != nil {
+		pricingError(c, err, "failed to create pricing rule")
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *pricingHandlerImpl) UpdateRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pricing rule ID"})
+		return
+	}
+
+	var req model.PricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), id, &req)
+	if err != nil {
+		pricingError(c, err, "failed to update pricing rule")
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *pricingHandlerImpl) DeleteRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pricing rule ID"})
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), id); err != nil {
+		pricingError(c, err, "failed to delete pricing rule")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *pricingHandlerImpl) SetMembership(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.SetMembership(c.Request.Context(), userID, req.MemberUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set membership"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// pricingError maps pricing service errors to responses
+func pricingError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "pricing rule not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
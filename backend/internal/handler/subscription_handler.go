@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionHandler interface {
+	GetSubscriptions(c *gin.Context)
+	RecordGrants(c *gin.Context)
+}
+
+type subscriptionHandlerImpl struct {
+	service service.SubscriptionService
+}
+
+func NewSubscriptionHandler(service service.SubscriptionService) SubscriptionHandler {
+	return &subscriptionHandlerImpl{service: service}
+}
+
+func (h *subscriptionHandlerImpl) GetSubscriptions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	subscriptions, err := h.service.GetSubscriptions(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+func (h *subscriptionHandlerImpl) RecordGrants(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req model.SubscriptionGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscriptions, err := h.service.RecordGrants(c.Request.Context(), userID.(int64), req.Results)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record subscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
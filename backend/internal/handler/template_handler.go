@@ -1,16 +1,32 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+// maxPreviewSize caps uploaded template preview images
+const maxPreviewSize = 5 << 20
+
 type TemplateHandler interface {
 	GetAll(c *gin.Context)
 	GetByID(c *gin.Context)
+	GetEligibility(c *gin.Context)
+	AdminList(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Activate(c *gin.Context)
+	Deactivate(c *gin.Context)
+	Delete(c *gin.Context)
+	SetSchedule(c *gin.Context)
+	Reorder(c *gin.Context)
+	UploadPreview(c *gin.Context)
 }
 
 type templateHandlerImpl struct {
@@ -22,7 +38,34 @@ func NewTemplateHandler(service service.TemplateService) TemplateHandler {
 }
 
 func (h *templateHandlerImpl) GetAll(c *gin.Context) {
-	templates, err := h.service.GetAllTemplates(c.Request.Context())
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	sort := c.Query("sort")
+	switch sort {
+	case model.TemplateSortDefault, model.TemplateSortPopular, model.TemplateSortTrending, model.TemplateSortNew:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort, expected popular, trending or new"})
+		return
+	}
+
+	filter := model.TemplateFilter{
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+		Query:    c.Query("q"),
+		Sort:     sort,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	// Signed-in users see their own prices; userID is 0 for anonymous visitors
+	templates, err := h.service.GetAllTemplates(c.Request.Context(), c.GetInt64("userID"), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -37,10 +80,218 @@ func (h *templateHandlerImpl) GetByID(c *gin.Context) {
 		return
 	}
 
-	template, err := h.service.GetTemplateByID(c.Request.Context(), id)
+	template, err := h.service.GetTemplateByID(c.Request.Context(), c.GetInt64("userID"), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
 		return
 	}
 	c.JSON(http.StatusOK, template)
-} 
\ No newline at end of file
+}
+
+func (h *templateHandlerImpl) GetEligibility(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+	variants, err := strconv.Atoi(c.DefaultQuery("variants", "1"))
+	if err != nil || variants < 1 || variants > model.MaxGenerationVariants {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variants"})
+		return
+	}
+
+	eligibility, err := h.service.GetEligibility(c.Request.Context(), userID.(int64), id, variants)
+	if err != nil {
+		eligibilityError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, eligibility)
+}
+
+func (h *templateHandlerImpl) AdminList(c *gin.Context) {
+	templates, err := h.service.ListTemplates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *templateHandlerImpl) Create(c *gin.Context) {
+	var req model.TemplateCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.CreateTemplate(c.Request.Context(), &req)
+	if err != nil {
+		templateError(c, err, "failed to create template")
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *templateHandlerImpl) Update(c *gin.Context) {
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.TemplateUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(c.Request.Context(), id, &req)
+	if err != nil {
+		templateError(c, err, "failed to update template")
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *templateHandlerImpl) Activate(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *templateHandlerImpl) Deactivate(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *templateHandlerImpl) setActive(c *gin.Context, isActive bool) {
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.service.SetTemplateActive(c.Request.Context(), id, isActive)
+	if err != nil {
+		templateError(c, err, "failed to update template")
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *templateHandlerImpl) Delete(c *gin.Context) {
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(c.Request.Context(), id); err != nil {
+		templateError(c, err, "failed to delete template")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *templateHandlerImpl) SetSchedule(c *gin.Context) {
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.TemplateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.SetTemplateSchedule(c.Request.Context(), id, &req)
+	if err != nil {
+		templateError(c, err, "failed to update template schedule")
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *templateHandlerImpl) Reorder(c *gin.Context) {
+	var req model.TemplateReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, err := h.service.ReorderTemplates(c.Request.Context(), req.IDs)
+	if err != nil {
+		templateError(c, err, "failed to reorder templates")
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *templateHandlerImpl) UploadPreview(c *gin.Context) {
+	id, ok := templateIDParam(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPreviewSize+1<<10)
+	file, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image file of at most 5MB is required"})
+		return
+	}
+	if file.Size > maxPreviewSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image must be at most 5MB"})
+		return
+	}
+
+	image, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open image"})
+		return
+	}
+	defer image.Close()
+
+	template, err := h.service.UploadPreview(c.Request.Context(), id, image)
+	if err != nil {
+		templateError(c, err, "failed to upload preview")
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// templateError maps template service errors to responses
+func templateError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+	case errors.Is(err, service.ErrTemplateNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// eligibilityError maps template eligibility errors to responses
+func eligibilityError(c *gin.Context, err error) {
+	var ineligibleErr *service.IneligibleError
+	switch {
+	case errors.As(err, &ineligibleErr):
+		c.JSON(http.StatusForbidden, gin.H{"error": "template cannot be used", "reasons": ineligibleErr.Eligibility.Reasons})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check eligibility"})
+	}
+}
+
+// templateIDParam parses the :id route parameter, writing a 400 if it is invalid
+func templateIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return 0, false
+	}
+	return id, true
+}
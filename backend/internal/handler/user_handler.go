@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -12,17 +15,22 @@ type UserHandler interface {
 	GetProfile(c *gin.Context)
 	UpdateProfile(c *gin.Context)
 	GetTransactions(c *gin.Context)
+	SyncWechatProfile(c *gin.Context)
+	DeleteAccount(c *gin.Context)
+	ExportData(c *gin.Context)
 }
 
 type userHandlerImpl struct {
 	userService        service.UserService
 	transactionService service.TransactionService
+	accountService     service.AccountService
 }
 
-func NewUserHandler(userService service.UserService, transactionService service.TransactionService) UserHandler {
+func NewUserHandler(userService service.UserService, transactionService service.TransactionService, accountService service.AccountService) UserHandler {
 	return &userHandlerImpl{
 		userService:        userService,
 		transactionService: transactionService,
+		accountService:     accountService,
 	}
 }
 
@@ -43,5 +51,123 @@ func (h *userHandlerImpl) GetProfile(c *gin.Context) {
 }
 
 func (h *userHandlerImpl) UpdateProfile(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-} 
\ No newline at end of file
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req model.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID.(int64), &req)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+} 
+
+func (h *userHandlerImpl) GetTransactions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	transactions, err := h.transactionService.GetTransactionsByUserID(c.Request.Context(), userID.(int64), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transactions": transactions})
+}
+
+func (h *userHandlerImpl) SyncWechatProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req model.WechatProfileSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.SyncWechatProfile(c.Request.Context(), userID.(int64), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWechatData) || errors.Is(err, service.ErrWechatSessionMissing) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sync wechat profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *userHandlerImpl) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	if err := h.accountService.DeleteAccount(c.Request.Context(), userID.(int64)); err != nil {
+		if errors.Is(err, service.ErrAccountDeleted) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *userHandlerImpl) ExportData(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	export, err := h.accountService.ExportData(c.Request.Context(), userID.(int64))
+	if err != nil {
+		if errors.Is(err, service.ErrAccountDeleted) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export account data"})
+		return
+	}
+
+	filename := fmt.Sprintf("45ai-export-%d-%s.json", export.Profile.ID, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.IndentedJSON(http.StatusOK, export)
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditBodySize caps how much of a request body is copied into the audit log
+const maxAuditBodySize = 8 << 10
+
+// auditRedactedFields are replaced in audited request bodies
+var auditRedactedFields = []string{"password", "secret", "token"}
+
+// AdminAuthMiddleware authenticates admin API requests. It sets "adminID" and
+// "admin" in the context.
+func AdminAuthMiddleware(adminService service.AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Abort()
+			return
+		}
+
+		admin, err := adminService.ParseToken(c.Request.Context(), parts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("adminID", admin.ID)
+		c.Set("admin", admin)
+
+		c.Next()
+	}
+}
+
+// RequireRole allows the request through if the admin holds any of the given
+// roles. Super admins pass every check. Must run after AdminAuthMiddleware.
+func RequireRole(roles ...model.AdminRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("admin")
+		admin, ok := value.(*model.AdminUser)
+		if !exists || !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "admin not authenticated"})
+			c.Abort()
+			return
+		}
+		if !admin.HasRole(roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuditMiddleware writes every admin API request to the audit log once it has
+// been handled, including requests rejected by RequireRole. The target is taken
+// from the :id route parameter and the path segment before it, e.g.
+// /admin/users/:id/ban is recorded against target_type "users".
+// Must run after AdminAuthMiddleware.
+func AuditMiddleware(auditService service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := captureBody(c)
+
+		c.Next()
+
+		entry := &model.AuditLog{
+			Action:     c.Request.Method + " " + c.FullPath(),
+			StatusCode: c.Writer.Status(),
+			IP:         c.ClientIP(),
+		}
+		if adminID, ok := c.Get("adminID"); ok {
+			id := adminID.(int64)
+			entry.AdminID = &id
+		}
+		if id := c.Param("id"); id != "" {
+			entry.TargetID = id
+			entry.TargetType = targetType(c.FullPath())
+		}
+
+		details := gin.H{}
+		if query := c.Request.URL.RawQuery; query != "" {
+			details["query"] = query
+		}
+		if body != nil {
+			details["body"] = body
+		}
+		if len(details) > 0 {
+			entry.Details, _ = json.Marshal(details)
+		}
+
+		if err := auditService.Record(c.Request.Context(), entry); err != nil {
+			log.Printf("Failed to audit %s by admin %v: %v", entry.Action, entry.AdminID, err)
+		}
+	}
+}
+
+// captureBody returns the request body for the audit log and puts it back for
+// the handler. JSON bodies are recorded with secrets redacted; other bodies,
+// such as file uploads, are summarized.
+func captureBody(c *gin.Context) interface{} {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(c.ContentType(), "application/json") {
+		return gin.H{"content_type": c.ContentType(), "size": c.Request.ContentLength}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBodySize+1))
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	if len(data) > maxAuditBodySize {
+		return gin.H{"truncated": true, "size": c.Request.ContentLength}
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return gin.H{"invalid_json": true, "size": len(data)}
+	}
+	for _, field := range auditRedactedFields {
+		if _, ok := parsed[field]; ok {
+			parsed[field] = "[redacted]"
+		}
+	}
+	return parsed
+}
+
+// targetType returns the path segment before the :id parameter
+func targetType(fullPath string) string {
+	segments := strings.Split(fullPath, "/")
+	for i, segment := range segments {
+		if segment == ":id" && i > 0 {
+			return segments[i-1]
+		}
+	}
+	return ""
+}
@@ -8,8 +8,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware creates a middleware function for JWT authentication
-func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
+// AuthMiddleware creates a middleware function for JWT authentication.
+// Tokens are also checked against the session revocation store.
+func AuthMiddleware(authService service.AuthService, sessionService service.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,15 +28,28 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		userID, err := authService.ValidateToken(c.Request.Context(), tokenString)
+		claims, err := authService.ParseToken(c.Request.Context(), tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// Set user ID in context for use in handlers
-		c.Set("userID", userID)
+		revoked, err := sessionService.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Set user ID and claims in context for use in handlers
+		c.Set("userID", claims.UserID)
+		c.Set("tokenClaims", claims)
 
 		c.Next()
 	}
@@ -43,16 +57,19 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 
 // OptionalAuthMiddleware creates a middleware that doesn't require authentication
 // but extracts user info if available
-func OptionalAuthMiddleware(authService service.AuthService) gin.HandlerFunc {
+func OptionalAuthMiddleware(authService service.AuthService, sessionService service.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				tokenString := parts[1]
-				userID, err := authService.ValidateToken(c.Request.Context(), tokenString)
+				claims, err := authService.ParseToken(c.Request.Context(), tokenString)
 				if err == nil {
-					c.Set("userID", userID)
+					if revoked, err := sessionService.IsRevoked(c.Request.Context(), claims); err == nil && !revoked {
+						c.Set("userID", claims.UserID)
+						c.Set("tokenClaims", claims)
+					}
 				}
 			}
 		}
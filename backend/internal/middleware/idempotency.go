@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotencyMultipartMemory matches gin's default MaxMultipartMemory
+	maxIdempotencyMultipartMemory = 32 << 20
+	// idempotencyStoreTimeout bounds saving the response after the client may have gone
+	idempotencyStoreTimeout = 10 * time.Second
+)
+
+// IdempotencyMiddleware makes requests that carry an Idempotency-Key header
+// safe to retry. The first request with a key runs normally and its response
+// is stored; later requests with the same key and body get that response back
+// without running the handler again. Reusing a key for a different request is
+// rejected with 422, and a retry while the first request is still running with
+// 409. Server errors and panics are not stored, so the request can be retried
+// with the same key. Requests without the header are not affected.
+// Must run after AuthMiddleware; keys are scoped to the user.
+func IdempotencyMiddleware(idempotencyService service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)})
+			c.Abort()
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+
+		record, err := idempotencyService.Begin(c.Request.Context(), &model.IdempotencyRecord{
+			Scope:       callerScope(c),
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        requestPath(c),
+			Fingerprint: fingerprint,
+		})
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			log.Printf("Failed to check idempotency key: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check idempotency key"})
+			c.Abort()
+			return
+		}
+
+		if record.IsCompleted() {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Deferred so a panicking handler abandons the key before the panic
+		// reaches the recovery middleware
+		completed := false
+		defer func() {
+			settleIdempotencyKey(idempotencyService, record, recorder, completed)
+		}()
+
+		c.Next()
+		completed = true
+	}
+}
+
+// settleIdempotencyKey stores the response for replay, or abandons the key when
+// the handler did not complete or failed with a server error
+func settleIdempotencyKey(idempotencyService service.IdempotencyService, record *model.IdempotencyRecord, recorder *responseRecorder, completed bool) {
+	// The client may already have disconnected; the key must still be settled
+	ctx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+	defer cancel()
+
+	var err error
+	if status := recorder.Status(); !completed || status >= http.StatusInternalServerError {
+		err = idempotencyService.Abandon(ctx, record)
+	} else {
+		err = idempotencyService.Complete(ctx, record, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+	}
+	if err != nil {
+		log.Printf("Failed to settle idempotency key %q for %s: %v", record.Key, record.Scope, err)
+	}
+}
+
+// requestPath is the path and query string the request was sent to
+func requestPath(c *gin.Context) string {
+	if c.Request.URL.RawQuery == "" {
+		return c.Request.URL.Path
+	}
+	return c.Request.URL.Path + "?" + c.Request.URL.RawQuery
+}
+
+// requestFingerprint hashes the method, path, query string and body of a
+// request, putting the body back for the handler. Multipart forms are hashed by
+// field and file content, since clients pick a new boundary and temporary file
+// name on retry.
+func requestFingerprint(c *gin.Context) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", c.Request.Method, requestPath(c))
+
+	if c.Request.Body == nil {
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		// The parsed form is kept on the request, so the handler reads it from there
+		if err := c.Request.ParseMultipartForm(maxIdempotencyMultipartMemory); err != nil {
+			return "", err
+		}
+		form := c.Request.MultipartForm
+		for _, name := range sortedKeys(form.Value) {
+			for _, value := range form.Value[name] {
+				fmt.Fprintf(hash, "field %q %q\n", name, value)
+			}
+		}
+		for _, name := range sortedKeys(form.File) {
+			for _, header := range form.File[name] {
+				file, err := header.Open()
+				if err != nil {
+					return "", err
+				}
+				fileHash := sha256.New()
+				_, err = io.Copy(fileHash, file)
+				file.Close()
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(hash, "file %q %x\n", name, fileHash.Sum(nil))
+			}
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sortedKeys returns the keys of a form map in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// responseRecorder keeps a copy of the response body while writing it out
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type formFile struct {
+	field    string
+	filename string
+	content  string
+}
+
+// multipartRequest builds a multipart POST with the given boundary, fields and files
+func multipartRequest(t *testing.T, target, boundary string, fields map[string]string, files []formFile) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.field, file.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(part, file.content)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func fingerprint(t *testing.T, req *http.Request) string {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	fingerprint, err := requestFingerprint(c)
+	if err != nil {
+		t.Fatalf("requestFingerprint() error = %v", err)
+	}
+	return fingerprint
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	fields := map[string]string{"template_id": "3", "variants": "2"}
+	selfie := []formFile{{field: "image", filename: "a.jpg", content: "selfie"}}
+	base := fingerprint(t, multipartRequest(t, "/api/v1/generate", "boundary-one", fields, selfie))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		same bool
+	}{
+		{
+			name: "new boundary and file name",
+			req:  multipartRequest(t, "/api/v1/generate", "boundary-two", fields, []formFile{{field: "image", filename: "b.jpg", content: "selfie"}}),
+			same: true,
+		},
+		{
+			name: "different file content",
+			req:  multipartRequest(t, "/api/v1/generate", "boundary-one", fields, []formFile{{field: "image", filename: "a.jpg", content: "another selfie"}}),
+		},
+		{
+			name: "different field value",
+			req:  multipartRequest(t, "/api/v1/generate", "boundary-one", map[string]string{"template_id": "4", "variants": "2"}, selfie),
+		},
+		{
+			name: "different path",
+			req:  multipartRequest(t, "/api/v1/generate/abc/regenerate", "boundary-one", fields, selfie),
+		},
+		{
+			name: "query string",
+			req:  multipartRequest(t, "/api/v1/generate?variants=4", "boundary-one", fields, selfie),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprint(t, tt.req); (got == base) != tt.same {
+				t.Errorf("fingerprint equal = %v, want %v", got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestRequestFingerprintRestoresBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/generate/abc/regenerate", bytes.NewBufferString(`{"seed":7}`))
+	req.Header.Set("Content-Type", "application/json")
+	first := fingerprint(t, req)
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"seed":7}` {
+		t.Errorf("body = %q, want it to be readable by the handler", body)
+	}
+
+	other := httptest.NewRequest(http.MethodPost, "/api/v1/generate/abc/regenerate", bytes.NewBufferString(`{"seed":8}`))
+	other.Header.Set("Content-Type", "application/json")
+	if fingerprint(t, other) == first {
+		t.Error("different bodies have the same fingerprint")
+	}
+}
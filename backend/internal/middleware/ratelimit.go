@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/45ai/backend/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware limits requests with a token bucket per client. Every
+// response carries RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; rejected requests get 429 with Retry-After.
+// User policies must run after AuthMiddleware to count by user. If the store
+// fails the request is let through rather than locking everyone out.
+func RateLimitMiddleware(store ratelimit.Store, policy ratelimit.Policy) gin.HandlerFunc {
+	if policy.Limit.IsZero() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit.Burst, int64(math.Ceil(policy.Limit.Period.Seconds())))
+	return func(c *gin.Context) {
+		scope := "ip:" + c.ClientIP()
+		if policy.KeyBy == ratelimit.KeyByUser {
+			scope = callerScope(c)
+		}
+
+		result, err := store.Take(c.Request.Context(), policy.Name+":"+scope, policy.Limit)
+		if err != nil {
+			log.Printf("Failed to check rate limit %s for %s: %v", policy.Name, scope, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// callerScope identifies the client: the signed-in user, or else the client IP
+func callerScope(c *gin.Context) string {
+	if userID := c.GetInt64("userID"); userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds formats a duration as whole seconds, rounding up
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
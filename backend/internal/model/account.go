@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// AccountExport is the personal data archive returned by GET /me/export
+type AccountExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	Profile       AccountExportProfile `json:"profile"`
+	Transactions  []Transaction        `json:"transactions"`
+	Subscriptions []SubscriptionStatus `json:"subscriptions"`
+}
+
+// AccountExportProfile holds every stored profile field, including the
+// identifiers that are hidden from regular API responses
+type AccountExportProfile struct {
+	ID            int64     `json:"id"`
+	WechatOpenID  string    `json:"wechat_openid"`
+	WechatUnionID *string   `json:"wechat_unionid,omitempty"`
+	Nickname      string    `json:"nickname"`
+	AvatarURL     string    `json:"avatar_url"`
+	Credits       int       `json:"credits"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
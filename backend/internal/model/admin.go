@@ -0,0 +1,103 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AdminRole grants access to a part of the admin API
+type AdminRole string
+
+const (
+	// AdminRoleSuperAdmin can do everything, including managing other admins
+	AdminRoleSuperAdmin AdminRole = "superadmin"
+	// AdminRoleOperator manages templates and content
+	AdminRoleOperator AdminRole = "operator"
+	// AdminRoleFinance manages pricing, credits and transactions
+	AdminRoleFinance AdminRole = "finance"
+	// AdminRoleModerator handles users, bans and reported content
+	AdminRoleModerator AdminRole = "moderator"
+)
+
+// AdminRoles lists every known role
+var AdminRoles = []AdminRole{AdminRoleSuperAdmin, AdminRoleOperator, AdminRoleFinance, AdminRoleModerator}
+
+// AdminUser represents a back-office account. Admins are separate from WeChat users.
+type AdminUser struct {
+	ID           int64       `json:"id" db:"id"`
+	Username     string      `json:"username" db:"username"`
+	PasswordHash string      `json:"-" db:"password_hash"`
+	Roles        []AdminRole `json:"roles" db:"roles"`
+	DisabledAt   *time.Time  `json:"disabled_at,omitempty" db:"disabled_at"`
+	LastLoginAt  *time.Time  `json:"last_login_at,omitempty" db:"last_login_at"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// HasRole reports whether the admin holds any of the given roles. Super admins hold every role.
+func (a *AdminUser) HasRole(roles ...AdminRole) bool {
+	for _, held := range a.Roles {
+		if held == AdminRoleSuperAdmin {
+			return true
+		}
+		for _, role := range roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsDisabled reports whether the admin account has been disabled
+func (a *AdminUser) IsDisabled() bool {
+	return a.DisabledAt != nil
+}
+
+// AdminLoginRequest represents the admin login request
+type AdminLoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// AdminLoginResponse represents the admin login response
+type AdminLoginResponse struct {
+	Token     string     `json:"token"`
+	ExpiresIn int64      `json:"expires_in"`
+	Admin     *AdminUser `json:"admin"`
+}
+
+// AdminCreateRequest represents the request to create an admin account
+type AdminCreateRequest struct {
+	Username string      `json:"username" binding:"required"`
+	Password string      `json:"password" binding:"required"`
+	Roles    []AdminRole `json:"roles" binding:"required"`
+}
+
+// AdminUpdateRequest represents the request to change an admin's roles or status
+type AdminUpdateRequest struct {
+	Roles    []AdminRole `json:"roles,omitempty"`
+	Disabled *bool       `json:"disabled,omitempty"`
+}
+
+// AuditLog records one action taken through the admin API
+type AuditLog struct {
+	ID         int64           `json:"id" db:"id"`
+	AdminID    *int64          `json:"admin_id,omitempty" db:"admin_id"`
+	Action     string          `json:"action" db:"action"`
+	TargetType string          `json:"target_type,omitempty" db:"target_type"`
+	TargetID   string          `json:"target_id,omitempty" db:"target_id"`
+	StatusCode int             `json:"status_code" db:"status_code"`
+	IP         string          `json:"ip" db:"ip"`
+	Details    json.RawMessage `json:"details,omitempty" db:"details"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditLogFilter narrows an audit log listing
+type AuditLogFilter struct {
+	AdminID    *int64
+	TargetType string
+	TargetID   string
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// ComfyUI node states
+const (
+	ComfyUINodeHealthy = "healthy"
+	// ComfyUINodeDegraded has failed recently but still receives renders
+	ComfyUINodeDegraded = "degraded"
+	// ComfyUINodeEjected receives no renders until a health probe succeeds
+	ComfyUINodeEjected = "ejected"
+)
+
+// ComfyUIProbe is what a ComfyUI node reports from /system_stats and /queue
+type ComfyUIProbe struct {
+	QueueRunning int   `json:"queue_running"`
+	QueuePending int   `json:"queue_pending"`
+	VRAMTotal    int64 `json:"vram_total"`
+	VRAMFree     int64 `json:"vram_free"`
+}
+
+// QueueDepth is the number of prompts the node has accepted but not finished
+func (p *ComfyUIProbe) QueueDepth() int {
+	return p.QueueRunning + p.QueuePending
+}
+
+// ComfyUINodeStatus is the state of a ComfyUI node as seen by one process
+type ComfyUINodeStatus struct {
+	// Worker and ReportedAt are set on statuses published by workers
+	Worker     string     `json:"worker,omitempty"`
+	ReportedAt *time.Time `json:"reported_at,omitempty"`
+
+	URL      string `json:"url"`
+	State    string `json:"state"`
+	Capacity int    `json:"capacity"`
+	// InFlight counts renders this process is running on the node
+	InFlight            int          `json:"in_flight"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastProbeAt         *time.Time   `json:"last_probe_at,omitempty"`
+	EjectedUntil        *time.Time   `json:"ejected_until,omitempty"`
+	Probe               ComfyUIProbe `json:"probe"`
+}
@@ -0,0 +1,40 @@
+package model
+
+// Eligibility reason codes
+const (
+	EligibilityTemplateDeleted     = "template_deleted"
+	EligibilityTemplateInactive    = "template_inactive"
+	EligibilityTemplateNotStarted  = "template_not_started"
+	EligibilityTemplateEnded       = "template_ended"
+	EligibilityUserBanned          = "user_banned"
+	EligibilityInsufficientCredits = "insufficient_credits"
+	EligibilityDailyLimitReached   = "daily_limit_reached"
+)
+
+// EligibilityReason explains why a user cannot use a template
+type EligibilityReason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// TemplateEligibility tells a user whether they can generate with a template
+// before they upload a photo. Every failed check is listed, not just the first.
+type TemplateEligibility struct {
+	TemplateID int  `json:"template_id"`
+	Eligible   bool `json:"eligible"`
+	// ListCreditCost is the template price before pricing rules, CreditCost what the
+	// user pays for the first variant, ExtraVariantCreditCost for each further one
+	// and TotalCreditCost what they pay for all Variants
+	ListCreditCost         int                 `json:"list_credit_cost"`
+	CreditCost             int                 `json:"credit_cost"`
+	ExtraVariantCreditCost int                 `json:"extra_variant_credit_cost"`
+	Variants               int                 `json:"variants"`
+	TotalCreditCost        int                 `json:"total_credit_cost"`
+	PricingRule            *AppliedPricingRule `json:"pricing_rule,omitempty"`
+	Credits                int                 `json:"credits"`
+	// DailyLimit is 0 when generations per day are not limited. Every variant
+	// counts as a generation; cancelled and failed jobs do not count.
+	DailyLimit int                 `json:"daily_limit"`
+	UsedToday  int                 `json:"used_today"`
+	Reasons    []EligibilityReason `json:"reasons"`
+}
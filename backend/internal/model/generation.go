@@ -0,0 +1,151 @@
+package model
+
+import (
+	"time"
+)
+
+// GenerationJobStatus is the state of a generation job
+type GenerationJobStatus string
+
+const (
+	GenerationStatusQueued     GenerationJobStatus = "queued"
+	GenerationStatusProcessing GenerationJobStatus = "processing"
+	GenerationStatusCompleted  GenerationJobStatus = "completed"
+	GenerationStatusFailed     GenerationJobStatus = "failed"
+	GenerationStatusCancelled  GenerationJobStatus = "cancelled"
+)
+
+// GenerationLane is the priority lane a job waits in. A queued job in a higher
+// lane always starts before any job in a lower one.
+type GenerationLane string
+
+const (
+	// GenerationLanePriority is for members
+	GenerationLanePriority GenerationLane = "priority"
+	// GenerationLaneStandard is for paid generations
+	GenerationLaneStandard GenerationLane = "standard"
+	// GenerationLaneTrial is for free generations, such as a first-generation offer
+	GenerationLaneTrial GenerationLane = "trial"
+)
+
+// Rank orders lanes; lower ranks are served first
+func (l GenerationLane) Rank() int {
+	switch l {
+	case GenerationLanePriority:
+		return 0
+	case GenerationLaneStandard:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// MaxGenerationVariants caps the variants one request can ask for
+const MaxGenerationVariants = 4
+
+// GenerationVariant is one output of a job, rendered with its own seed
+type GenerationVariant struct {
+	Seed   int64    `json:"seed"`
+	Images []string `json:"images,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// GenerationJob tracks a generation request from upload to results
+type GenerationJob struct {
+	ID              int64               `json:"-" db:"id"`
+	RequestID       string              `json:"request_id" db:"request_id"`
+	UserID          int64               `json:"-" db:"user_id"`
+	TemplateID      int                 `json:"template_id" db:"template_id"`
+	ParentRequestID *string             `json:"parent_request_id,omitempty" db:"parent_request_id"`
+	SourceKey       string              `json:"-" db:"source_key"`
+	Status          GenerationJobStatus `json:"status" db:"status"`
+	Lane            GenerationLane      `json:"lane" db:"lane"`
+	Variants        []GenerationVariant `json:"variants" db:"variants"`
+	// CreditsPerVariant is quoted on submission; with several variants it is the
+	// price of each one after the first. Every variant is charged up front and
+	// failed variants are refunded, so CreditsCharged only counts successes once
+	// the job has finished.
+	CreditsPerVariant int        `json:"credits_per_variant" db:"credits_per_variant"`
+	CreditsCharged    int        `json:"credits_charged" db:"credits_charged"`
+	Attempts          int        `json:"attempts" db:"attempts"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	PricingRuleID     *int       `json:"pricing_rule_id,omitempty" db:"pricing_rule_id"`
+	Error             string     `json:"error,omitempty" db:"error"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	StartedAt         *time.Time `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	// Timings is stored in one column per stage
+	Timings GenerationTimings `json:"timings" db:"-"`
+	// QueuePosition is the job's place in line while queued, 1 being next
+	QueuePosition int `json:"queue_position,omitempty" db:"-"`
+}
+
+// GenerationTimings records how long the stages of a job took, in milliseconds.
+// Stages that did not run, such as the upload of a regeneration, are zero.
+type GenerationTimings struct {
+	ValidationMS int64 `json:"validation_ms" db:"validation_ms"`
+	SafetyMS     int64 `json:"safety_ms" db:"safety_ms"`
+	UploadMS     int64 `json:"upload_ms" db:"upload_ms"`
+	// QueueWaitMS and RenderMS add up over attempts
+	QueueWaitMS int64 `json:"queue_wait_ms" db:"queue_wait_ms"`
+	RenderMS    int64 `json:"render_ms" db:"render_ms"`
+	// TotalMS is from the request to the job finishing, zero until then
+	TotalMS int64 `json:"total_ms" db:"total_ms"`
+}
+
+// LatencyPercentiles summarizes a latency distribution, in milliseconds
+type LatencyPercentiles struct {
+	P50MS int64 `json:"p50_ms"`
+	P95MS int64 `json:"p95_ms"`
+	P99MS int64 `json:"p99_ms"`
+}
+
+// GenerationLatencyStats summarizes the latency of a template's completed jobs
+type GenerationLatencyStats struct {
+	TemplateID int `json:"template_id"`
+	Jobs       int `json:"jobs"`
+	// WithinSLA is the share of jobs whose total time met the SLA target
+	WithinSLA float64            `json:"within_sla"`
+	Total     LatencyPercentiles `json:"total"`
+	QueueWait LatencyPercentiles `json:"queue_wait"`
+	Render    LatencyPercentiles `json:"render"`
+}
+
+// GenerationLatencyReport is the latency of jobs completed since a point in time
+type GenerationLatencyReport struct {
+	Since       time.Time                `json:"since"`
+	SLATargetMS int64                    `json:"sla_target_ms"`
+	Templates   []GenerationLatencyStats `json:"templates"`
+}
+
+// IsFinished reports whether the job has reached a final state
+func (j *GenerationJob) IsFinished() bool {
+	return j.Status == GenerationStatusCompleted || j.Status == GenerationStatusFailed || j.Status == GenerationStatusCancelled
+}
+
+// Images lists the outputs of every successful variant in order
+func (j *GenerationJob) Images() []string {
+	images := []string{}
+	for _, variant := range j.Variants {
+		images = append(images, variant.Images...)
+	}
+	return images
+}
+
+// GenerationQueue is a snapshot of the generation queue for scheduling
+type GenerationQueue struct {
+	// Queued lists queued jobs in submission order
+	Queued []GenerationJob
+	// InFlight counts processing jobs per user
+	InFlight map[int64]int
+	// LastStarted is when each user with queued jobs last had a job started
+	LastStarted map[int64]time.Time
+}
+
+// GenerationOptions sets how many variants to render. Seeds, when given, must
+// have one entry per variant; missing seeds are chosen at random.
+type GenerationOptions struct {
+	Variants int     `json:"variants"`
+	Seeds    []int64 `json:"seeds,omitempty"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyRecord remembers a request sent with an Idempotency-Key and its response
+type IdempotencyRecord struct {
+	ID          int64  `db:"id"`
+	Scope       string `db:"scope"`
+	Key         string `db:"idempotency_key"`
+	Method      string `db:"method"`
+	Path        string `db:"path"`
+	Fingerprint string `db:"fingerprint"`
+	// StatusCode is 0 while the first request is still running
+	StatusCode   int       `db:"status_code"`
+	ContentType  string    `db:"content_type"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// IsCompleted reports whether a response has been stored for replay
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+)
+
+// PricingAudience selects the users a pricing rule applies to
+type PricingAudience string
+
+const (
+	PricingAudienceEveryone        PricingAudience = "everyone"
+	PricingAudienceFirstGeneration PricingAudience = "first_generation"
+	PricingAudienceNewUser         PricingAudience = "new_user"
+	PricingAudienceMember          PricingAudience = "member"
+)
+
+// PricingDiscountType says how a pricing rule's value is applied
+type PricingDiscountType string
+
+const (
+	// PricingDiscountPercent takes Value percent off the list price
+	PricingDiscountPercent PricingDiscountType = "percent"
+	// PricingDiscountFixedPrice charges Value credits, never more than the list price
+	PricingDiscountFixedPrice PricingDiscountType = "fixed_price"
+)
+
+// PricingRule lowers the price of one or all templates for an audience,
+// optionally only between StartsAt and EndsAt
+type PricingRule struct {
+	ID           int                 `json:"id" db:"id"`
+	Name         string              `json:"name" db:"name"`
+	TemplateID   *int                `json:"template_id,omitempty" db:"template_id"`
+	Audience     PricingAudience     `json:"audience" db:"audience"`
+	DiscountType PricingDiscountType `json:"discount_type" db:"discount_type"`
+	Value        int                 `json:"value" db:"value"`
+	StartsAt     *time.Time          `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt       *time.Time          `json:"ends_at,omitempty" db:"ends_at"`
+	IsActive     bool                `json:"is_active" db:"is_active"`
+	CreatedAt    time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// PricingRuleRequest represents the request to create or replace a pricing rule
+type PricingRuleRequest struct {
+	Name         string              `json:"name" binding:"required"`
+	TemplateID   *int                `json:"template_id"`
+	Audience     PricingAudience     `json:"audience"`
+	DiscountType PricingDiscountType `json:"discount_type" binding:"required"`
+	Value        int                 `json:"value"`
+	StartsAt     *time.Time          `json:"starts_at"`
+	EndsAt       *time.Time          `json:"ends_at"`
+	IsActive     *bool               `json:"is_active"`
+}
+
+// AppliedPricingRule identifies the rule that set a price
+type AppliedPricingRule struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Audience PricingAudience `json:"audience"`
+}
+
+// MembershipRequest sets or, with a null MemberUntil, ends a user's membership
+type MembershipRequest struct {
+	MemberUntil *time.Time `json:"member_until"`
+}
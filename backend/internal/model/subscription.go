@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// SubscriptionGrant represents a one-time permission to send a subscribe message
+type SubscriptionGrant struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	TemplateID string     `json:"template_id" db:"template_id"`
+	GrantedAt  time.Time  `json:"granted_at" db:"granted_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+}
+
+// SubscriptionGrantRequest carries the result of wx.requestSubscribeMessage,
+// mapping each template ID to "accept", "reject", "ban" or "filter"
+type SubscriptionGrantRequest struct {
+	Results map[string]string `json:"results" binding:"required"`
+}
+
+// SubscriptionStatus describes a notification template and the unused grants for it
+type SubscriptionStatus struct {
+	Event      string `json:"event"`
+	TemplateID string `json:"template_id"`
+	Available  int    `json:"available"`
+}
@@ -6,17 +6,127 @@ import (
 
 // Template represents an AI style template
 type Template struct {
-	ID              int       `json:"id" db:"id"`
-	Name            string    `json:"name" db:"name"`
-	Description     string    `json:"description" db:"description"`
-	PreviewImageURL string    `json:"preview_image_url" db:"preview_image_url"`
-	CreditCost      int       `json:"credit_cost" db:"credit_cost"`
-	IsActive        bool      `json:"is_active" db:"is_active"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	ID              int    `json:"id" db:"id"`
+	Name            string `json:"name" db:"name"`
+	Description     string `json:"description" db:"description"`
+	PreviewImageURL string `json:"preview_image_url" db:"preview_image_url"`
+	CreditCost      int    `json:"credit_cost" db:"credit_cost"`
+	// EffectiveCreditCost is what the requesting user pays after pricing rules
+	EffectiveCreditCost int                 `json:"effective_credit_cost" db:"-"`
+	PricingRule         *AppliedPricingRule `json:"pricing_rule,omitempty" db:"-"`
+	IsActive            bool                `json:"is_active" db:"is_active"`
+	SortOrder           int                 `json:"sort_order" db:"sort_order"`
+	// AvailableFrom and AvailableUntil bound when the template can be used; nil is unbounded
+	AvailableFrom  *time.Time `json:"available_from,omitempty" db:"available_from"`
+	AvailableUntil *time.Time `json:"available_until,omitempty" db:"available_until"`
+	// IsLimitedTime is set when the template goes away at AvailableUntil
+	IsLimitedTime bool               `json:"is_limited_time" db:"-"`
+	Categories    []TemplateCategory `json:"categories"`
+	Tags          []string           `json:"tags"`
+	Usage         *TemplateUsage     `json:"usage,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" db:"updated_at"`
+	DeletedAt     *time.Time         `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
-// TemplateListResponse represents the response for template listing
+// IsDeleted reports whether the template has been soft-deleted
+func (t *Template) IsDeleted() bool {
+	return t.DeletedAt != nil
+}
+
+// TemplateUsage holds how often a template has been used. Recent counts are
+// refreshed periodically and may lag by a few minutes.
+type TemplateUsage struct {
+	Total         int     `json:"total"`
+	Last24h       int     `json:"last_24h"`
+	Last7d        int     `json:"last_7d"`
+	TrendingScore float64 `json:"trending_score"`
+}
+
+// Template list sort orders
+const (
+	// TemplateSortDefault follows the order set by operators
+	TemplateSortDefault = ""
+	// TemplateSortPopular orders by all-time uses
+	TemplateSortPopular = "popular"
+	// TemplateSortTrending orders by recent uses, decaying over time
+	TemplateSortTrending = "trending"
+	// TemplateSortNew orders by creation time, newest first
+	TemplateSortNew = "new"
+)
+
+// TemplateCategory groups templates in the gallery
+type TemplateCategory struct {
+	ID        int    `json:"id" db:"id"`
+	Slug      string `json:"slug" db:"slug"`
+	Name      string `json:"name" db:"name"`
+	SortOrder int    `json:"sort_order" db:"sort_order"`
+	// TemplateCount is the number of active templates in the category, filled in listings only
+	TemplateCount int `json:"template_count,omitempty" db:"-"`
+}
+
+// TemplateCategoryRequest represents the request to create or update a category
+type TemplateCategoryRequest struct {
+	Slug      string `json:"slug" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// TemplateFilter narrows a gallery listing
+type TemplateFilter struct {
+	// Category is a category slug
+	Category string
+	Tag      string
+	// Query is matched against template names and descriptions
+	Query string
+	// Sort is one of the TemplateSort constants
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// TemplateListResponse represents the response for template listing.
+// Total counts every match, not just the returned page.
 type TemplateListResponse struct {
 	Templates []Template `json:"templates"`
 	Total     int        `json:"total"`
-} 
\ No newline at end of file
+}
+
+// TemplateCreateRequest represents the request to create a template
+type TemplateCreateRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	PreviewImageURL string `json:"preview_image_url"`
+	CreditCost      int    `json:"credit_cost"`
+	IsActive        bool   `json:"is_active"`
+	// SortOrder places the template in the gallery; omitted appends it at the end
+	SortOrder      *int       `json:"sort_order,omitempty"`
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	CategoryIDs    []int      `json:"category_ids,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+}
+
+// TemplateUpdateRequest represents a partial update of a template
+type TemplateUpdateRequest struct {
+	Name            *string `json:"name,omitempty"`
+	Description     *string `json:"description,omitempty"`
+	PreviewImageURL *string `json:"preview_image_url,omitempty"`
+	CreditCost      *int    `json:"credit_cost,omitempty"`
+	SortOrder       *int    `json:"sort_order,omitempty"`
+	// CategoryIDs and Tags replace the current links when present
+	CategoryIDs *[]int    `json:"category_ids,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+}
+
+// TemplateScheduleRequest replaces the availability window of a template.
+// Omitted or null bounds leave that side of the window open.
+type TemplateScheduleRequest struct {
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+}
+
+// TemplateReorderRequest lists template IDs in their new gallery order
+type TemplateReorderRequest struct {
+	IDs []int `json:"ids" binding:"required"`
+}
@@ -10,6 +10,7 @@ type TransactionType string
 const (
 	TransactionTypePurchase   TransactionType = "purchase"
 	TransactionTypeGeneration TransactionType = "generation"
+	TransactionTypeRefund     TransactionType = "refund"
 )
 
 // Transaction represents a credit transaction
@@ -21,6 +22,7 @@ type Transaction struct {
 	Description       string          `json:"description" db:"description"`
 	ExternalPaymentID *string         `json:"external_payment_id,omitempty" db:"external_payment_id"`
 	RelatedTemplateID *int            `json:"related_template_id,omitempty" db:"related_template_id"`
+	PricingRuleID     *int            `json:"pricing_rule_id,omitempty" db:"pricing_rule_id"`
 	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
 }
 
@@ -6,13 +6,32 @@ import (
 
 // User represents a user account in the system
 type User struct {
-	ID           int64     `json:"id" db:"id"`
-	WechatOpenID string    `json:"wechat_openid" db:"wechat_openid"`
-	Nickname     string    `json:"nickname" db:"nickname"`
-	AvatarURL    string    `json:"avatar_url" db:"avatar_url"`
-	Credits      int       `json:"credits" db:"credits"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID            int64      `json:"id" db:"id"`
+	WechatOpenID  string     `json:"wechat_openid" db:"wechat_openid"`
+	WechatUnionID *string    `json:"-" db:"wechat_unionid"`
+	Nickname      string     `json:"nickname" db:"nickname"`
+	AvatarURL     string     `json:"avatar_url" db:"avatar_url"`
+	Credits       int        `json:"credits" db:"credits"`
+	MemberUntil   *time.Time `json:"member_until,omitempty" db:"member_until"`
+	BannedAt      *time.Time `json:"banned_at,omitempty" db:"banned_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsBanned reports whether the account has been banned
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
+
+// IsMember reports whether the account has an unexpired membership at now
+func (u *User) IsMember(now time.Time) bool {
+	return u.MemberUntil != nil && now.Before(*u.MemberUntil)
+}
+
+// IsDeleted reports whether the account has been deleted and anonymized
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
 }
 
 // UserCreateRequest represents the request to create a new user
@@ -26,4 +45,4 @@ type UserCreateRequest struct {
 type UserUpdateRequest struct {
 	Nickname  *string `json:"nickname,omitempty"`
 	AvatarURL *string `json:"avatar_url,omitempty"`
-} 
\ No newline at end of file
+}
@@ -1,5 +1,9 @@
 package model
 
+import (
+	"time"
+)
+
 // WechatLoginResponse represents the response from WeChat login
 type WechatLoginResponse struct {
 	OpenID     string `json:"openid"`
@@ -7,4 +11,67 @@ type WechatLoginResponse struct {
 	UnionID    string `json:"unionid,omitempty"`
 	ErrCode    int    `json:"errcode"`
 	ErrMsg     string `json:"errmsg"`
-} 
\ No newline at end of file
+} 
+
+// WechatUserInfo represents the decrypted payload of wx.getUserProfile
+type WechatUserInfo struct {
+	OpenID    string          `json:"openId"`
+	UnionID   string          `json:"unionId"`
+	NickName  string          `json:"nickName"`
+	AvatarURL string          `json:"avatarUrl"`
+	Watermark WechatWatermark `json:"watermark"`
+}
+
+// WechatWatermark identifies the mini program an encrypted payload was issued for
+type WechatWatermark struct {
+	AppID     string `json:"appid"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// WechatProfileSyncRequest represents an encrypted profile payload sent by the mini program
+type WechatProfileSyncRequest struct {
+	EncryptedData string `json:"encrypted_data" binding:"required"`
+	IV            string `json:"iv" binding:"required"`
+	RawData       string `json:"raw_data"`
+	Signature     string `json:"signature"`
+}
+
+// WechatAccessToken represents a cached app access_token
+type WechatAccessToken struct {
+	AppID       string    `json:"app_id" db:"app_id"`
+	AccessToken string    `json:"-" db:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// WechatAccessTokenResponse represents the response from the stable_token API
+type WechatAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+}
+
+// WechatSubscribeMessage represents a request to the subscribeMessage.send API
+type WechatSubscribeMessage struct {
+	ToUser           string                        `json:"touser"`
+	TemplateID       string                        `json:"template_id"`
+	Page             string                        `json:"page,omitempty"`
+	MiniprogramState string                        `json:"miniprogram_state,omitempty"`
+	Lang             string                        `json:"lang,omitempty"`
+	Data             map[string]WechatMessageValue `json:"data"`
+}
+
+// WechatMessageValue is a single field of a subscribe message
+type WechatMessageValue struct {
+	Value string `json:"value"`
+}
+
+// WechatMsgSecCheckResponse represents the response from the msgSecCheck v2 API
+type WechatMsgSecCheckResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Result  struct {
+		Suggest string `json:"suggest"` // "pass", "review" or "risky"
+		Label   int    `json:"label"`
+	} `json:"result"`
+}
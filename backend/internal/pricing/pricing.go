@@ -0,0 +1,173 @@
+// Package pricing computes what a user pays for a template from pricing rules.
+// It only works on values passed in, so rules can be evaluated without a database.
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// Customer describes the user a price is quoted for
+type Customer struct {
+	// Anonymous customers only get rules for everyone
+	Anonymous    bool
+	CreatedAt    time.Time
+	HasGenerated bool
+	MemberUntil  *time.Time
+}
+
+// Quote is the price of one generation with a template
+type Quote struct {
+	ListPrice int
+	Price     int
+	// Rule is the rule that set Price, nil when the list price applies
+	Rule *model.PricingRule
+	// ExtraVariantPrice is what every variant after the first costs. It only
+	// differs from Price when a first generation rule applies, since that rule
+	// makes one image cheaper, not every variant of the first request.
+	ExtraVariantPrice int
+}
+
+// Total is the price of a request for variants images
+func (q Quote) Total(variants int) int {
+	if variants < 1 {
+		return 0
+	}
+	return q.Price + (variants-1)*q.ExtraVariantPrice
+}
+
+// AppliedRule identifies the rule behind the quote, if any
+func (q Quote) AppliedRule() *model.AppliedPricingRule {
+	if q.Rule == nil {
+		return nil
+	}
+	return &model.AppliedPricingRule{ID: q.Rule.ID, Name: q.Rule.Name, Audience: q.Rule.Audience}
+}
+
+// Engine picks the best price for a customer. Rules do not stack: the lowest
+// price wins, and ties go to the rule with the lowest ID.
+type Engine struct {
+	// NewUserPeriod is how long after sign-up new user pricing applies
+	NewUserPeriod time.Duration
+}
+
+// NewEngine creates an Engine
+func NewEngine(newUserPeriod time.Duration) *Engine {
+	return &Engine{NewUserPeriod: newUserPeriod}
+}
+
+// Quote prices a template for a customer at now
+func (e *Engine) Quote(template *model.Template, customer Customer, rules []model.PricingRule, now time.Time) Quote {
+	quote := e.quote(template, customer, rules, now)
+	quote.ExtraVariantPrice = quote.Price
+	if quote.Rule != nil && quote.Rule.Audience == model.PricingAudienceFirstGeneration {
+		// Later variants are priced as if the first one had been generated
+		customer.HasGenerated = true
+		quote.ExtraVariantPrice = e.quote(template, customer, rules, now).Price
+	}
+	return quote
+}
+
+// quote finds the lowest price of a single image
+func (e *Engine) quote(template *model.Template, customer Customer, rules []model.PricingRule, now time.Time) Quote {
+	quote := Quote{ListPrice: template.CreditCost, Price: template.CreditCost}
+	for i := range rules {
+		rule := &rules[i]
+		if !e.Applies(rule, template.ID, customer, now) {
+			continue
+		}
+		price := Price(rule, template.CreditCost)
+		if price < quote.Price || (price == quote.Price && quote.Rule != nil && rule.ID < quote.Rule.ID) {
+			quote.Price = price
+			quote.Rule = rule
+		}
+	}
+	return quote
+}
+
+// Applies reports whether a rule applies to a template and customer at now
+func (e *Engine) Applies(rule *model.PricingRule, templateID int, customer Customer, now time.Time) bool {
+	if !rule.IsActive {
+		return false
+	}
+	if rule.TemplateID != nil && *rule.TemplateID != templateID {
+		return false
+	}
+	if rule.StartsAt != nil && now.Before(*rule.StartsAt) {
+		return false
+	}
+	if rule.EndsAt != nil && !now.Before(*rule.EndsAt) {
+		return false
+	}
+
+	switch rule.Audience {
+	case model.PricingAudienceEveryone:
+		return true
+	case model.PricingAudienceFirstGeneration:
+		return !customer.Anonymous && !customer.HasGenerated
+	case model.PricingAudienceNewUser:
+		return !customer.Anonymous && now.Before(customer.CreatedAt.Add(e.NewUserPeriod))
+	case model.PricingAudienceMember:
+		return !customer.Anonymous && customer.MemberUntil != nil && now.Before(*customer.MemberUntil)
+	default:
+		return false
+	}
+}
+
+// Price applies a rule to a list price. Percentages round in the customer's
+// favour and a rule never raises the price.
+func Price(rule *model.PricingRule, listPrice int) int {
+	var price int
+	switch rule.DiscountType {
+	case model.PricingDiscountPercent:
+		price = listPrice * (100 - rule.Value) / 100
+	case model.PricingDiscountFixedPrice:
+		price = rule.Value
+	default:
+		return listPrice
+	}
+	if price < 0 {
+		price = 0
+	}
+	if price > listPrice {
+		price = listPrice
+	}
+	return price
+}
+
+// FieldError describes an invalid field of a pricing rule
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Validate checks that a rule is well formed, returning a *FieldError if not
+func Validate(rule *model.PricingRule) error {
+	switch rule.Audience {
+	case model.PricingAudienceEveryone, model.PricingAudienceFirstGeneration, model.PricingAudienceNewUser, model.PricingAudienceMember:
+	default:
+		return &FieldError{Field: "audience", Message: fmt.Sprintf("unknown audience %q", rule.Audience)}
+	}
+	switch rule.DiscountType {
+	case model.PricingDiscountPercent:
+		if rule.Value < 0 || rule.Value > 100 {
+			return &FieldError{Field: "value", Message: "percent must be between 0 and 100"}
+		}
+	case model.PricingDiscountFixedPrice:
+		if rule.Value < 0 {
+			return &FieldError{Field: "value", Message: "fixed price must not be negative"}
+		}
+	default:
+		return &FieldError{Field: "discount_type", Message: fmt.Sprintf("unknown discount type %q", rule.DiscountType)}
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return &FieldError{Field: "ends_at", Message: "must be after starts_at"}
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func rule(id int, audience model.PricingAudience, discountType model.PricingDiscountType, value int) model.PricingRule {
+	return model.PricingRule{ID: id, Name: "rule", Audience: audience, DiscountType: discountType, Value: value, IsActive: true}
+}
+
+func TestQuote(t *testing.T) {
+	engine := NewEngine(7 * 24 * time.Hour)
+	template := &model.Template{ID: 1, CreditCost: 10}
+	returning := Customer{CreatedAt: now.Add(-30 * 24 * time.Hour), HasGenerated: true}
+
+	tests := []struct {
+		name     string
+		rules    []model.PricingRule
+		customer Customer
+		price    int
+		ruleID   int
+	}{
+		{
+			name:     "no rules charges the list price",
+			customer: returning,
+			price:    10,
+		},
+		{
+			name: "lowest price wins",
+			rules: []model.PricingRule{
+				rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, 20),
+				rule(2, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 5),
+				rule(3, model.PricingAudienceEveryone, model.PricingDiscountPercent, 30),
+			},
+			customer: returning,
+			price:    5,
+			ruleID:   2,
+		},
+		{
+			name: "tie goes to the lowest rule ID",
+			rules: []model.PricingRule{
+				rule(4, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 6),
+				rule(2, model.PricingAudienceEveryone, model.PricingDiscountPercent, 40),
+				rule(3, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 6),
+			},
+			customer: returning,
+			price:    6,
+			ruleID:   2,
+		},
+		{
+			name:     "rule that does not lower the price is not applied",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, 0)},
+			customer: returning,
+			price:    10,
+		},
+		{
+			name:     "inactive rules do not apply",
+			rules:    []model.PricingRule{{ID: 1, Audience: model.PricingAudienceEveryone, DiscountType: model.PricingDiscountFixedPrice, Value: 1}},
+			customer: returning,
+			price:    10,
+		},
+		{
+			name: "rules for other templates do not apply",
+			rules: []model.PricingRule{func() model.PricingRule {
+				r := rule(1, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 1)
+				r.TemplateID = intPtr(2)
+				return r
+			}()},
+			customer: returning,
+			price:    10,
+		},
+		{
+			name: "rules outside their window do not apply",
+			rules: []model.PricingRule{
+				func() model.PricingRule {
+					r := rule(1, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 1)
+					r.StartsAt = timePtr(now.Add(time.Hour))
+					return r
+				}(),
+				func() model.PricingRule {
+					r := rule(2, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 2)
+					r.EndsAt = timePtr(now)
+					return r
+				}(),
+			},
+			customer: returning,
+			price:    10,
+		},
+		{
+			name: "anonymous customers only get rules for everyone",
+			rules: []model.PricingRule{
+				rule(1, model.PricingAudienceFirstGeneration, model.PricingDiscountFixedPrice, 0),
+				rule(2, model.PricingAudienceNewUser, model.PricingDiscountFixedPrice, 1),
+				rule(3, model.PricingAudienceMember, model.PricingDiscountFixedPrice, 2),
+				rule(4, model.PricingAudienceEveryone, model.PricingDiscountPercent, 10),
+			},
+			customer: Customer{Anonymous: true},
+			price:    9,
+			ruleID:   4,
+		},
+		{
+			name:     "new user inside the window",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceNewUser, model.PricingDiscountFixedPrice, 3)},
+			customer: Customer{CreatedAt: now.Add(-7*24*time.Hour + time.Second), HasGenerated: true},
+			price:    3,
+			ruleID:   1,
+		},
+		{
+			name:     "new user window has ended",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceNewUser, model.PricingDiscountFixedPrice, 3)},
+			customer: Customer{CreatedAt: now.Add(-7 * 24 * time.Hour), HasGenerated: true},
+			price:    10,
+		},
+		{
+			name:     "member",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceMember, model.PricingDiscountPercent, 50)},
+			customer: Customer{CreatedAt: returning.CreatedAt, HasGenerated: true, MemberUntil: timePtr(now.Add(time.Hour))},
+			price:    5,
+			ruleID:   1,
+		},
+		{
+			name:     "expired membership",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceMember, model.PricingDiscountPercent, 50)},
+			customer: Customer{CreatedAt: returning.CreatedAt, HasGenerated: true, MemberUntil: timePtr(now)},
+			price:    10,
+		},
+		{
+			name:     "first generation rule after generating",
+			rules:    []model.PricingRule{rule(1, model.PricingAudienceFirstGeneration, model.PricingDiscountFixedPrice, 0)},
+			customer: returning,
+			price:    10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := engine.Quote(template, tt.customer, tt.rules, now)
+			if quote.ListPrice != template.CreditCost {
+				t.Errorf("ListPrice = %d, want %d", quote.ListPrice, template.CreditCost)
+			}
+			if quote.Price != tt.price {
+				t.Errorf("Price = %d, want %d", quote.Price, tt.price)
+			}
+			if quote.ExtraVariantPrice != tt.price {
+				t.Errorf("ExtraVariantPrice = %d, want %d", quote.ExtraVariantPrice, tt.price)
+			}
+			ruleID := 0
+			if quote.Rule != nil {
+				ruleID = quote.Rule.ID
+			}
+			if ruleID != tt.ruleID {
+				t.Errorf("Rule ID = %d, want %d", ruleID, tt.ruleID)
+			}
+		})
+	}
+}
+
+func TestQuoteFirstGeneration(t *testing.T) {
+	engine := NewEngine(7 * 24 * time.Hour)
+	template := &model.Template{ID: 1, CreditCost: 10}
+	firstTimer := Customer{CreatedAt: now.Add(-30 * 24 * time.Hour)}
+	free := rule(1, model.PricingAudienceFirstGeneration, model.PricingDiscountFixedPrice, 0)
+
+	tests := []struct {
+		name       string
+		rules      []model.PricingRule
+		customer   Customer
+		variants   int
+		price      int
+		extraPrice int
+		total      int
+	}{
+		{
+			name:       "first generation is free",
+			rules:      []model.PricingRule{free},
+			customer:   firstTimer,
+			variants:   1,
+			price:      0,
+			extraPrice: 10,
+			total:      0,
+		},
+		{
+			name:       "only one variant of the first generation is free",
+			rules:      []model.PricingRule{free},
+			customer:   firstTimer,
+			variants:   4,
+			price:      0,
+			extraPrice: 10,
+			total:      30,
+		},
+		{
+			name:       "later variants get the next best rule",
+			rules:      []model.PricingRule{free, rule(2, model.PricingAudienceEveryone, model.PricingDiscountPercent, 20)},
+			customer:   firstTimer,
+			variants:   3,
+			price:      0,
+			extraPrice: 8,
+			total:      16,
+		},
+		{
+			name:       "new user pricing still applies to later variants",
+			rules:      []model.PricingRule{rule(2, model.PricingAudienceNewUser, model.PricingDiscountFixedPrice, 3), free},
+			customer:   Customer{CreatedAt: now.Add(-time.Hour)},
+			variants:   2,
+			price:      0,
+			extraPrice: 3,
+			total:      3,
+		},
+		{
+			name:       "other rules price every variant the same",
+			rules:      []model.PricingRule{rule(2, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, 4)},
+			customer:   firstTimer,
+			variants:   3,
+			price:      4,
+			extraPrice: 4,
+			total:      12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := engine.Quote(template, tt.customer, tt.rules, now)
+			if quote.Price != tt.price {
+				t.Errorf("Price = %d, want %d", quote.Price, tt.price)
+			}
+			if quote.ExtraVariantPrice != tt.extraPrice {
+				t.Errorf("ExtraVariantPrice = %d, want %d", quote.ExtraVariantPrice, tt.extraPrice)
+			}
+			if got := quote.Total(tt.variants); got != tt.total {
+				t.Errorf("Total(%d) = %d, want %d", tt.variants, got, tt.total)
+			}
+		})
+	}
+}
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		discountType model.PricingDiscountType
+		value        int
+		listPrice    int
+		want         int
+	}{
+		{"percent floors", model.PricingDiscountPercent, 15, 9, 7},
+		{"percent floors to zero", model.PricingDiscountPercent, 99, 1, 0},
+		{"full percent is free", model.PricingDiscountPercent, 100, 9, 0},
+		{"fixed price", model.PricingDiscountFixedPrice, 4, 9, 4},
+		{"fixed price never raises the price", model.PricingDiscountFixedPrice, 12, 9, 9},
+		{"unknown discount type keeps the list price", "bogus", 1, 9, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rule(1, model.PricingAudienceEveryone, tt.discountType, tt.value)
+			if got := Price(&r, tt.listPrice); got != tt.want {
+				t.Errorf("Price = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  model.PricingRule
+		field string
+	}{
+		{
+			name: "valid percent",
+			rule: rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, 100),
+		},
+		{
+			name: "valid fixed price",
+			rule: rule(1, model.PricingAudienceMember, model.PricingDiscountFixedPrice, 0),
+		},
+		{
+			name:  "unknown audience",
+			rule:  rule(1, "vip", model.PricingDiscountPercent, 10),
+			field: "audience",
+		},
+		{
+			name:  "unknown discount type",
+			rule:  rule(1, model.PricingAudienceEveryone, "bogus", 10),
+			field: "discount_type",
+		},
+		{
+			name:  "percent above 100",
+			rule:  rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, 101),
+			field: "value",
+		},
+		{
+			name:  "negative percent",
+			rule:  rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, -1),
+			field: "value",
+		},
+		{
+			name:  "negative fixed price",
+			rule:  rule(1, model.PricingAudienceEveryone, model.PricingDiscountFixedPrice, -1),
+			field: "value",
+		},
+		{
+			name: "window ends before it starts",
+			rule: func() model.PricingRule {
+				r := rule(1, model.PricingAudienceEveryone, model.PricingDiscountPercent, 10)
+				r.StartsAt = timePtr(now)
+				r.EndsAt = timePtr(now)
+				return r
+			}(),
+			field: "ends_at",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.rule)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Validate() = %v, want a *FieldError", err)
+			}
+			if fieldErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", fieldErr.Field, tt.field)
+			}
+		})
+	}
+}
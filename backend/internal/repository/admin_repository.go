@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"github.com/45ai/backend/internal/model"
+)
+
+// AdminRepository defines the interface for admin account data access
+type AdminRepository interface {
+	// Create creates a new admin account. A taken username returns ErrDuplicateKey.
+	Create(ctx context.Context, admin *model.AdminUser) error
+	
+	// GetByID retrieves an admin by ID
+	GetByID(ctx context.Context, id int64) (*model.AdminUser, error)
+	
+	// GetByUsername retrieves an admin by username
+	GetByUsername(ctx context.Context, username string) (*model.AdminUser, error)
+	
+	// List retrieves all admin accounts
+	List(ctx context.Context) ([]model.AdminUser, error)
+	
+	// UpdateRoles replaces an admin's roles
+	UpdateRoles(ctx context.Context, id int64, roles []model.AdminRole) error
+	
+	// SetDisabled disables or re-enables an admin account
+	SetDisabled(ctx context.Context, id int64, disabled bool) error
+	
+	// UpdateLastLogin records a successful login
+	UpdateLastLogin(ctx context.Context, id int64) error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+const adminColumns = "id, username, password_hash, roles, disabled_at, last_login_at, created_at, updated_at"
+
+type adminRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAdminRepository(db *sql.DB) AdminRepository {
+	return &adminRepositoryImpl{db: db}
+}
+
+func (r *adminRepositoryImpl) Create(ctx context.Context, admin *model.AdminUser) error {
+	query := "INSERT INTO admin_users (username, password_hash, roles) VALUES (?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, admin.Username, admin.PasswordHash, joinRoles(admin.Roles))
+	if err != nil {
+		return mapDuplicateKey(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	admin.ID = id
+	return nil
+}
+
+func (r *adminRepositoryImpl) GetByID(ctx context.Context, id int64) (*model.AdminUser, error) {
+	query := "SELECT " + adminColumns + " FROM admin_users WHERE id = ?"
+	return scanAdmin(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *adminRepositoryImpl) GetByUsername(ctx context.Context, username string) (*model.AdminUser, error) {
+	query := "SELECT " + adminColumns + " FROM admin_users WHERE username = ?"
+	return scanAdmin(r.db.QueryRowContext(ctx, query, username))
+}
+
+func (r *adminRepositoryImpl) List(ctx context.Context) ([]model.AdminUser, error) {
+	query := "SELECT " + adminColumns + " FROM admin_users ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []model.AdminUser
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, *admin)
+	}
+	return admins, rows.Err()
+}
+
+func (r *adminRepositoryImpl) UpdateRoles(ctx context.Context, id int64, roles []model.AdminRole) error {
+	query := "UPDATE admin_users SET roles = ? WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, joinRoles(roles), id)
+	return err
+}
+
+func (r *adminRepositoryImpl) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	query := "UPDATE admin_users SET disabled_at = NULL WHERE id = ?"
+	if disabled {
+		query = "UPDATE admin_users SET disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	}
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *adminRepositoryImpl) UpdateLastLogin(ctx context.Context, id int64) error {
+	query := "UPDATE admin_users SET last_login_at = CURRENT_TIMESTAMP WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAdmin(row rowScanner) (*model.AdminUser, error) {
+	admin := &model.AdminUser{}
+	var roles string
+	err := row.Scan(&admin.ID, &admin.Username, &admin.PasswordHash, &roles, &admin.DisabledAt, &admin.LastLoginAt, &admin.CreatedAt, &admin.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range strings.Split(roles, ",") {
+		if role != "" {
+			admin.Roles = append(admin.Roles, model.AdminRole(role))
+		}
+	}
+	return admin, nil
+}
+
+func joinRoles(roles []model.AdminRole) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ",")
+}
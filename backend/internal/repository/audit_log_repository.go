@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"github.com/45ai/backend/internal/model"
+)
+
+// AuditLogRepository defines the interface for admin audit log data access
+type AuditLogRepository interface {
+	// Create appends an entry to the audit log
+	Create(ctx context.Context, entry *model.AuditLog) error
+	
+	// List retrieves entries matching the filter, newest first
+	List(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLog, error)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+type auditLogRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepositoryImpl{db: db}
+}
+
+func (r *auditLogRepositoryImpl) Create(ctx context.Context, entry *model.AuditLog) error {
+	query := "INSERT INTO admin_audit_logs (admin_id, action, target_type, target_id, status_code, ip, details) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)"
+	var details interface{}
+	if len(entry.Details) > 0 {
+		details = string(entry.Details)
+	}
+	result, err := r.db.ExecContext(ctx, query, entry.AdminID, entry.Action, entry.TargetType, entry.TargetID, entry.StatusCode, entry.IP, details)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	return nil
+}
+
+func (r *auditLogRepositoryImpl) List(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLog, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.AdminID != nil {
+		conditions = append(conditions, "admin_id = ?")
+		args = append(args, *filter.AdminID)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+
+	query := "SELECT id, admin_id, action, COALESCE(target_type, ''), COALESCE(target_id, ''), status_code, ip, details, created_at FROM admin_audit_logs"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditLog
+	for rows.Next() {
+		var entry model.AuditLog
+		var details []byte
+		if err := rows.Scan(&entry.ID, &entry.AdminID, &entry.Action, &entry.TargetType, &entry.TargetID, &entry.StatusCode, &entry.IP, &details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.Details = details
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
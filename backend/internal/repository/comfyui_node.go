@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// ErrComfyUINoWorkflow is returned when a template has no ComfyUI workflow to render
+var ErrComfyUINoWorkflow = errors.New("template has no comfyui workflow")
+
+// httpComfyUINode talks to a ComfyUI server over its HTTP API
+type httpComfyUINode struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newHTTPComfyUINode(baseURL, apiKey string, client *http.Client) *httpComfyUINode {
+	return &httpComfyUINode{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  client,
+	}
+}
+
+func (n *httpComfyUINode) GenerateImage(ctx context.Context, promptID string, templateID int, imageData io.Reader, seed int64) ([]string, error) {
+	// Templates do not carry ComfyUI workflows yet, so there is no prompt to queue
+	return nil, fmt.Errorf("%w: template %d", ErrComfyUINoWorkflow, templateID)
+}
+
+func (n *httpComfyUINode) Cancel(ctx context.Context, promptID string) error {
+	queue, err := n.getQueue(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case queue.Running.contains(promptID):
+		// A bare /interrupt stops whatever is running; newer servers also check
+		// the prompt ID in case the prompt finished since the queue was read
+		return n.postJSON(ctx, "/interrupt", map[string]string{"prompt_id": promptID})
+	case queue.Pending.contains(promptID):
+		return n.postJSON(ctx, "/queue", map[string][]string{"delete": {promptID}})
+	}
+	// The prompt already finished or never reached the node
+	return nil
+}
+
+func (n *httpComfyUINode) Probe(ctx context.Context) (*model.ComfyUIProbe, error) {
+	var stats struct {
+		Devices []struct {
+			VRAMTotal int64 `json:"vram_total"`
+			VRAMFree  int64 `json:"vram_free"`
+		} `json:"devices"`
+	}
+	if err := n.getJSON(ctx, "/system_stats", &stats); err != nil {
+		return nil, err
+	}
+
+	queue, err := n.getQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &model.ComfyUIProbe{
+		QueueRunning: len(queue.Running),
+		QueuePending: len(queue.Pending),
+	}
+	for _, device := range stats.Devices {
+		probe.VRAMTotal += device.VRAMTotal
+		probe.VRAMFree += device.VRAMFree
+	}
+	return probe, nil
+}
+
+// comfyUIQueue is the response of GET /queue
+type comfyUIQueue struct {
+	Running comfyUIQueueEntries `json:"queue_running"`
+	Pending comfyUIQueueEntries `json:"queue_pending"`
+}
+
+// comfyUIQueueEntries are queued prompts, each an array whose second element is the prompt ID
+type comfyUIQueueEntries []json.RawMessage
+
+func (e comfyUIQueueEntries) contains(promptID string) bool {
+	for _, raw := range e {
+		var entry []json.RawMessage
+		if err := json.Unmarshal(raw, &entry); err != nil || len(entry) < 2 {
+			continue
+		}
+		var id string
+		if err := json.Unmarshal(entry[1], &id); err == nil && id == promptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *httpComfyUINode) getQueue(ctx context.Context) (*comfyUIQueue, error) {
+	var queue comfyUIQueue
+	if err := n.getJSON(ctx, "/queue", &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+// newPromptID returns a random UUID. ComfyUI accepts client chosen prompt IDs,
+// which lets the pool find and cancel its own prompt on a shared node.
+func newPromptID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate prompt id: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// getJSON performs a GET request against the node and decodes the JSON response
+func (n *httpComfyUINode) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	return n.do(req, out)
+}
+
+// postJSON performs a POST request against the node with a JSON body
+func (n *httpComfyUINode) postJSON(ctx context.Context, path string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return n.do(req, nil)
+}
+
+func (n *httpComfyUINode) do(req *http.Request, out interface{}) error {
+	if n.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+n.apiKey)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("%w: %v", ErrComfyUIUnavailable, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &ComfyUIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrComfyUIUnavailable, err)
+	}
+	return nil
+}
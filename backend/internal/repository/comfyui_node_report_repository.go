@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// ComfyUINodeReportRepository stores the state of the ComfyUI nodes as each worker sees it
+type ComfyUINodeReportRepository interface {
+	// Report stores the worker's current status of each of its nodes
+	Report(ctx context.Context, worker string, nodes []model.ComfyUINodeStatus) error
+	
+	// List returns the statuses reported within maxAge, by worker and node URL
+	List(ctx context.Context, maxAge time.Duration) ([]model.ComfyUINodeStatus, error)
+	
+	// PurgeStale deletes statuses that were not reported within maxAge
+	PurgeStale(ctx context.Context, maxAge time.Duration) (int64, error)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+type comfyUINodeReportRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewComfyUINodeReportRepository(db *sql.DB) ComfyUINodeReportRepository {
+	return &comfyUINodeReportRepositoryImpl{db: db}
+}
+
+func (r *comfyUINodeReportRepositoryImpl) Report(ctx context.Context, worker string, nodes []model.ComfyUINodeStatus) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(nodes))
+	args := make([]interface{}, 0, len(nodes)*3)
+	for i, node := range nodes {
+		status, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		placeholders[i] = "(?, ?, ?, NOW(3))"
+		args = append(args, worker, node.URL, status)
+	}
+	query := "INSERT INTO comfyui_node_reports (worker, url, status, reported_at) VALUES " + strings.Join(placeholders, ", ") +
+		" ON DUPLICATE KEY UPDATE status = VALUES(status), reported_at = VALUES(reported_at)"
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *comfyUINodeReportRepositoryImpl) List(ctx context.Context, maxAge time.Duration) ([]model.ComfyUINodeStatus, error) {
+	query := `SELECT worker, status, reported_at FROM comfyui_node_reports
+		WHERE reported_at > NOW(3) - INTERVAL ? MICROSECOND ORDER BY worker, url`
+	rows, err := r.db.QueryContext(ctx, query, maxAge.Microseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []model.ComfyUINodeStatus
+	for rows.Next() {
+		var worker string
+		var status []byte
+		var reportedAt time.Time
+		if err := rows.Scan(&worker, &status, &reportedAt); err != nil {
+			return nil, err
+		}
+		var node model.ComfyUINodeStatus
+		if err := json.Unmarshal(status, &node); err != nil {
+			return nil, err
+		}
+		node.Worker = worker
+		node.ReportedAt = &reportedAt
+		statuses = append(statuses, node)
+	}
+	return statuses, rows.Err()
+}
+
+func (r *comfyUINodeReportRepositoryImpl) PurgeStale(ctx context.Context, maxAge time.Duration) (int64, error) {
+	query := "DELETE FROM comfyui_node_reports WHERE reported_at <= NOW(3) - INTERVAL ? MICROSECOND"
+	result, err := r.db.ExecContext(ctx, query, maxAge.Microseconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
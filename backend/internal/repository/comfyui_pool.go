@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+)
+
+// comfyUIPoolNode is a node and what the pool knows about it
+type comfyUIPoolNode struct {
+	url      string
+	capacity int
+	node     comfyUINode
+
+	inFlight     int
+	failures     int
+	lastError    string
+	lastProbeAt  time.Time
+	ejectedUntil time.Time
+	probe        model.ComfyUIProbe
+}
+
+// load is the node's queue depth. The last probe also counts renders other
+// processes sent, so the larger of the two is the best estimate.
+func (n *comfyUIPoolNode) load() int {
+	if depth := n.probe.QueueDepth(); depth > n.inFlight {
+		return depth
+	}
+	return n.inFlight
+}
+
+// comfyUIPool routes renders to the least loaded node that is not ejected.
+// A node that fails FailureThreshold times in a row, in renders or health
+// probes, is ejected until a probe succeeds after EjectDuration.
+type comfyUIPool struct {
+	cfg   config.ComfyUIConfig
+	mutex sync.Mutex
+	nodes []*comfyUIPoolNode
+}
+
+func newComfyUIPool(cfg config.ComfyUIConfig, nodes []comfyUINode) *comfyUIPool {
+	p := &comfyUIPool{cfg: cfg}
+	for i, node := range nodes {
+		p.nodes = append(p.nodes, &comfyUIPoolNode{
+			url:      cfg.Nodes[i].URL,
+			capacity: cfg.Nodes[i].Capacity,
+			node:     node,
+		})
+	}
+	return p
+}
+
+func (p *comfyUIPool) GenerateImage(ctx context.Context, templateID int, imageData io.Reader, seed int64) ([]string, error) {
+	promptID, err := newPromptID()
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := node.node.GenerateImage(ctx, promptID, templateID, imageData, seed)
+	if err != nil && ctx.Err() != nil {
+		// The node keeps rendering after the request is abandoned. Other
+		// workers share the node, so only this render's prompt is cancelled.
+		cancelCtx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if cancelErr := node.node.Cancel(cancelCtx, promptID); cancelErr != nil {
+			log.Printf("Failed to cancel prompt %s on ComfyUI node %s: %v", promptID, node.url, cancelErr)
+		}
+		cancel()
+	}
+
+	p.release(node, err)
+	return images, err
+}
+
+func (p *comfyUIPool) RunHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, node := range p.nodes {
+			wg.Add(1)
+			go func(node *comfyUIPoolNode) {
+				defer wg.Done()
+				p.probe(ctx, node)
+			}(node)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *comfyUIPool) Nodes() []model.ComfyUINodeStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	statuses := make([]model.ComfyUINodeStatus, len(p.nodes))
+	for i, node := range p.nodes {
+		status := model.ComfyUINodeStatus{
+			URL:                 node.url,
+			State:               model.ComfyUINodeHealthy,
+			Capacity:            node.capacity,
+			InFlight:            node.inFlight,
+			ConsecutiveFailures: node.failures,
+			LastError:           node.lastError,
+			Probe:               node.probe,
+		}
+		switch {
+		case !node.ejectedUntil.IsZero():
+			status.State = model.ComfyUINodeEjected
+			ejectedUntil := node.ejectedUntil
+			if ejectedUntil.Before(now) {
+				// Waiting for a successful probe
+				ejectedUntil = now
+			}
+			status.EjectedUntil = &ejectedUntil
+		case node.failures > 0:
+			status.State = model.ComfyUINodeDegraded
+		}
+		if !node.lastProbeAt.IsZero() {
+			lastProbeAt := node.lastProbeAt
+			status.LastProbeAt = &lastProbeAt
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// acquire reserves a render slot on the least loaded node with spare capacity
+func (p *comfyUIPool) acquire() (*comfyUIPoolNode, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var best *comfyUIPoolNode
+	for _, node := range p.nodes {
+		if !node.ejectedUntil.IsZero() || node.load() >= node.capacity {
+			continue
+		}
+		if best == nil || node.load() < best.load() || node.load() == best.load() && node.inFlight < best.inFlight {
+			best = node
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no healthy node has capacity", ErrComfyUIUnavailable)
+	}
+	best.inFlight++
+	return best, nil
+}
+
+// release frees the slot taken by acquire and records the outcome of the render
+func (p *comfyUIPool) release(node *comfyUIPoolNode, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	node.inFlight--
+	// The render has left the node's queue since the last probe
+	if node.probe.QueueRunning > 0 {
+		node.probe.QueueRunning--
+	}
+
+	switch {
+	case err == nil:
+		p.recordSuccess(node)
+	case errors.Is(err, ErrComfyUIUnavailable), errors.Is(err, ErrComfyUIOutOfMemory), errors.Is(err, context.DeadlineExceeded):
+		p.recordFailure(node, err)
+	}
+}
+
+func (p *comfyUIPool) probe(ctx context.Context, node *comfyUIPoolNode) {
+	probeCtx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	result, err := node.node.Probe(probeCtx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	node.lastProbeAt = time.Now()
+	if err != nil {
+		p.recordFailure(node, err)
+		return
+	}
+	node.probe = *result
+	p.recordSuccess(node)
+}
+
+// recordSuccess resets the failure count; an ejected node is restored once its
+// ejection has run out. The caller holds the mutex.
+func (p *comfyUIPool) recordSuccess(node *comfyUIPoolNode) {
+	node.failures = 0
+	node.lastError = ""
+	if !node.ejectedUntil.IsZero() && !time.Now().Before(node.ejectedUntil) {
+		log.Printf("ComfyUI node %s is healthy again", node.url)
+		node.ejectedUntil = time.Time{}
+	}
+}
+
+// recordFailure counts a failure and ejects the node once the threshold is
+// reached; further failures extend the ejection. The caller holds the mutex.
+func (p *comfyUIPool) recordFailure(node *comfyUIPoolNode, err error) {
+	node.failures++
+	node.lastError = err.Error()
+	if node.failures < p.cfg.FailureThreshold {
+		return
+	}
+	if node.ejectedUntil.IsZero() {
+		log.Printf("Ejecting ComfyUI node %s after %d consecutive failures: %v", node.url, node.failures, err)
+	}
+	node.ejectedUntil = time.Now().Add(p.cfg.EjectDuration)
+}
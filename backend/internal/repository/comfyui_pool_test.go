@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+)
+
+// fakeComfyUINode renders until ctx is done when block is set, and records
+// the prompts it was asked to cancel
+type fakeComfyUINode struct {
+	mutex     sync.Mutex
+	block     bool
+	probe     model.ComfyUIProbe
+	probeErr  error
+	started   chan string
+	cancelled []string
+}
+
+func (n *fakeComfyUINode) GenerateImage(ctx context.Context, promptID string, templateID int, imageData io.Reader, seed int64) ([]string, error) {
+	if n.started != nil {
+		n.started <- promptID
+	}
+	if n.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return []string{promptID}, nil
+}
+
+func (n *fakeComfyUINode) Cancel(ctx context.Context, promptID string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.cancelled = append(n.cancelled, promptID)
+	return nil
+}
+
+func (n *fakeComfyUINode) Probe(ctx context.Context) (*model.ComfyUIProbe, error) {
+	if n.probeErr != nil {
+		return nil, n.probeErr
+	}
+	probe := n.probe
+	return &probe, nil
+}
+
+func (n *fakeComfyUINode) cancelledPrompts() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return append([]string(nil), n.cancelled...)
+}
+
+// newFakePool builds a pool of fake nodes with the given capacities
+func newFakePool(capacities ...int) (*comfyUIPool, []*fakeComfyUINode) {
+	cfg := config.ComfyUIConfig{Timeout: time.Second, FailureThreshold: 2, EjectDuration: time.Hour}
+	fakes := make([]*fakeComfyUINode, len(capacities))
+	nodes := make([]comfyUINode, len(capacities))
+	for i, capacity := range capacities {
+		cfg.Nodes = append(cfg.Nodes, config.ComfyUINodeConfig{URL: string(rune('a' + i)), Capacity: capacity})
+		fakes[i] = &fakeComfyUINode{}
+		nodes[i] = fakes[i]
+	}
+	return newComfyUIPool(cfg, nodes), fakes
+}
+
+func TestPoolAcquireLeastQueueDepth(t *testing.T) {
+	pool, fakes := newFakePool(3, 3, 3)
+	// Other processes have queued renders on a and c
+	fakes[0].probe = model.ComfyUIProbe{QueuePending: 2}
+	fakes[2].probe = model.ComfyUIProbe{QueueRunning: 1}
+	for _, node := range pool.nodes {
+		pool.probe(context.Background(), node)
+	}
+
+	// Ties in queue depth go to the node with fewer renders from this pool,
+	// then to the first node
+	want := []string{"b", "c", "b", "c", "a"}
+	for i, url := range want {
+		node, err := pool.acquire()
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		if node.url != url {
+			t.Errorf("acquire %d = %s, want %s", i, node.url, url)
+		}
+	}
+}
+
+func TestPoolCapacity(t *testing.T) {
+	pool, _ := newFakePool(2)
+
+	first, err := pool.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.acquire(); !errors.Is(err, ErrComfyUIUnavailable) {
+		t.Fatalf("acquire beyond capacity error = %v, want %v", err, ErrComfyUIUnavailable)
+	}
+
+	pool.release(first, nil)
+	if _, err := pool.acquire(); err != nil {
+		t.Errorf("acquire after release error = %v", err)
+	}
+}
+
+func TestPoolEjection(t *testing.T) {
+	pool, fakes := newFakePool(1, 1)
+	a := pool.nodes[0]
+
+	// Failures that are not the node's fault do not count
+	pool.release(mustAcquire(t, pool, "a"), ErrComfyUINoWorkflow)
+	pool.release(mustAcquire(t, pool, "a"), context.Canceled)
+	if a.failures != 0 {
+		t.Fatalf("failures = %d, want 0", a.failures)
+	}
+
+	pool.release(mustAcquire(t, pool, "a"), &ComfyUIError{StatusCode: 502, Message: "bad gateway"})
+	if state := pool.Nodes()[0].State; state != model.ComfyUINodeDegraded {
+		t.Errorf("state after one failure = %s, want %s", state, model.ComfyUINodeDegraded)
+	}
+	pool.release(mustAcquire(t, pool, "a"), context.DeadlineExceeded)
+	if state := pool.Nodes()[0].State; state != model.ComfyUINodeEjected {
+		t.Fatalf("state after two failures = %s, want %s", state, model.ComfyUINodeEjected)
+	}
+
+	// Renders go elsewhere while the node is ejected
+	pool.release(mustAcquire(t, pool, "b"), nil)
+
+	// A failed probe after the ejection ran out extends it
+	a.ejectedUntil = time.Now().Add(-time.Second)
+	fakes[0].probeErr = ErrComfyUIUnavailable
+	pool.probe(context.Background(), a)
+	if !a.ejectedUntil.After(time.Now()) {
+		t.Fatal("failed probe did not extend the ejection")
+	}
+
+	// A successful probe does not end the ejection early, only once it ran out
+	fakes[0].probeErr = nil
+	pool.probe(context.Background(), a)
+	if a.ejectedUntil.IsZero() {
+		t.Fatal("node was readmitted before its ejection ran out")
+	}
+	a.ejectedUntil = time.Now().Add(-time.Second)
+	pool.probe(context.Background(), a)
+	if state := pool.Nodes()[0].State; state != model.ComfyUINodeHealthy {
+		t.Fatalf("state after a successful probe = %s, want %s", state, model.ComfyUINodeHealthy)
+	}
+	mustAcquire(t, pool, "a")
+}
+
+func mustAcquire(t *testing.T, pool *comfyUIPool, url string) *comfyUIPoolNode {
+	t.Helper()
+	node, err := pool.acquire()
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if node.url != url {
+		t.Fatalf("acquire = %s, want %s", node.url, url)
+	}
+	return node
+}
+
+func TestPoolCancelsOnlyOwnPrompt(t *testing.T) {
+	pool, fakes := newFakePool(2)
+	fakes[0].block = true
+	fakes[0].started = make(chan string, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	other, cancelOther := context.WithCancel(context.Background())
+	defer cancelOther()
+	done := make(chan error, 2)
+	go func() {
+		_, err := pool.GenerateImage(ctx, 1, strings.NewReader("image"), 1)
+		done <- err
+	}()
+	prompt := <-fakes[0].started
+	go func() {
+		_, err := pool.GenerateImage(other, 1, strings.NewReader("image"), 2)
+		done <- err
+	}()
+	otherPrompt := <-fakes[0].started
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, context.Canceled)
+	}
+	if got := fakes[0].cancelledPrompts(); len(got) != 1 || got[0] != prompt {
+		t.Errorf("cancelled = %v, want [%s]", got, prompt)
+	}
+	if prompt == otherPrompt {
+		t.Errorf("renders share prompt ID %s", prompt)
+	}
+	if inFlight := pool.Nodes()[0].InFlight; inFlight != 1 {
+		t.Errorf("in flight = %d, want 1", inFlight)
+	}
+}
+
+func TestHTTPNodeCancel(t *testing.T) {
+	const queue = `{"queue_running": [[0, "running", {}]], "queue_pending": [[1, "pending", {}], [2, "other", {}]]}`
+
+	tests := []struct {
+		name     string
+		promptID string
+		want     string
+	}{
+		{"running prompt is interrupted", "running", `POST /interrupt {"prompt_id":"running"}`},
+		{"pending prompt is deleted", "pending", `POST /queue {"delete":["pending"]}`},
+		{"finished prompt is left alone", "finished", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/queue" {
+					io.WriteString(w, queue)
+					return
+				}
+				var body json.RawMessage
+				json.NewDecoder(r.Body).Decode(&body)
+				requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+			}))
+			defer server.Close()
+
+			node := newHTTPComfyUINode(server.URL, "", server.Client())
+			if err := node.Cancel(context.Background(), tt.promptID); err != nil {
+				t.Fatalf("Cancel() error = %v", err)
+			}
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+			if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+				t.Errorf("requests = %q, want %q", requests, want)
+			}
+		})
+	}
+}
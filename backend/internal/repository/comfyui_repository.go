@@ -2,25 +2,103 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"strings"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
 )
 
+var (
+	// ErrComfyUIUnavailable is returned when ComfyUI fails with a server error
+	ErrComfyUIUnavailable = errors.New("comfyui is unavailable")
+
+	// ErrComfyUIOutOfMemory is returned when the GPU runs out of memory during a render
+	ErrComfyUIOutOfMemory = errors.New("comfyui ran out of gpu memory")
+)
+
+// ComfyUIError is an error response from ComfyUI
+type ComfyUIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ComfyUIError) Error() string {
+	return fmt.Sprintf("comfyui error %d: %s", e.StatusCode, e.Message)
+}
+
+// Is maps server errors and out of memory failures to the sentinel errors above
+func (e *ComfyUIError) Is(target error) bool {
+	switch target {
+	case ErrComfyUIUnavailable:
+		return e.StatusCode >= 500
+	case ErrComfyUIOutOfMemory:
+		message := strings.ToLower(e.Message)
+		return strings.Contains(message, "out of memory") || strings.Contains(message, "outofmemory")
+	}
+	return false
+}
+
+// ComfyUIRepository renders templates on a pool of ComfyUI nodes
 type ComfyUIRepository interface {
-	GenerateImage(ctx context.Context, templateID int, imageData io.Reader) ([]string, error)
+	// GenerateImage renders a template for an image on the least busy healthy node.
+	// The same seed reproduces the same output. Cancelling ctx interrupts the render.
+	GenerateImage(ctx context.Context, templateID int, imageData io.Reader, seed int64) ([]string, error)
+	
+	// RunHealthChecks probes every node until ctx is cancelled
+	RunHealthChecks(ctx context.Context)
+	
+	// Nodes returns the state of every node in the pool
+	Nodes() []model.ComfyUINodeStatus
+}
+
+// comfyUINode is a single ComfyUI backend
+type comfyUINode interface {
+	// GenerateImage queues the render under promptID, so it can be cancelled alone
+	GenerateImage(ctx context.Context, promptID string, templateID int, imageData io.Reader, seed int64) ([]string, error)
+	
+	// Cancel interrupts the prompt if the node is running it, or removes it from
+	// the node's queue if it is still pending. Other prompts are left alone.
+	Cancel(ctx context.Context, promptID string) error
+	
+	// Probe reports the node's queue and GPU memory
+	Probe(ctx context.Context) (*model.ComfyUIProbe, error)
+}
+
+// NewComfyUIRepository creates a pool of the configured nodes. A nil client uses
+// a default one; in mock mode the nodes render placeholder images.
+func NewComfyUIRepository(cfg config.ComfyUIConfig, client *http.Client) ComfyUIRepository {
+	if client == nil {
+		client = &http.Client{}
+	}
+	nodes := make([]comfyUINode, len(cfg.Nodes))
+	for i, nodeCfg := range cfg.Nodes {
+		if cfg.Mock {
+			nodes[i] = &mockComfyUINode{}
+		} else {
+			nodes[i] = newHTTPComfyUINode(nodeCfg.URL, cfg.APIKey, client)
+		}
+	}
+	return newComfyUIPool(cfg, nodes)
 }
 
-type mockComfyUIRepository struct{}
+type mockComfyUINode struct{}
+
+func (n *mockComfyUINode) Cancel(ctx context.Context, promptID string) error {
+	return nil
+}
 
-func NewMockComfyUIRepository() ComfyUIRepository {
-	return &mockComfyUIRepository{}
+func (n *mockComfyUINode) Probe(ctx context.Context) (*model.ComfyUIProbe, error) {
+	return &model.ComfyUIProbe{}, nil
 }
 
-func (r *mockComfyUIRepository) GenerateImage(ctx context.Context, templateID int, imageData io.Reader) ([]string, error) {
+func (n *mockComfyUINode) GenerateImage(ctx context.Context, promptID string, templateID int, imageData io.Reader, seed int64) ([]string, error) {
 	// In a real implementation, this would call the ComfyUI API.
-	// For now, we'll just return some mock image URLs.
+	// For now, we'll just return a mock image URL per seed.
 	return []string{
-		"https://example.com/image1.png",
-		"https://example.com/image2.png",
-		"https://example.com/image3.png",
+		fmt.Sprintf("https://example.com/%d/%d.png", templateID, seed),
 	}, nil
-} 
\ No newline at end of file
+}
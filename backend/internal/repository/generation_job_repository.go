@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// ErrDailyLimitReached is returned when a job would take a user past their
+// daily generation limit
+var ErrDailyLimitReached = errors.New("daily generation limit reached")
+
+// GenerationCharge is what a new job costs the user
+type GenerationCharge struct {
+	Transaction *model.Transaction
+	// FirstGeneration fails the charge with ErrNotFirstGeneration if the user
+	// already has a generation transaction
+	FirstGeneration bool
+	// DailyLimit caps the variants of a user's jobs created today that were not
+	// cancelled or failed; 0 disables the limit
+	DailyLimit int
+}
+
+// GenerationJobRepository defines the interface for generation job data access
+type GenerationJobRepository interface {
+	// Create creates a new generation job
+	Create(ctx context.Context, job *model.GenerationJob) error
+	
+	// CreateWithCharge creates a job and charges the user for it in one
+	// transaction that holds the user's row lock, so concurrent requests cannot
+	// together overdraw the balance, share a first generation price or pass the
+	// daily limit. It fails with ErrInsufficientCredits, ErrNotFirstGeneration or
+	// ErrDailyLimitReached.
+	CreateWithCharge(ctx context.Context, job *model.GenerationJob, charge *GenerationCharge) error
+	
+	// CountVariantsToday counts the variants of the user's jobs created since
+	// midnight that were not cancelled or failed
+	CountVariantsToday(ctx context.Context, userID int64) (int, error)
+	
+	// GetByRequestID retrieves a generation job by its request ID
+	GetByRequestID(ctx context.Context, requestID string) (*model.GenerationJob, error)
+	
+	// Update stores the status, outputs, charge, retry time, error and timings of a job
+	Update(ctx context.Context, job *model.GenerationJob) error
+	
+	// Transition updates a job like Update, but only while it is in one of the from
+	// statuses. It returns false if the job had already moved on, e.g. was cancelled.
+	Transition(ctx context.Context, job *model.GenerationJob, from ...model.GenerationJobStatus) (bool, error)
+	
+	// TransitionAttempt is Transition for the worker that claimed attempt of a job.
+	// It returns false once the job was claimed again, so a worker whose lease
+	// expired cannot overwrite or refund a job another worker has taken over.
+	TransitionAttempt(ctx context.Context, job *model.GenerationJob, attempt int, from ...model.GenerationJobStatus) (bool, error)
+	
+	// GetQueue reads a snapshot of every queued job, including jobs waiting out a
+	// retry backoff. Jobs only carry their ID, request ID, user, template, lane and
+	// attempts.
+	GetQueue(ctx context.Context) (*model.GenerationQueue, error)
+	
+	// Claim lets pick choose from the first job of each user in each lane that may
+	// start now, marks it processing, counting an attempt, and leases it to the
+	// caller for lease. The returned job's Attempts is the attempt it was claimed
+	// for. Claims are serialized between instances. It returns nil
+	// when pick returns nil or the picked job was cancelled meanwhile.
+	Claim(ctx context.Context, lease time.Duration, pick func(*model.GenerationQueue) *model.GenerationJob) (*model.GenerationJob, error)
+	
+	// RenewLease extends the lease of a processing job
+	RenewLease(ctx context.Context, id int64, lease time.Duration) error
+	
+	// ListExpired retrieves processing jobs whose lease ran out
+	ListExpired(ctx context.Context) ([]model.GenerationJob, error)
+	
+	// LatencyStats reports latency percentiles per template for jobs completed since
+	// the given time, and how many of them finished within slaTarget
+	LatencyStats(ctx context.Context, since time.Time, slaTarget time.Duration) ([]model.GenerationLatencyStats, error)
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+const generationJobColumns = `id, request_id, user_id, template_id, parent_request_id, source_key, status, lane, variants,
+	credits_per_variant, credits_charged, attempts, next_attempt_at, pricing_rule_id, COALESCE(error, ''), created_at, updated_at, started_at, completed_at,
+	validation_ms, safety_ms, upload_ms, queue_wait_ms, render_ms, total_ms`
+
+// generationQueueColumns are the columns scheduling needs
+const generationQueueColumns = "id, request_id, user_id, template_id, lane, attempts"
+
+const (
+	// generationClaimLock is the MySQL named lock that serializes claims
+	generationClaimLock = "generation_jobs_claim"
+	// generationClaimLockWait is how long a claim waits for another one, in seconds
+	generationClaimLockWait = 5
+)
+
+type generationJobRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewGenerationJobRepository(db *sql.DB) GenerationJobRepository {
+	return &generationJobRepositoryImpl{db: db}
+}
+
+func (r *generationJobRepositoryImpl) Create(ctx context.Context, job *model.GenerationJob) error {
+	return insertGenerationJob(ctx, r.db, job)
+}
+
+func (r *generationJobRepositoryImpl) CreateWithCharge(ctx context.Context, job *model.GenerationJob, charge *GenerationCharge) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if charge.DailyLimit > 0 {
+		// The user's lock makes the count below see every job charged before this one
+		if err := lockUser(ctx, tx, job.UserID); err != nil {
+			return err
+		}
+		used, err := countVariantsToday(ctx, tx, job.UserID, " LOCK IN SHARE MODE")
+		if err != nil {
+			return err
+		}
+		if used+len(job.Variants) > charge.DailyLimit {
+			return ErrDailyLimitReached
+		}
+	}
+	if err := createWithCredits(ctx, tx, charge.Transaction, charge.FirstGeneration); err != nil {
+		return err
+	}
+	if err := insertGenerationJob(ctx, tx, job); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *generationJobRepositoryImpl) CountVariantsToday(ctx context.Context, userID int64) (int, error) {
+	return countVariantsToday(ctx, r.db, userID, "")
+}
+
+// countVariantsToday counts the variants of a user's jobs created since
+// midnight, in the database time zone, that were not cancelled or failed
+func countVariantsToday(ctx context.Context, q queryer, userID int64, lock string) (int, error) {
+	query := `SELECT COALESCE(SUM(JSON_LENGTH(variants)), 0) FROM generation_jobs
+		WHERE user_id = ? AND created_at >= CURDATE() AND status NOT IN (?, ?)` + lock
+	var count int
+	err := q.QueryRowContext(ctx, query, userID, model.GenerationStatusCancelled, model.GenerationStatusFailed).Scan(&count)
+	return count, err
+}
+
+func insertGenerationJob(ctx context.Context, db execer, job *model.GenerationJob) error {
+	variants, err := json.Marshal(job.Variants)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO generation_jobs (request_id, user_id, template_id, parent_request_id, source_key, status, lane, variants,
+		credits_per_variant, credits_charged, pricing_rule_id, validation_ms, safety_ms, upload_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.ExecContext(ctx, query, job.RequestID, job.UserID, job.TemplateID, job.ParentRequestID, job.SourceKey, job.Status, job.Lane, variants,
+		job.CreditsPerVariant, job.CreditsCharged, job.PricingRuleID, job.Timings.ValidationMS, job.Timings.SafetyMS, job.Timings.UploadMS)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	job.ID = id
+	return nil
+}
+
+func (r *generationJobRepositoryImpl) GetByRequestID(ctx context.Context, requestID string) (*model.GenerationJob, error) {
+	query := "SELECT " + generationJobColumns + " FROM generation_jobs WHERE request_id = ?"
+	return scanGenerationJob(r.db.QueryRowContext(ctx, query, requestID))
+}
+
+func (r *generationJobRepositoryImpl) Update(ctx context.Context, job *model.GenerationJob) error {
+	_, err := r.update(ctx, job, "")
+	return err
+}
+
+func (r *generationJobRepositoryImpl) Transition(ctx context.Context, job *model.GenerationJob, from ...model.GenerationJobStatus) (bool, error) {
+	return r.transition(ctx, job, "", nil, from)
+}
+
+func (r *generationJobRepositoryImpl) TransitionAttempt(ctx context.Context, job *model.GenerationJob, attempt int, from ...model.GenerationJobStatus) (bool, error) {
+	return r.transition(ctx, job, " AND attempts = ?", []interface{}{attempt}, from)
+}
+
+// transition updates a job that is in one of the from statuses and matches condition
+func (r *generationJobRepositoryImpl) transition(ctx context.Context, job *model.GenerationJob, condition string, args []interface{},
+	from []model.GenerationJobStatus) (bool, error) {
+	if len(from) == 0 {
+		return false, nil
+	}
+	condition += " AND status IN (?" + strings.Repeat(", ?", len(from)-1) + ")"
+	for _, status := range from {
+		args = append(args, status)
+	}
+	affected, err := r.update(ctx, job, condition, args...)
+	return affected > 0, err
+}
+
+// update writes the mutable fields of a job, restricted by an extra condition
+func (r *generationJobRepositoryImpl) update(ctx context.Context, job *model.GenerationJob, condition string, conditionArgs ...interface{}) (int64, error) {
+	variants, err := json.Marshal(job.Variants)
+	if err != nil {
+		return 0, err
+	}
+	query := `UPDATE generation_jobs SET status = ?, variants = ?, credits_charged = ?, next_attempt_at = ?, error = NULLIF(?, ''),
+		completed_at = ?, queue_wait_ms = ?, render_ms = ?, total_ms = ? WHERE id = ?` + condition
+	args := append([]interface{}{job.Status, variants, job.CreditsCharged, job.NextAttemptAt, job.Error,
+		job.CompletedAt, job.Timings.QueueWaitMS, job.Timings.RenderMS, job.Timings.TotalMS, job.ID}, conditionArgs...)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *generationJobRepositoryImpl) GetQueue(ctx context.Context) (*model.GenerationQueue, error) {
+	query := "SELECT " + generationQueueColumns + " FROM generation_jobs WHERE status = ? ORDER BY id"
+	return loadGenerationQueue(ctx, r.db, query, model.GenerationStatusQueued)
+}
+
+func (r *generationJobRepositoryImpl) Claim(ctx context.Context, lease time.Duration, pick func(*model.GenerationQueue) *model.GenerationJob) (*model.GenerationJob, error) {
+	// Named locks belong to a session, so the whole claim runs on one connection
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The lock serializes claims, so the in-flight counts pick sees stay true
+	// until the picked job is marked processing. Unlike locking the queued rows,
+	// it does not hold up jobs being queued or cancelled.
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", generationClaimLock, generationClaimLockWait).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		return nil, nil
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", generationClaimLock)
+
+	// Any job the scheduler starts is the first of its user in its lane, so
+	// those are all it needs to see
+	query := "SELECT " + generationQueueColumns + ` FROM generation_jobs WHERE id IN (
+			SELECT MIN(id) FROM generation_jobs
+			WHERE status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			GROUP BY user_id, lane
+		) ORDER BY id`
+	queue, err := loadGenerationQueue(ctx, conn, query, model.GenerationStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	job := pick(queue)
+	if job == nil {
+		return nil, nil
+	}
+
+	// Cancelling does not take the lock, so the job may have left the queue
+	query = `UPDATE generation_jobs SET status = ?, attempts = attempts + 1, started_at = NOW(3), locked_until = NOW() + INTERVAL ? SECOND
+		WHERE id = ? AND status = ?`
+	result, err := conn.ExecContext(ctx, query, model.GenerationStatusProcessing, int64(lease.Seconds()), job.ID, model.GenerationStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, err
+	}
+
+	now := time.Now()
+	job.Status = model.GenerationStatusProcessing
+	job.Attempts++
+	job.StartedAt = &now
+	return job, nil
+}
+
+func (r *generationJobRepositoryImpl) RenewLease(ctx context.Context, id int64, lease time.Duration) error {
+	query := "UPDATE generation_jobs SET locked_until = NOW() + INTERVAL ? SECOND WHERE id = ? AND status = ?"
+	_, err := r.db.ExecContext(ctx, query, int64(lease.Seconds()), id, model.GenerationStatusProcessing)
+	return err
+}
+
+func (r *generationJobRepositoryImpl) ListExpired(ctx context.Context) ([]model.GenerationJob, error) {
+	query := "SELECT " + generationJobColumns + ` FROM generation_jobs
+		WHERE status = ? AND (locked_until IS NULL OR locked_until <= NOW()) ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, model.GenerationStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []model.GenerationJob
+	for rows.Next() {
+		job, err := scanGenerationJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// LatencyStats ranks each template's completed jobs by every timing and reads the
+// nearest-rank percentiles, so only one row per template leaves the database
+func (r *generationJobRepositoryImpl) LatencyStats(ctx context.Context, since time.Time, slaTarget time.Duration) ([]model.GenerationLatencyStats, error) {
+	query := `SELECT template_id, jobs, SUM(total_ms <= ?),
+			MAX(CASE WHEN total_rank = CEIL(0.50 * jobs) THEN total_ms END),
+			MAX(CASE WHEN total_rank = CEIL(0.95 * jobs) THEN total_ms END),
+			MAX(CASE WHEN total_rank = CEIL(0.99 * jobs) THEN total_ms END),
+			MAX(CASE WHEN queue_wait_rank = CEIL(0.50 * jobs) THEN queue_wait_ms END),
+			MAX(CASE WHEN queue_wait_rank = CEIL(0.95 * jobs) THEN queue_wait_ms END),
+			MAX(CASE WHEN queue_wait_rank = CEIL(0.99 * jobs) THEN queue_wait_ms END),
+			MAX(CASE WHEN render_rank = CEIL(0.50 * jobs) THEN render_ms END),
+			MAX(CASE WHEN render_rank = CEIL(0.95 * jobs) THEN render_ms END),
+			MAX(CASE WHEN render_rank = CEIL(0.99 * jobs) THEN render_ms END)
+		FROM (
+			SELECT template_id, total_ms, queue_wait_ms, render_ms,
+				COUNT(*) OVER (PARTITION BY template_id) AS jobs,
+				ROW_NUMBER() OVER (PARTITION BY template_id ORDER BY total_ms) AS total_rank,
+				ROW_NUMBER() OVER (PARTITION BY template_id ORDER BY queue_wait_ms) AS queue_wait_rank,
+				ROW_NUMBER() OVER (PARTITION BY template_id ORDER BY render_ms) AS render_rank
+			FROM generation_jobs
+			WHERE status = ? AND completed_at >= ?
+		) ranked
+		GROUP BY template_id, jobs
+		ORDER BY template_id`
+	rows, err := r.db.QueryContext(ctx, query, slaTarget.Milliseconds(), model.GenerationStatusCompleted, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []model.GenerationLatencyStats{}
+	for rows.Next() {
+		var entry model.GenerationLatencyStats
+		var withinSLA int
+		err := rows.Scan(&entry.TemplateID, &entry.Jobs, &withinSLA,
+			&entry.Total.P50MS, &entry.Total.P95MS, &entry.Total.P99MS,
+			&entry.QueueWait.P50MS, &entry.QueueWait.P95MS, &entry.QueueWait.P99MS,
+			&entry.Render.P50MS, &entry.Render.P95MS, &entry.Render.P99MS)
+		if err != nil {
+			return nil, err
+		}
+		entry.WithinSLA = float64(withinSLA) / float64(entry.Jobs)
+		stats = append(stats, entry)
+	}
+	return stats, rows.Err()
+}
+
+// queryer is satisfied by *sql.DB, *sql.Tx and *sql.Conn
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// loadGenerationQueue reads the queued jobs query selects, in submission order,
+// and the in-flight counts and last start times of their users. Processing
+// jobs whose lease ran out are not counted as in flight.
+func loadGenerationQueue(ctx context.Context, q queryer, query string, args ...interface{}) (*model.GenerationQueue, error) {
+	queue := &model.GenerationQueue{
+		InFlight:    make(map[int64]int),
+		LastStarted: make(map[int64]time.Time),
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var job model.GenerationJob
+		if err := rows.Scan(&job.ID, &job.RequestID, &job.UserID, &job.TemplateID, &job.Lane, &job.Attempts); err != nil {
+			return nil, err
+		}
+		job.Status = model.GenerationStatusQueued
+		queue.Queued = append(queue.Queued, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(queue.Queued) == 0 {
+		return queue, nil
+	}
+
+	query = "SELECT user_id, COUNT(*) FROM generation_jobs WHERE status = ? AND locked_until > NOW() GROUP BY user_id"
+	rows, err = q.QueryContext(ctx, query, model.GenerationStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int64
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		queue.InFlight[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT user_id, MAX(started_at) FROM generation_jobs
+		WHERE started_at IS NOT NULL AND user_id IN (SELECT user_id FROM generation_jobs WHERE status = ?)
+		GROUP BY user_id`
+	rows, err = q.QueryContext(ctx, query, model.GenerationStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int64
+		var startedAt time.Time
+		if err := rows.Scan(&userID, &startedAt); err != nil {
+			return nil, err
+		}
+		queue.LastStarted[userID] = startedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+func scanGenerationJob(row rowScanner) (*model.GenerationJob, error) {
+	job := &model.GenerationJob{}
+	var variants []byte
+	err := row.Scan(&job.ID, &job.RequestID, &job.UserID, &job.TemplateID, &job.ParentRequestID, &job.SourceKey, &job.Status, &job.Lane, &variants,
+		&job.CreditsPerVariant, &job.CreditsCharged, &job.Attempts, &job.NextAttemptAt, &job.PricingRuleID, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.CompletedAt,
+		&job.Timings.ValidationMS, &job.Timings.SafetyMS, &job.Timings.UploadMS, &job.Timings.QueueWaitMS, &job.Timings.RenderMS, &job.Timings.TotalMS)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variants, &job.Variants); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// IdempotencyRepository defines the interface for idempotency key data access
+type IdempotencyRepository interface {
+	// Reserve stores a new in-progress record that expires after ttl and holds the
+	// key for lock. It returns false without storing anything when the key is
+	// already held in its scope; an in-progress record whose lock ran out no
+	// longer holds it.
+	Reserve(ctx context.Context, record *model.IdempotencyRecord, ttl, lock time.Duration) (bool, error)
+	
+	// GetByKey retrieves an unexpired record by scope and key
+	GetByKey(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error)
+	
+	// Complete stores the response of a reserved record and releases its lock
+	Complete(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error
+	
+	// Delete releases a record so its key can be used again
+	Delete(ctx context.Context, id int64) error
+	
+	// PurgeExpired deletes expired records
+	PurgeExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+const idempotencyColumns = `id, scope, idempotency_key, method, path, fingerprint, COALESCE(status_code, 0), COALESCE(content_type, ''),
+	response_body, created_at, expires_at`
+
+type idempotencyRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepositoryImpl{db: db}
+}
+
+func (r *idempotencyRepositoryImpl) Reserve(ctx context.Context, record *model.IdempotencyRecord, ttl, lock time.Duration) (bool, error) {
+	// An expired record would otherwise hold the key until the next purge, and
+	// one whose request died without settling it would hold it until it expires
+	query := `DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?
+		AND (expires_at <= NOW() OR (status_code IS NULL AND locked_until <= NOW()))`
+	if _, err := r.db.ExecContext(ctx, query, record.Scope, record.Key); err != nil {
+		return false, err
+	}
+
+	query = `INSERT IGNORE INTO idempotency_keys (scope, idempotency_key, method, path, fingerprint, locked_until, expires_at)
+		VALUES (?, ?, ?, ?, ?, NOW() + INTERVAL ? SECOND, NOW() + INTERVAL ? SECOND)`
+	result, err := r.db.ExecContext(ctx, query, record.Scope, record.Key, record.Method, record.Path, record.Fingerprint,
+		int64(lock.Seconds()), int64(ttl.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	record.ID = id
+	return true, nil
+}
+
+func (r *idempotencyRepositoryImpl) GetByKey(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	query := "SELECT " + idempotencyColumns + " FROM idempotency_keys WHERE scope = ? AND idempotency_key = ? AND expires_at > NOW()"
+	record := &model.IdempotencyRecord{}
+	err := r.db.QueryRowContext(ctx, query, scope, key).Scan(&record.ID, &record.Scope, &record.Key, &record.Method, &record.Path, &record.Fingerprint,
+		&record.StatusCode, &record.ContentType, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *idempotencyRepositoryImpl) Complete(ctx context.Context, id int64, statusCode int, contentType string, body []byte) error {
+	query := "UPDATE idempotency_keys SET status_code = ?, content_type = ?, response_body = ?, locked_until = NULL WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, statusCode, contentType, body, id)
+	return err
+}
+
+func (r *idempotencyRepositoryImpl) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE id = ?", id)
+	return err
+}
+
+func (r *idempotencyRepositoryImpl) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
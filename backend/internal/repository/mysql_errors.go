@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicateKey is returned when a write conflicts with a unique index
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// mysqlErrDuplicateEntry is ER_DUP_ENTRY
+const mysqlErrDuplicateEntry = 1062
+
+// mapDuplicateKey turns MySQL's duplicate entry error into ErrDuplicateKey
+func mapDuplicateKey(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return fmt.Errorf("%w: %s", ErrDuplicateKey, mysqlErr.Message)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// PricingRuleRepository defines the interface for pricing rule data access
+type PricingRuleRepository interface {
+	// List retrieves every pricing rule, newest first
+	List(ctx context.Context) ([]model.PricingRule, error)
+	
+	// ListActive retrieves active rules that have not ended yet
+	ListActive(ctx context.Context) ([]model.PricingRule, error)
+	
+	// GetByID retrieves a pricing rule by ID
+	GetByID(ctx context.Context, id int) (*model.PricingRule, error)
+	
+	// Create creates a new pricing rule
+	Create(ctx context.Context, rule *model.PricingRule) error
+	
+	// Update replaces a pricing rule
+	Update(ctx context.Context, rule *model.PricingRule) error
+	
+	// Delete deletes a pricing rule
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+const pricingRuleColumns = "id, name, template_id, audience, discount_type, value, starts_at, ends_at, is_active, created_at, updated_at"
+
+type pricingRuleRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewPricingRuleRepository(db *sql.DB) PricingRuleRepository {
+	return &pricingRuleRepositoryImpl{db: db}
+}
+
+func (r *pricingRuleRepositoryImpl) List(ctx context.Context) ([]model.PricingRule, error) {
+	query := "SELECT " + pricingRuleColumns + " FROM pricing_rules ORDER BY id DESC"
+	return r.query(ctx, query)
+}
+
+func (r *pricingRuleRepositoryImpl) ListActive(ctx context.Context) ([]model.PricingRule, error) {
+	query := "SELECT " + pricingRuleColumns + " FROM pricing_rules WHERE is_active = true AND (ends_at IS NULL OR ends_at > NOW()) ORDER BY id"
+	return r.query(ctx, query)
+}
+
+func (r *pricingRuleRepositoryImpl) GetByID(ctx context.Context, id int) (*model.PricingRule, error) {
+	query := "SELECT " + pricingRuleColumns + " FROM pricing_rules WHERE id = ?"
+	return scanPricingRule(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *pricingRuleRepositoryImpl) Create(ctx context.Context, rule *model.PricingRule) error {
+	query := `INSERT INTO pricing_rules (name, template_id, audience, discount_type, value, starts_at, ends_at, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, rule.Name, rule.TemplateID, rule.Audience, rule.DiscountType, rule.Value, rule.StartsAt, rule.EndsAt, rule.IsActive)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rule.ID = int(id)
+	return nil
+}
+
+func (r *pricingRuleRepositoryImpl) Update(ctx context.Context, rule *model.PricingRule) error {
+	query := `UPDATE pricing_rules SET name = ?, template_id = ?, audience = ?, discount_type = ?, value = ?,
+		starts_at = ?, ends_at = ?, is_active = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, rule.Name, rule.TemplateID, rule.Audience, rule.DiscountType, rule.Value, rule.StartsAt, rule.EndsAt, rule.IsActive, rule.ID)
+	return err
+}
+
+func (r *pricingRuleRepositoryImpl) Delete(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM pricing_rules WHERE id = ?", id)
+	return err
+}
+
+func (r *pricingRuleRepositoryImpl) query(ctx context.Context, query string, args ...interface{}) ([]model.PricingRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []model.PricingRule
+	for rows.Next() {
+		rule, err := scanPricingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+func scanPricingRule(row rowScanner) (*model.PricingRule, error) {
+	rule := &model.PricingRule{}
+	err := row.Scan(&rule.ID, &rule.Name, &rule.TemplateID, &rule.Audience, &rule.DiscountType, &rule.Value,
+		&rule.StartsAt, &rule.EndsAt, &rule.IsActive, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
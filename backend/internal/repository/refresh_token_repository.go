@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/45ai/backend/internal/model"
+)
+
+// RefreshTokenRepository defines the interface for refresh token data access
+type RefreshTokenRepository interface {
+	// Create stores a new refresh token
+	Create(ctx context.Context, token *model.RefreshToken) error
+	
+	// GetByHash retrieves a refresh token by the hash of its value
+	GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	
+	// Revoke revokes a single token and reports whether this call revoked it
+	Revoke(ctx context.Context, id int64) (bool, error)
+	
+	// RevokeFamily revokes every token rotated from the same login
+	RevokeFamily(ctx context.Context, familyID string) error
+	
+	// RevokeAllForUser revokes every refresh token belonging to a user
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
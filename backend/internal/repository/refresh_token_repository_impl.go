@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+type refreshTokenRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepositoryImpl{db: db}
+}
+
+func (r *refreshTokenRepositoryImpl) Create(ctx context.Context, token *model.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES (?, ?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	return nil
+}
+
+func (r *refreshTokenRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	query := "SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = ?"
+	row := r.db.QueryRowContext(ctx, query, tokenHash)
+	token := &model.RefreshToken{}
+	err := row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepositoryImpl) Revoke(ctx context.Context, id int64) (bool, error) {
+	// The revoked_at guard makes rotation atomic: only one concurrent caller wins
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL"
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = ? AND revoked_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RevocationRepository defines the interface for access token revocation data access
+type RevocationRepository interface {
+	// RevokeToken adds a single access token to the denylist
+	RevokeToken(ctx context.Context, jti string, userID int64, expiresAt time.Time) error
+	
+	// IsTokenRevoked checks whether an access token is on the denylist
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	
+	// RevokeUserSessions rejects every access token issued to a user at or before the given time
+	RevokeUserSessions(ctx context.Context, userID int64, before time.Time) error
+	
+	// GetUserRevokedBefore returns the user's revocation cutoff, or nil if there is none
+	GetUserRevokedBefore(ctx context.Context, userID int64) (*time.Time, error)
+	
+	// DeleteExpired removes denylist entries for tokens that have expired
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
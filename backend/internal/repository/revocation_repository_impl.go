@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type revocationRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRevocationRepository(db *sql.DB) RevocationRepository {
+	return &revocationRepositoryImpl{db: db}
+}
+
+func (r *revocationRepositoryImpl) RevokeToken(ctx context.Context, jti string, userID int64, expiresAt time.Time) error {
+	query := "INSERT IGNORE INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, jti, userID, expiresAt)
+	return err
+}
+
+func (r *revocationRepositoryImpl) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)"
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *revocationRepositoryImpl) RevokeUserSessions(ctx context.Context, userID int64, before time.Time) error {
+	query := "INSERT INTO session_revocations (user_id, revoked_before) VALUES (?, ?) ON DUPLICATE KEY UPDATE revoked_before = GREATEST(revoked_before, VALUES(revoked_before))"
+	_, err := r.db.ExecContext(ctx, query, userID, before)
+	return err
+}
+
+func (r *revocationRepositoryImpl) GetUserRevokedBefore(ctx context.Context, userID int64) (*time.Time, error) {
+	query := "SELECT revoked_before FROM session_revocations WHERE user_id = ?"
+	var before time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&before)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &before, nil
+}
+
+func (r *revocationRepositoryImpl) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := "DELETE FROM revoked_tokens WHERE expires_at < ?"
+	result, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
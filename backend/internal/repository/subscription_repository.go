@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+)
+
+// SubscriptionRepository defines the interface for subscribe message grant data access
+type SubscriptionRepository interface {
+	// CreateGrant records one accepted one-time subscription
+	CreateGrant(ctx context.Context, userID int64, templateID string) error
+	
+	// ConsumeGrant uses up the oldest unused grant and returns its ID, or 0 if there was none
+	ConsumeGrant(ctx context.Context, userID int64, templateID string) (int64, error)
+	
+	// RestoreGrant makes a consumed grant available again after its message could not be sent
+	RestoreGrant(ctx context.Context, grantID int64) error
+	
+	// CountAvailable returns the number of unused grants
+	CountAvailable(ctx context.Context, userID int64, templateID string) (int, error)
+	
+	// DeleteByUserID removes all grants for a user
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
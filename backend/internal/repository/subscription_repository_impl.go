@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type subscriptionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepository(db *sql.DB) SubscriptionRepository {
+	return &subscriptionRepositoryImpl{db: db}
+}
+
+func (r *subscriptionRepositoryImpl) CreateGrant(ctx context.Context, userID int64, templateID string) error {
+	query := "INSERT INTO subscription_grants (user_id, template_id) VALUES (?, ?)"
+	_, err := r.db.ExecContext(ctx, query, userID, templateID)
+	return err
+}
+
+func (r *subscriptionRepositoryImpl) ConsumeGrant(ctx context.Context, userID int64, templateID string) (int64, error) {
+	// LAST_INSERT_ID(id) hands back the ID of the updated row without another query
+	query := `UPDATE subscription_grants SET consumed_at = CURRENT_TIMESTAMP, id = LAST_INSERT_ID(id)
+		WHERE user_id = ? AND template_id = ? AND consumed_at IS NULL ORDER BY id LIMIT 1`
+	result, err := r.db.ExecContext(ctx, query, userID, templateID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *subscriptionRepositoryImpl) RestoreGrant(ctx context.Context, grantID int64) error {
+	query := "UPDATE subscription_grants SET consumed_at = NULL WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, grantID)
+	return err
+}
+
+func (r *subscriptionRepositoryImpl) CountAvailable(ctx context.Context, userID int64, templateID string) (int, error) {
+	query := "SELECT COUNT(*) FROM subscription_grants WHERE user_id = ? AND template_id = ? AND consumed_at IS NULL"
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID, templateID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *subscriptionRepositoryImpl) DeleteByUserID(ctx context.Context, userID int64) error {
+	query := "DELETE FROM subscription_grants WHERE user_id = ?"
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"github.com/45ai/backend/internal/model"
+)
+
+// TemplateCategoryRepository defines the interface for template category data access
+type TemplateCategoryRepository interface {
+	// List retrieves all categories in display order with their available template counts
+	List(ctx context.Context) ([]model.TemplateCategory, error)
+	
+	// GetByID retrieves a category by ID
+	GetByID(ctx context.Context, id int) (*model.TemplateCategory, error)
+	
+	// GetBySlug retrieves a category by slug
+	GetBySlug(ctx context.Context, slug string) (*model.TemplateCategory, error)
+	
+	// Create creates a new category
+	Create(ctx context.Context, category *model.TemplateCategory) error
+	
+	// Update updates a category
+	Update(ctx context.Context, category *model.TemplateCategory) error
+	
+	// Delete deletes a category and unlinks its templates
+	Delete(ctx context.Context, id int) error
+}
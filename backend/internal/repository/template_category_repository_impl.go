@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+type templateCategoryRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewTemplateCategoryRepository(db *sql.DB) TemplateCategoryRepository {
+	return &templateCategoryRepositoryImpl{db: db}
+}
+
+func (r *templateCategoryRepositoryImpl) List(ctx context.Context) ([]model.TemplateCategory, error) {
+	query := `SELECT c.id, c.slug, c.name, c.sort_order, COUNT(t.id) FROM template_categories c
+		LEFT JOIN template_category_links l ON l.category_id = c.id
+		LEFT JOIN templates t ON t.id = l.template_id AND t.is_active = true AND t.deleted_at IS NULL AND ` + templateAvailable + `
+		GROUP BY c.id, c.slug, c.name, c.sort_order
+		ORDER BY c.sort_order, c.id`
+	now := time.Now()
+	rows, err := r.db.QueryContext(ctx, query, now, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []model.TemplateCategory
+	for rows.Next() {
+		var c model.TemplateCategory
+		if err := rows.Scan(&c.ID, &c.Slug, &c.Name, &c.SortOrder, &c.TemplateCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *templateCategoryRepositoryImpl) GetByID(ctx context.Context, id int) (*model.TemplateCategory, error) {
+	query := "SELECT id, slug, name, sort_order FROM template_categories WHERE id = ?"
+	c := &model.TemplateCategory{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Slug, &c.Name, &c.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *templateCategoryRepositoryImpl) GetBySlug(ctx context.Context, slug string) (*model.TemplateCategory, error) {
+	query := "SELECT id, slug, name, sort_order FROM template_categories WHERE slug = ?"
+	c := &model.TemplateCategory{}
+	err := r.db.QueryRowContext(ctx, query, slug).Scan(&c.ID, &c.Slug, &c.Name, &c.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *templateCategoryRepositoryImpl) Create(ctx context.Context, category *model.TemplateCategory) error {
+	query := "INSERT INTO template_categories (slug, name, sort_order) VALUES (?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, category.Slug, category.Name, category.SortOrder)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	category.ID = int(id)
+	return nil
+}
+
+func (r *templateCategoryRepositoryImpl) Update(ctx context.Context, category *model.TemplateCategory) error {
+	query := "UPDATE template_categories SET slug = ?, name = ?, sort_order = ? WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, category.Slug, category.Name, category.SortOrder, category.ID)
+	return err
+}
+
+func (r *templateCategoryRepositoryImpl) Delete(ctx context.Context, id int) error {
+	query := "DELETE FROM template_categories WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
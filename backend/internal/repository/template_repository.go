@@ -5,26 +5,48 @@ import (
 	"github.com/45ai/backend/internal/model"
 )
 
-// TemplateRepository defines the interface for template data access
+// TemplateRepository defines the interface for template data access.
+// Soft-deleted templates are only returned by GetByIDWithDeleted.
 type TemplateRepository interface {
-	// GetAll retrieves all active templates
+	// GetAll retrieves all active templates inside their availability window in gallery order
 	GetAll(ctx context.Context) ([]model.Template, error)
 	
+	// Search retrieves a page of available templates matching the filter and the total number of matches
+	Search(ctx context.Context, filter model.TemplateFilter) ([]model.Template, int, error)
+	
+	// List retrieves all templates, including inactive ones, in gallery order
+	List(ctx context.Context) ([]model.Template, error)
+	
 	// GetByID retrieves a template by ID
 	GetByID(ctx context.Context, id int) (*model.Template, error)
 	
+	// GetByIDWithDeleted retrieves a template by ID even if it was soft-deleted,
+	// for records that keep referencing it
+	GetByIDWithDeleted(ctx context.Context, id int) (*model.Template, error)
+	
 	// GetByName retrieves a template by name
 	GetByName(ctx context.Context, name string) (*model.Template, error)
 	
-	// Create creates a new template
-	Create(ctx context.Context, template *model.Template) error
+	// Create creates a new template with its categories and tags in one transaction.
+	// A zero SortOrder places it after all others. A name taken by another
+	// template returns ErrDuplicateKey.
+	Create(ctx context.Context, template *model.Template, categoryIDs []int, tags []string) error
 	
-	// Update updates template information
-	Update(ctx context.Context, template *model.Template) error
+	// Update updates template information and replaces the categories and tags
+	// that are not nil in one transaction. A name taken by another template
+	// returns ErrDuplicateKey, and a missing or deleted template sql.ErrNoRows.
+	Update(ctx context.Context, template *model.Template, categoryIDs *[]int, tags *[]string) error
 	
 	// SetActive activates or deactivates a template
 	SetActive(ctx context.Context, id int, isActive bool) error
 	
+	// Delete soft-deletes a template; transactions keep referencing it. A
+	// missing or already deleted template returns sql.ErrNoRows.
+	Delete(ctx context.Context, id int) error
+	
+	// Reorder sets the sort order of the given templates to their position in ids
+	Reorder(ctx context.Context, ids []int) error
+	
 	// Count returns the total number of active templates
 	Count(ctx context.Context) (int, error)
-} 
\ No newline at end of file
+}
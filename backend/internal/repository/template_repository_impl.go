@@ -3,9 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/45ai/backend/internal/model"
 )
 
+const templateColumns = "id, name, COALESCE(description, ''), preview_image_url, credit_cost, is_active, sort_order, available_from, available_until, created_at, updated_at, deleted_at"
+
+// templateAvailable matches templates inside their availability window. It takes
+// the current time twice as arguments, so listing uses the same clock as the
+// eligibility check rather than the database's.
+const templateAvailable = "(available_from IS NULL OR available_from <= ?) AND (available_until IS NULL OR available_until > ?)"
+
 type templateRepositoryImpl struct {
 	db *sql.DB
 }
@@ -15,71 +26,377 @@ func NewTemplateRepository(db *sql.DB) TemplateRepository {
 }
 
 func (r *templateRepositoryImpl) GetAll(ctx context.Context) ([]model.Template, error) {
-	query := "SELECT id, name, description, preview_image_url, credit_cost, is_active, created_at FROM templates WHERE is_active = true"
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+	query := "SELECT " + templateColumns + " FROM templates WHERE is_active = true AND deleted_at IS NULL AND " + templateAvailable + " ORDER BY sort_order, id"
+	now := time.Now()
+	return r.query(ctx, query, now, now)
+}
+
+func (r *templateRepositoryImpl) Search(ctx context.Context, filter model.TemplateFilter) ([]model.Template, int, error) {
+	where := []string{"is_active = true", "deleted_at IS NULL", templateAvailable}
+	now := time.Now()
+	args := []interface{}{now, now}
+	if filter.Category != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM template_category_links l JOIN template_categories c ON c.id = l.category_id
+			WHERE l.template_id = templates.id AND c.slug = ?)`)
+		args = append(args, filter.Category)
+	}
+	if filter.Tag != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM template_tag_links l JOIN template_tags g ON g.id = l.tag_id
+			WHERE l.template_id = templates.id AND g.name = ?)`)
+		args = append(args, filter.Tag)
 	}
-	defer rows.Close()
 
-	var templates []model.Template
-	for rows.Next() {
-		var t model.Template
-		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.PreviewImageURL, &t.CreditCost, &t.IsActive, &t.CreatedAt); err != nil {
-			return nil, err
+	orderBy := "sort_order, id"
+	switch filter.Sort {
+	case model.TemplateSortPopular:
+		orderBy = "COALESCE((SELECT uses_total FROM template_stats WHERE template_id = templates.id), 0) DESC, " + orderBy
+	case model.TemplateSortTrending:
+		orderBy = "COALESCE((SELECT trending_score FROM template_stats WHERE template_id = templates.id), 0) DESC, " + orderBy
+	case model.TemplateSortNew:
+		orderBy = "created_at DESC, id DESC"
+	}
+	var orderArgs []interface{}
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		tagMatch := `EXISTS (SELECT 1 FROM template_tag_links l JOIN template_tags g ON g.id = l.tag_id
+			WHERE l.template_id = templates.id AND g.name LIKE ?)`
+		like := "%" + escapeLike(query) + "%"
+		if utf8.RuneCountInString(query) < 2 {
+			// The ngram parser indexes two-character tokens, so single characters need LIKE
+			where = append(where, "(name LIKE ? OR description LIKE ? OR "+tagMatch+")")
+			args = append(args, like, like, like)
+		} else {
+			// Phrase search: every bigram of the query must appear in sequence
+			phrase := `"` + strings.ReplaceAll(query, `"`, " ") + `"`
+			where = append(where, "(MATCH(name, description) AGAINST (? IN BOOLEAN MODE) OR "+tagMatch+")")
+			args = append(args, phrase, like)
+			if filter.Sort == model.TemplateSortDefault {
+				// Without an explicit sort the best matches come first
+				orderBy = "MATCH(name, description) AGAINST (? IN BOOLEAN MODE) DESC, " + orderBy
+				orderArgs = append(orderArgs, phrase)
+			}
 		}
-		templates = append(templates, t)
 	}
-	return templates, nil
+	conditions := strings.Join(where, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM templates WHERE "+conditions, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + templateColumns + " FROM templates WHERE " + conditions + " ORDER BY " + orderBy + " LIMIT ? OFFSET ?"
+	args = append(append(args, orderArgs...), filter.Limit, filter.Offset)
+	templates, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return templates, total, nil
+}
+
+func (r *templateRepositoryImpl) List(ctx context.Context) ([]model.Template, error) {
+	query := "SELECT " + templateColumns + " FROM templates WHERE deleted_at IS NULL ORDER BY sort_order, id"
+	return r.query(ctx, query)
 }
 
 func (r *templateRepositoryImpl) GetByID(ctx context.Context, id int) (*model.Template, error) {
-	query := "SELECT id, name, description, preview_image_url, credit_cost, is_active, created_at FROM templates WHERE id = ?"
-	row := r.db.QueryRowContext(ctx, query, id)
-	template := &model.Template{}
-	err := row.Scan(&template.ID, &template.Name, &template.Description, &template.PreviewImageURL, &template.CreditCost, &template.IsActive, &template.CreatedAt)
+	return r.getByID(ctx, "SELECT "+templateColumns+" FROM templates WHERE id = ? AND deleted_at IS NULL", id)
+}
+
+func (r *templateRepositoryImpl) GetByIDWithDeleted(ctx context.Context, id int) (*model.Template, error) {
+	return r.getByID(ctx, "SELECT "+templateColumns+" FROM templates WHERE id = ?", id)
+}
+
+func (r *templateRepositoryImpl) getByID(ctx context.Context, query string, id int) (*model.Template, error) {
+	templates, err := r.query(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
-	return template, nil
+	if len(templates) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &templates[0], nil
 }
 
 func (r *templateRepositoryImpl) GetByName(ctx context.Context, name string) (*model.Template, error) {
-	query := "SELECT id, name, description, preview_image_url, credit_cost, is_active, created_at FROM templates WHERE name = ?"
-	row := r.db.QueryRowContext(ctx, query, name)
-	template := &model.Template{}
-	err := row.Scan(&template.ID, &template.Name, &template.Description, &template.PreviewImageURL, &template.CreditCost, &template.IsActive, &template.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return template, nil
+	query := "SELECT " + templateColumns + " FROM templates WHERE name = ? AND deleted_at IS NULL"
+	return scanTemplate(r.db.QueryRowContext(ctx, query, name))
 }
 
-func (r *templateRepositoryImpl) Create(ctx context.Context, template *model.Template) error {
-	query := "INSERT INTO templates (name, description, preview_image_url, credit_cost, is_active) VALUES (?, ?, ?, ?, ?)"
-	result, err := r.db.ExecContext(ctx, query, template.Name, template.Description, template.PreviewImageURL, template.CreditCost, template.IsActive)
+func (r *templateRepositoryImpl) Create(ctx context.Context, template *model.Template, categoryIDs []int, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO templates (name, description, preview_image_url, credit_cost, is_active, sort_order, available_from, available_until)
+		SELECT ?, ?, ?, ?, ?, IF(? <> 0, ?, COALESCE(MAX(sort_order), 0) + 1), ?, ? FROM templates WHERE deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, template.Name, template.Description, template.PreviewImageURL, template.CreditCost, template.IsActive,
+		template.SortOrder, template.SortOrder, template.AvailableFrom, template.AvailableUntil)
+	if err != nil {
+		return mapDuplicateKey(err)
+	}
 	id, err := result.LastInsertId()
 	if err != nil {
 		return err
 	}
+	if err := setTemplateCategories(ctx, tx, int(id), categoryIDs); err != nil {
+		return err
+	}
+	if err := setTemplateTags(ctx, tx, int(id), tags); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	template.ID = int(id)
 	return nil
 }
 
-func (r *templateRepositoryImpl) Update(ctx context.Context, template *model.Template) error {
-	// To be implemented in a future task
-	return nil
+func (r *templateRepositoryImpl) Update(ctx context.Context, template *model.Template, categoryIDs *[]int, tags *[]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// An UPDATE that changes nothing affects no rows, so check the template
+	// exists first; the lock keeps it from being deleted meanwhile
+	var id int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM templates WHERE id = ? AND deleted_at IS NULL FOR UPDATE", template.ID).Scan(&id); err != nil {
+		return err
+	}
+
+	query := `UPDATE templates SET name = ?, description = ?, preview_image_url = ?, credit_cost = ?, is_active = ?, sort_order = ?,
+		available_from = ?, available_until = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err = tx.ExecContext(ctx, query, template.Name, template.Description, template.PreviewImageURL, template.CreditCost, template.IsActive, template.SortOrder,
+		template.AvailableFrom, template.AvailableUntil, template.ID)
+	if err != nil {
+		return mapDuplicateKey(err)
+	}
+	if categoryIDs != nil {
+		if err := setTemplateCategories(ctx, tx, template.ID, *categoryIDs); err != nil {
+			return err
+		}
+	}
+	if tags != nil {
+		if err := setTemplateTags(ctx, tx, template.ID, *tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *templateRepositoryImpl) SetActive(ctx context.Context, id int, isActive bool) error {
-	// To be implemented in a future task
+	query := "UPDATE templates SET is_active = ? WHERE id = ? AND deleted_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, isActive, id)
+	return err
+}
+
+func (r *templateRepositoryImpl) Delete(ctx context.Context, id int) error {
+	query := "UPDATE templates SET is_active = false, deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL"
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *templateRepositoryImpl) Reorder(ctx context.Context, ids []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE templates SET sort_order = ? WHERE id = ? AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range ids {
+		if _, err := stmt.ExecContext(ctx, i+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// setTemplateCategories replaces the categories a template belongs to
+func setTemplateCategories(ctx context.Context, tx *sql.Tx, id int, categoryIDs []int) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM template_category_links WHERE template_id = ?", id); err != nil {
+		return err
+	}
+	for _, categoryID := range categoryIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO template_category_links (template_id, category_id) VALUES (?, ?)", id, categoryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setTemplateTags replaces a template's tags, creating tags that do not exist yet
+func setTemplateTags(ctx context.Context, tx *sql.Tx, id int, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM template_tag_links WHERE template_id = ?", id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO template_tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		query := "INSERT IGNORE INTO template_tag_links (template_id, tag_id) SELECT ?, id FROM template_tags WHERE name = ?"
+		if _, err := tx.ExecContext(ctx, query, id, tag); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (r *templateRepositoryImpl) Count(ctx context.Context) (int, error) {
-	// To be implemented in a future task
-	return 0, nil
-} 
\ No newline at end of file
+	query := "SELECT COUNT(*) FROM templates WHERE is_active = true AND deleted_at IS NULL"
+	var count int
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *templateRepositoryImpl) query(ctx context.Context, query string, args ...interface{}) ([]model.Template, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []model.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachTaxonomy(ctx, templates); err != nil {
+		return nil, err
+	}
+	if err := r.attachUsage(ctx, templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// attachTaxonomy loads the categories and tags of the given templates
+func (r *templateRepositoryImpl) attachTaxonomy(ctx context.Context, templates []model.Template) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*model.Template, len(templates))
+	placeholders := make([]string, len(templates))
+	args := make([]interface{}, len(templates))
+	for i := range templates {
+		templates[i].Categories = []model.TemplateCategory{}
+		templates[i].Tags = []string{}
+		index[templates[i].ID] = &templates[i]
+		placeholders[i] = "?"
+		args[i] = templates[i].ID
+	}
+	in := "(" + strings.Join(placeholders, ", ") + ")"
+
+	query := `SELECT l.template_id, c.id, c.slug, c.name, c.sort_order FROM template_category_links l
+		JOIN template_categories c ON c.id = l.category_id WHERE l.template_id IN ` + in + " ORDER BY c.sort_order, c.id"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var templateID int
+		var category model.TemplateCategory
+		if err := rows.Scan(&templateID, &category.ID, &category.Slug, &category.Name, &category.SortOrder); err != nil {
+			return err
+		}
+		index[templateID].Categories = append(index[templateID].Categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query = `SELECT l.template_id, g.name FROM template_tag_links l
+		JOIN template_tags g ON g.id = l.tag_id WHERE l.template_id IN ` + in + " ORDER BY g.name"
+	tagRows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var templateID int
+		var tag string
+		if err := tagRows.Scan(&templateID, &tag); err != nil {
+			return err
+		}
+		index[templateID].Tags = append(index[templateID].Tags, tag)
+	}
+	return tagRows.Err()
+}
+
+// attachUsage loads the usage counters of the given templates
+func (r *templateRepositoryImpl) attachUsage(ctx context.Context, templates []model.Template) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*model.Template, len(templates))
+	placeholders := make([]string, len(templates))
+	args := make([]interface{}, len(templates))
+	for i := range templates {
+		templates[i].Usage = &model.TemplateUsage{}
+		index[templates[i].ID] = &templates[i]
+		placeholders[i] = "?"
+		args[i] = templates[i].ID
+	}
+
+	query := "SELECT template_id, uses_total, uses_24h, uses_7d, trending_score FROM template_stats WHERE template_id IN (" +
+		strings.Join(placeholders, ", ") + ")"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var templateID int
+		var usage model.TemplateUsage
+		if err := rows.Scan(&templateID, &usage.Total, &usage.Last24h, &usage.Last7d, &usage.TrendingScore); err != nil {
+			return err
+		}
+		*index[templateID].Usage = usage
+	}
+	return rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func scanTemplate(row rowScanner) (*model.Template, error) {
+	t := &model.Template{}
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.PreviewImageURL, &t.CreditCost, &t.IsActive, &t.SortOrder, &t.AvailableFrom, &t.AvailableUntil, &t.CreatedAt, &t.UpdatedAt, &t.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.IsLimitedTime = t.AvailableUntil != nil
+	t.EffectiveCreditCost = t.CreditCost
+	return t, nil
+}
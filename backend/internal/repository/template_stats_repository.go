@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TemplateStatsRepository defines the interface for template usage counters
+type TemplateStatsRepository interface {
+	// RecordUse counts one generation with a template
+	RecordUse(ctx context.Context, templateID int) error
+	
+	// Refresh recomputes the 24h and 7d counts and the trending score, which
+	// halves for every halfLife that passes since a use
+	Refresh(ctx context.Context, halfLife time.Duration) error
+	
+	// PruneUsage drops hourly usage older than retention; totals are kept
+	PruneUsage(ctx context.Context, retention time.Duration) error
+}
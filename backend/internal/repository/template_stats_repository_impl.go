@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type templateStatsRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewTemplateStatsRepository(db *sql.DB) TemplateStatsRepository {
+	return &templateStatsRepositoryImpl{db: db}
+}
+
+func (r *templateStatsRepositoryImpl) RecordUse(ctx context.Context, templateID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Hours are computed by the database so every instance buckets alike
+	query := `INSERT INTO template_usage_hourly (template_id, hour, uses) VALUES (?, DATE_FORMAT(NOW(), '%Y-%m-%d %H:00:00'), 1)
+		ON DUPLICATE KEY UPDATE uses = uses + 1`
+	if _, err := tx.ExecContext(ctx, query, templateID); err != nil {
+		return err
+	}
+	query = "INSERT INTO template_stats (template_id, uses_total) VALUES (?, 1) ON DUPLICATE KEY UPDATE uses_total = uses_total + 1"
+	if _, err := tx.ExecContext(ctx, query, templateID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *templateStatsRepositoryImpl) Refresh(ctx context.Context, halfLife time.Duration) error {
+	// Each hour is weighted from its midpoint. Templates without recent use
+	// fall back to zero through the LEFT JOIN.
+	query := `UPDATE template_stats s
+		LEFT JOIN (
+			SELECT template_id,
+				SUM(IF(hour >= NOW() - INTERVAL 24 HOUR, uses, 0)) AS uses_24h,
+				SUM(IF(hour >= NOW() - INTERVAL 7 DAY, uses, 0)) AS uses_7d,
+				SUM(uses * POW(0.5, GREATEST(TIMESTAMPDIFF(SECOND, hour + INTERVAL 30 MINUTE, NOW()), 0) / ?)) AS score
+			FROM template_usage_hourly
+			GROUP BY template_id
+		) recent ON recent.template_id = s.template_id
+		SET s.uses_24h = COALESCE(recent.uses_24h, 0),
+			s.uses_7d = COALESCE(recent.uses_7d, 0),
+			s.trending_score = COALESCE(recent.score, 0),
+			s.refreshed_at = CURRENT_TIMESTAMP`
+	_, err := r.db.ExecContext(ctx, query, halfLife.Seconds())
+	return err
+}
+
+func (r *templateStatsRepositoryImpl) PruneUsage(ctx context.Context, retention time.Duration) error {
+	query := "DELETE FROM template_usage_hourly WHERE hour < NOW() - INTERVAL ? SECOND"
+	_, err := r.db.ExecContext(ctx, query, int64(retention.Seconds()))
+	return err
+}
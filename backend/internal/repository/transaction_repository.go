@@ -2,15 +2,27 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 	"github.com/45ai/backend/internal/model"
 )
 
+// ErrInsufficientCredits is returned when a transaction would leave a negative balance
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
+// ErrNotFirstGeneration is returned when a first generation charge is made for a
+// user who already has a generation transaction
+var ErrNotFirstGeneration = errors.New("user already has a generation")
+
 // TransactionRepository defines the interface for transaction data access
 type TransactionRepository interface {
 	// Create creates a new transaction
 	Create(ctx context.Context, transaction *model.Transaction) error
 	
+	// CreateWithCredits creates a transaction and adds its amount to the user's
+	// credits atomically, failing with ErrInsufficientCredits instead of going negative
+	CreateWithCredits(ctx context.Context, transaction *model.Transaction) error
+	
 	// GetByID retrieves a transaction by ID
 	GetByID(ctx context.Context, id int64) (*model.Transaction, error)
 	
@@ -23,6 +35,12 @@ type TransactionRepository interface {
 	// CountByUserID returns the total number of transactions for a user
 	CountByUserID(ctx context.Context, userID int64) (int, error)
 	
+	// HasTransactionOfType reports whether a user ever made a transaction of a type
+	HasTransactionOfType(ctx context.Context, userID int64, txType model.TransactionType) (bool, error)
+	
+	// CountTodayByUserIDAndType returns how many transactions of a type a user made since midnight
+	CountTodayByUserIDAndType(ctx context.Context, userID int64, txType model.TransactionType) (int, error)
+	
 	// SumCreditsByUserID calculates the total credits for a user
 	SumCreditsByUserID(ctx context.Context, userID int64) (int, error)
 } 
\ No newline at end of file
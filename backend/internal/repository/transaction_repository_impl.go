@@ -17,8 +17,77 @@ func NewTransactionRepository(db *sql.DB) TransactionRepository {
 }
 
 func (r *transactionRepositoryImpl) Create(ctx context.Context, transaction *model.Transaction) error {
-	query := "INSERT INTO transactions (user_id, type, amount, description, external_payment_id, related_template_id) VALUES (?, ?, ?, ?, ?, ?)"
-	result, err := r.db.ExecContext(ctx, query, transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, transaction.ExternalPaymentID, transaction.RelatedTemplateID)
+	return insertTransaction(ctx, r.db, transaction)
+}
+
+func (r *transactionRepositoryImpl) CreateWithCredits(ctx context.Context, transaction *model.Transaction) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createWithCredits(ctx, tx, transaction, false); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// createWithCredits creates a transaction and adds its amount to the user's
+// credits within tx. A first generation charge fails with ErrNotFirstGeneration
+// if the user already has a generation transaction.
+func createWithCredits(ctx context.Context, tx *sql.Tx, transaction *model.Transaction, firstGeneration bool) error {
+	// Every transaction locks the user row until it commits: the balance update
+	// does so for non-zero amounts. Once the lock is held, the locking read below
+	// sees every generation recorded before this one.
+	if transaction.Amount == 0 || firstGeneration {
+		if err := lockUser(ctx, tx, transaction.UserID); err != nil {
+			return err
+		}
+	}
+	if firstGeneration {
+		var count int
+		query := "SELECT COUNT(*) FROM transactions WHERE user_id = ? AND type = ? LOCK IN SHARE MODE"
+		if err := tx.QueryRowContext(ctx, query, transaction.UserID, model.TransactionTypeGeneration).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrNotFirstGeneration
+		}
+	}
+
+	// MySQL reports zero affected rows for an unchanged balance, so free transactions skip the update
+	if transaction.Amount != 0 {
+		query := "UPDATE users SET credits = credits + ? WHERE id = ? AND credits + ? >= 0"
+		result, err := tx.ExecContext(ctx, query, transaction.Amount, transaction.UserID, transaction.Amount)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrInsufficientCredits
+		}
+	}
+	return insertTransaction(ctx, tx, transaction)
+}
+
+// lockUser locks a user row until tx ends, serializing the user's charges
+func lockUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	var id int64
+	return tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&id)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertTransaction(ctx context.Context, db execer, transaction *model.Transaction) error {
+	query := "INSERT INTO transactions (user_id, type, amount, description, external_payment_id, related_template_id, pricing_rule_id) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	result, err := db.ExecContext(ctx, query, transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, transaction.ExternalPaymentID, transaction.RelatedTemplateID, transaction.PricingRuleID)
 	if err != nil {
 		return err
 	}
@@ -36,7 +105,7 @@ func (r *transactionRepositoryImpl) GetByID(ctx context.Context, id int64) (*mod
 }
 
 func (r *transactionRepositoryImpl) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]model.Transaction, error) {
-	query := "SELECT id, user_id, type, amount, description, external_payment_id, related_template_id, created_at FROM transactions WHERE user_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	query := "SELECT id, user_id, type, amount, description, external_payment_id, related_template_id, pricing_rule_id, created_at FROM transactions WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
 	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, err
@@ -46,7 +115,7 @@ func (r *transactionRepositoryImpl) GetByUserID(ctx context.Context, userID int6
 	var transactions []model.Transaction
 	for rows.Next() {
 		var t model.Transaction
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &t.Description, &t.ExternalPaymentID, &t.RelatedTemplateID, &t.CreatedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &t.Description, &t.ExternalPaymentID, &t.RelatedTemplateID, &t.PricingRuleID, &t.CreatedAt); err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, t)
@@ -64,6 +133,25 @@ func (r *transactionRepositoryImpl) CountByUserID(ctx context.Context, userID in
 	return 0, nil
 }
 
+func (r *transactionRepositoryImpl) HasTransactionOfType(ctx context.Context, userID int64, txType model.TransactionType) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM transactions WHERE user_id = ? AND type = ?)"
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, userID, txType).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *transactionRepositoryImpl) CountTodayByUserIDAndType(ctx context.Context, userID int64, txType model.TransactionType) (int, error) {
+	// Midnight in the database time zone, matching how created_at is written
+	query := "SELECT COUNT(*) FROM transactions WHERE user_id = ? AND type = ? AND created_at >= CURDATE()"
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID, txType).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (r *transactionRepositoryImpl) SumCreditsByUserID(ctx context.Context, userID int64) (int, error) {
 	// To be implemented in a future task
 	return 0, nil
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 	"github.com/45ai/backend/internal/model"
 )
 
@@ -16,12 +17,36 @@ type UserRepository interface {
 	// GetByWechatOpenID retrieves a user by WeChat OpenID
 	GetByWechatOpenID(ctx context.Context, openID string) (*model.User, error)
 	
+	// GetByWechatUnionID retrieves a user by WeChat UnionID
+	GetByWechatUnionID(ctx context.Context, unionID string) (*model.User, error)
+	
 	// Update updates user information
 	Update(ctx context.Context, user *model.User) error
 	
 	// UpdateCredits updates user credit balance
 	UpdateCredits(ctx context.Context, userID int64, amount int) error
 	
+	// UpdateWechatSession stores the OpenID, UnionID and sealed session key from the latest login
+	UpdateWechatSession(ctx context.Context, userID int64, openID string, unionID *string, sealedSessionKey string) error
+	
+	// GetWechatSessionKey retrieves the sealed session key from the latest login
+	GetWechatSessionKey(ctx context.Context, userID int64) (string, error)
+	
+	// SetMemberUntil sets when a user's membership ends; nil ends it now
+	SetMemberUntil(ctx context.Context, userID int64, memberUntil *time.Time) error
+	
+	// SetBanned bans or unbans a user
+	SetBanned(ctx context.Context, userID int64, banned bool) error
+	
+	// Anonymize strips personal data from a user and marks it deleted
+	Anonymize(ctx context.Context, userID int64) error
+	
+	// MigratePIIBatch encrypts (or with decrypt, decrypts) the PII of up to limit users
+	// with IDs above afterID, re-encrypting values under old master keys. It returns
+	// the last ID examined, which equals afterID once every row has been processed,
+	// and the number of rows rewritten.
+	MigratePIIBatch(ctx context.Context, afterID int64, limit int, decrypt bool) (int64, int, error)
+	
 	// Exists checks if a user exists by WeChat OpenID
 	Exists(ctx context.Context, openID string) (bool, error)
 } 
\ No newline at end of file
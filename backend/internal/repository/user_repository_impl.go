@@ -3,21 +3,45 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/pkg/fieldcrypt"
 )
 
+// Field names bound to the ciphertext of each encrypted users column
+const (
+	fieldWechatOpenID  = "users.wechat_openid"
+	fieldWechatUnionID = "users.wechat_unionid"
+	fieldNickname      = "users.nickname"
+	fieldAvatarURL     = "users.avatar_url"
+)
+
+const userColumns = "id, wechat_openid, wechat_unionid, COALESCE(nickname, ''), COALESCE(avatar_url, ''), credits, member_until, banned_at, deleted_at, created_at, updated_at"
+
 type userRepositoryImpl struct {
-	db *sql.DB
+	db     *sql.DB
+	cipher *fieldcrypt.Cipher
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepositoryImpl{db: db}
+// NewUserRepository creates a UserRepository that encrypts PII columns with cipher
+func NewUserRepository(db *sql.DB, cipher *fieldcrypt.Cipher) UserRepository {
+	return &userRepositoryImpl{db: db, cipher: cipher}
 }
 
 func (r *userRepositoryImpl) Create(ctx context.Context, user *model.User) error {
-	query := "INSERT INTO users (wechat_openid, credits) VALUES (?, ?)"
-	result, err := r.db.ExecContext(ctx, query, user.WechatOpenID, user.Credits)
+	openID, err := r.cipher.Encrypt(user.WechatOpenID, fieldWechatOpenID)
+	if err != nil {
+		return err
+	}
+	unionID, unionIDIndex, err := r.encryptOptional(user.WechatUnionID, fieldWechatUnionID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO users (wechat_openid, wechat_openid_bidx, wechat_unionid, wechat_unionid_bidx, credits) VALUES (?, ?, ?, ?, ?)"
+	result, err := r.db.ExecContext(ctx, query, openID, r.cipher.BlindIndex(user.WechatOpenID, fieldWechatOpenID), unionID, unionIDIndex, user.Credits)
 	if err != nil {
 		return err
 	}
@@ -30,30 +54,39 @@ func (r *userRepositoryImpl) Create(ctx context.Context, user *model.User) error
 }
 
 func (r *userRepositoryImpl) GetByID(ctx context.Context, id int64) (*model.User, error) {
-	query := "SELECT id, wechat_openid, nickname, avatar_url, credits, created_at, updated_at FROM users WHERE id = ?"
-	row := r.db.QueryRowContext(ctx, query, id)
-	user := &model.User{}
-	err := row.Scan(&user.ID, &user.WechatOpenID, &user.Nickname, &user.AvatarURL, &user.Credits, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+	return r.scanUser(r.db.QueryRowContext(ctx, query, id))
 }
 
 func (r *userRepositoryImpl) GetByWechatOpenID(ctx context.Context, openID string) (*model.User, error) {
-	query := "SELECT id, wechat_openid, nickname, avatar_url, credits, created_at, updated_at FROM users WHERE wechat_openid = ?"
-	row := r.db.QueryRowContext(ctx, query, openID)
-	user := &model.User{}
-	err := row.Scan(&user.ID, &user.WechatOpenID, &user.Nickname, &user.AvatarURL, &user.Credits, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	// Rows not yet processed by cmd/encrypt-pii have no blind index and a plaintext openid
+	query := "SELECT " + userColumns + " FROM users WHERE wechat_openid_bidx = ? OR (wechat_openid_bidx IS NULL AND wechat_openid = ?) LIMIT 1"
+	return r.scanUser(r.db.QueryRowContext(ctx, query, r.cipher.BlindIndex(openID, fieldWechatOpenID), openID))
+}
+
+func (r *userRepositoryImpl) GetByWechatUnionID(ctx context.Context, unionID string) (*model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE wechat_unionid_bidx = ? OR (wechat_unionid_bidx IS NULL AND wechat_unionid = ?) LIMIT 1"
+	return r.scanUser(r.db.QueryRowContext(ctx, query, r.cipher.BlindIndex(unionID, fieldWechatUnionID), unionID))
 }
 
 func (r *userRepositoryImpl) Update(ctx context.Context, user *model.User) error {
-	query := "UPDATE users SET nickname = ?, avatar_url = ? WHERE id = ?"
-	_, err := r.db.ExecContext(ctx, query, user.Nickname, user.AvatarURL, user.ID)
+	nickname, err := r.cipher.Encrypt(user.Nickname, fieldNickname)
+	if err != nil {
+		return err
+	}
+	avatarURL, err := r.cipher.Encrypt(user.AvatarURL, fieldAvatarURL)
+	if err != nil {
+		return err
+	}
+	unionID, unionIDIndex, err := r.encryptOptional(user.WechatUnionID, fieldWechatUnionID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET nickname = ?, avatar_url = ?,
+		wechat_unionid = COALESCE(?, wechat_unionid), wechat_unionid_bidx = COALESCE(?, wechat_unionid_bidx)
+		WHERE id = ?`
+	_, err = r.db.ExecContext(ctx, query, nickname, avatarURL, unionID, unionIDIndex, user.ID)
 	return err
 }
 
@@ -63,12 +96,204 @@ func (r *userRepositoryImpl) UpdateCredits(ctx context.Context, userID int64, am
 	return err
 }
 
+func (r *userRepositoryImpl) UpdateWechatSession(ctx context.Context, userID int64, openID string, unionID *string, sealedSessionKey string) error {
+	encryptedOpenID, err := r.cipher.Encrypt(openID, fieldWechatOpenID)
+	if err != nil {
+		return err
+	}
+	encryptedUnionID, unionIDIndex, err := r.encryptOptional(unionID, fieldWechatUnionID)
+	if err != nil {
+		return err
+	}
+
+	// Never overwrite a known UnionID with an empty one
+	query := `UPDATE users SET wechat_openid = ?, wechat_openid_bidx = ?, wechat_unionid = COALESCE(?, wechat_unionid),
+		wechat_unionid_bidx = COALESCE(?, wechat_unionid_bidx), wechat_session_key = ? WHERE id = ?`
+	_, err = r.db.ExecContext(ctx, query, encryptedOpenID, r.cipher.BlindIndex(openID, fieldWechatOpenID), encryptedUnionID, unionIDIndex, sealedSessionKey, userID)
+	return err
+}
+
+func (r *userRepositoryImpl) GetWechatSessionKey(ctx context.Context, userID int64) (string, error) {
+	query := "SELECT COALESCE(wechat_session_key, '') FROM users WHERE id = ?"
+	var sealed string
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&sealed)
+	if err != nil {
+		return "", err
+	}
+	return sealed, nil
+}
+
+func (r *userRepositoryImpl) SetMemberUntil(ctx context.Context, userID int64, memberUntil *time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET member_until = ? WHERE id = ?", memberUntil, userID)
+	return err
+}
+
+func (r *userRepositoryImpl) SetBanned(ctx context.Context, userID int64, banned bool) error {
+	query := "UPDATE users SET banned_at = NULL WHERE id = ?"
+	if banned {
+		query = "UPDATE users SET banned_at = COALESCE(banned_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	}
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *userRepositoryImpl) Anonymize(ctx context.Context, userID int64) error {
+	// The openid is replaced rather than cleared because it is NOT NULL and unique;
+	// a later login with the same WeChat account creates a fresh user
+	query := `UPDATE users SET wechat_openid = CONCAT('deleted:', id), wechat_openid_bidx = NULL,
+		wechat_unionid = NULL, wechat_unionid_bidx = NULL, wechat_session_key = NULL,
+		nickname = NULL, avatar_url = NULL, deleted_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *userRepositoryImpl) MigratePIIBatch(ctx context.Context, afterID int64, limit int, decrypt bool) (int64, int, error) {
+	query := `SELECT id, wechat_openid, wechat_openid_bidx, wechat_unionid, wechat_unionid_bidx, nickname, avatar_url
+		FROM users WHERE id > ? AND deleted_at IS NULL ORDER BY id LIMIT ?`
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return afterID, 0, err
+	}
+	defer rows.Close()
+
+	type piiRow struct {
+		id                    int64
+		openID, openIDIndex   sql.NullString
+		unionID, unionIDIndex sql.NullString
+		nickname, avatarURL   sql.NullString
+	}
+	var batch []piiRow
+	for rows.Next() {
+		var row piiRow
+		if err := rows.Scan(&row.id, &row.openID, &row.openIDIndex, &row.unionID, &row.unionIDIndex, &row.nickname, &row.avatarURL); err != nil {
+			return afterID, 0, err
+		}
+		batch = append(batch, row)
+	}
+	if err := rows.Err(); err != nil {
+		return afterID, 0, err
+	}
+
+	lastID, updated := afterID, 0
+	for _, row := range batch {
+		lastID = row.id
+
+		pending := row.openIDIndex.Valid == decrypt || row.unionID.Valid && row.unionIDIndex.Valid == decrypt
+		for _, value := range []sql.NullString{row.openID, row.unionID, row.nickname, row.avatarURL} {
+			if decrypt {
+				pending = pending || fieldcrypt.IsEncrypted(value.String)
+			} else {
+				pending = pending || r.cipher.NeedsReencrypt(value.String)
+			}
+		}
+		if !pending {
+			continue
+		}
+
+		openID, openIDIndex, err := r.rewrite(row.openID, fieldWechatOpenID, decrypt)
+		if err != nil {
+			return lastID, updated, fmt.Errorf("user %d: %w", row.id, err)
+		}
+		unionID, unionIDIndex, err := r.rewrite(row.unionID, fieldWechatUnionID, decrypt)
+		if err != nil {
+			return lastID, updated, fmt.Errorf("user %d: %w", row.id, err)
+		}
+		nickname, _, err := r.rewrite(row.nickname, fieldNickname, decrypt)
+		if err != nil {
+			return lastID, updated, fmt.Errorf("user %d: %w", row.id, err)
+		}
+		avatarURL, _, err := r.rewrite(row.avatarURL, fieldAvatarURL, decrypt)
+		if err != nil {
+			return lastID, updated, fmt.Errorf("user %d: %w", row.id, err)
+		}
+
+		// Only rewrite the row if nobody changed it since we read it
+		update := `UPDATE users SET wechat_openid = ?, wechat_openid_bidx = ?, wechat_unionid = ?, wechat_unionid_bidx = ?,
+			nickname = ?, avatar_url = ?
+			WHERE id = ? AND wechat_openid <=> ? AND wechat_unionid <=> ? AND nickname <=> ? AND avatar_url <=> ?`
+		result, err := r.db.ExecContext(ctx, update, openID, openIDIndex, unionID, unionIDIndex, nickname, avatarURL,
+			row.id, row.openID, row.unionID, row.nickname, row.avatarURL)
+		if err != nil {
+			return lastID, updated, fmt.Errorf("user %d: %w", row.id, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return lastID, updated, err
+		}
+		updated += int(affected)
+	}
+
+	return lastID, updated, nil
+}
+
 func (r *userRepositoryImpl) Exists(ctx context.Context, openID string) (bool, error) {
-	query := "SELECT EXISTS(SELECT 1 FROM users WHERE wechat_openid = ?)"
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE wechat_openid_bidx = ? OR (wechat_openid_bidx IS NULL AND wechat_openid = ?))"
 	var exists bool
-	err := r.db.QueryRowContext(ctx, query, openID).Scan(&exists)
+	err := r.db.QueryRowContext(ctx, query, r.cipher.BlindIndex(openID, fieldWechatOpenID), openID).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
 	return exists, nil
-} 
\ No newline at end of file
+}
+
+// scanUser scans a row selected with userColumns and decrypts its PII
+func (r *userRepositoryImpl) scanUser(row *sql.Row) (*model.User, error) {
+	user := &model.User{}
+	err := row.Scan(&user.ID, &user.WechatOpenID, &user.WechatUnionID, &user.Nickname, &user.AvatarURL, &user.Credits, &user.MemberUntil, &user.BannedAt, &user.DeletedAt, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.WechatOpenID, err = r.cipher.Decrypt(user.WechatOpenID, fieldWechatOpenID); err != nil {
+		return nil, err
+	}
+	if user.WechatUnionID != nil {
+		unionID, err := r.cipher.Decrypt(*user.WechatUnionID, fieldWechatUnionID)
+		if err != nil {
+			return nil, err
+		}
+		user.WechatUnionID = &unionID
+	}
+	if user.Nickname, err = r.cipher.Decrypt(user.Nickname, fieldNickname); err != nil {
+		return nil, err
+	}
+	if user.AvatarURL, err = r.cipher.Decrypt(user.AvatarURL, fieldAvatarURL); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// rewrite converts a stored column value to plaintext, or to ciphertext under
+// the current master key, and returns its new blind index
+func (r *userRepositoryImpl) rewrite(value sql.NullString, field string, decrypt bool) (sql.NullString, sql.NullString, error) {
+	if !value.Valid {
+		return value, sql.NullString{}, nil
+	}
+	plaintext, err := r.cipher.Decrypt(value.String, field)
+	if err != nil {
+		return value, sql.NullString{}, err
+	}
+	if decrypt {
+		return sql.NullString{String: plaintext, Valid: true}, sql.NullString{}, nil
+	}
+	encrypted, err := r.cipher.Encrypt(plaintext, field)
+	if err != nil {
+		return value, sql.NullString{}, err
+	}
+	index := r.cipher.BlindIndex(plaintext, field)
+	return sql.NullString{String: encrypted, Valid: true}, sql.NullString{String: index, Valid: true}, nil
+}
+
+// encryptOptional encrypts a nullable identifier and returns its blind index
+func (r *userRepositoryImpl) encryptOptional(value *string, field string) (*string, *string, error) {
+	if value == nil {
+		return nil, nil, nil
+	}
+	encrypted, err := r.cipher.Encrypt(*value, field)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := r.cipher.BlindIndex(*value, field)
+	return &encrypted, &index, nil
+}
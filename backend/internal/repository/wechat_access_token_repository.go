@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"github.com/45ai/backend/internal/model"
+)
+
+// WechatAccessTokenRepository defines the interface for persisting WeChat access tokens
+type WechatAccessTokenRepository interface {
+	// Get retrieves the stored access token for an app
+	Get(ctx context.Context, appID string) (*model.WechatAccessToken, error)
+	
+	// Save stores the access token for an app, replacing any previous one
+	Save(ctx context.Context, token *model.WechatAccessToken) error
+}
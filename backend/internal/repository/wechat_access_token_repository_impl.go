@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+type wechatAccessTokenRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewWechatAccessTokenRepository(db *sql.DB) WechatAccessTokenRepository {
+	return &wechatAccessTokenRepositoryImpl{db: db}
+}
+
+func (r *wechatAccessTokenRepositoryImpl) Get(ctx context.Context, appID string) (*model.WechatAccessToken, error) {
+	query := "SELECT app_id, access_token, expires_at FROM wechat_access_tokens WHERE app_id = ?"
+	row := r.db.QueryRowContext(ctx, query, appID)
+	token := &model.WechatAccessToken{}
+	err := row.Scan(&token.AppID, &token.AccessToken, &token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *wechatAccessTokenRepositoryImpl) Save(ctx context.Context, token *model.WechatAccessToken) error {
+	query := "INSERT INTO wechat_access_tokens (app_id, access_token, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE access_token = VALUES(access_token), expires_at = VALUES(expires_at)"
+	_, err := r.db.ExecContext(ctx, query, token.AppID, token.AccessToken, token.ExpiresAt)
+	return err
+}
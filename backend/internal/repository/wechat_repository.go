@@ -1,44 +1,276 @@
 package repository
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/model"
 )
 
+var (
+	// ErrWechatInvalidCode is returned when a login code is invalid, expired or already used
+	ErrWechatInvalidCode = errors.New("wechat login code is invalid")
+
+	// ErrWechatRateLimited is returned when WeChat throttles our requests
+	ErrWechatRateLimited = errors.New("wechat api rate limit exceeded")
+
+	// ErrWechatBusy is returned when WeChat reports a transient system error
+	ErrWechatBusy = errors.New("wechat api is busy")
+
+	// ErrWechatNotSubscribed is returned when the user has no subscription for a message template
+	ErrWechatNotSubscribed = errors.New("user has not subscribed to this message")
+
+	// ErrWechatUnavailable is returned when WeChat cannot be reached or responds unexpectedly
+	ErrWechatUnavailable = errors.New("wechat api is unavailable")
+)
+
+// WechatError is an errcode returned by the WeChat API
+type WechatError struct {
+	Code    int
+	Message string
+}
+
+func (e *WechatError) Error() string {
+	return fmt.Sprintf("wechat api error %d: %s", e.Code, e.Message)
+}
+
+// Is maps well-known errcodes to the sentinel errors above
+func (e *WechatError) Is(target error) bool {
+	switch target {
+	case ErrWechatInvalidCode:
+		return e.Code == 40029 || e.Code == 40163
+	case ErrWechatRateLimited:
+		return e.Code == 45011
+	case ErrWechatBusy:
+		return e.Code == -1
+	case ErrWechatNotSubscribed:
+		return e.Code == 43101
+	}
+	return false
+}
+
+// expiredToken reports whether the errcode means the access_token must be replaced
+func (e *WechatError) expiredToken() bool {
+	return e.Code == 40001 || e.Code == 40014 || e.Code == 42001
+}
+
 type WechatRepository interface {
-	Code2Session(code string) (*model.WechatLoginResponse, error)
+	Code2Session(ctx context.Context, code string) (*model.WechatLoginResponse, error)
+	
+	// AccessToken returns a valid app access_token for server-side APIs
+	AccessToken(ctx context.Context) (string, error)
+	
+	// RunTokenRefresher keeps the access_token fresh until ctx is cancelled
+	RunTokenRefresher(ctx context.Context)
+	
+	// SendSubscribeMessage sends a subscribe message to a user
+	SendSubscribeMessage(ctx context.Context, msg *model.WechatSubscribeMessage) error
+	
+	// MsgSecCheck checks text written by a user and returns WeChat's suggestion
+	MsgSecCheck(ctx context.Context, openID string, scene int, content string) (string, error)
 }
 
 type wechatRepositoryImpl struct {
-	cfg config.WeChatConfig
+	cfg    config.WeChatConfig
+	client *http.Client
+	tokens *wechatTokenManager
 }
 
-func NewWechatRepository(cfg config.WeChatConfig) WechatRepository {
-	return &wechatRepositoryImpl{cfg: cfg}
+// NewWechatRepository creates a WeChat API client. A nil client uses a default
+// one with the configured timeout; a nil tokenRepo keeps access tokens in memory only.
+func NewWechatRepository(cfg config.WeChatConfig, client *http.Client, tokenRepo WechatAccessTokenRepository) WechatRepository {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	r := &wechatRepositoryImpl{cfg: cfg, client: client}
+	r.tokens = newWechatTokenManager(cfg.AppID, cfg.TokenRefreshBefore, tokenRepo, r.fetchAccessToken)
+	return r
 }
 
-func (r *wechatRepositoryImpl) Code2Session(code string) (*model.WechatLoginResponse, error) {
-	url := fmt.Sprintf("https://api.weixin.qq.com/sns/jscode2session?appid=%s&secret=%s&js_code=%s&grant_type=authorization_code",
-		r.cfg.AppID, r.cfg.AppSecret, code)
+func (r *wechatRepositoryImpl) Code2Session(ctx context.Context, code string) (*model.WechatLoginResponse, error) {
+	params := url.Values{}
+	params.Set("appid", r.cfg.AppID)
+	params.Set("secret", r.cfg.AppSecret)
+	params.Set("js_code", code)
+	params.Set("grant_type", "authorization_code")
 
-	resp, err := http.Get(url)
-	if err != nil {
+	var wechatResp model.WechatLoginResponse
+	if err := r.getJSON(ctx, "/sns/jscode2session", params, &wechatResp); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var wechatResp model.WechatLoginResponse
-	if err := json.NewDecoder(resp.Body).Decode(&wechatResp); err != nil {
+	if err := checkErrCode(wechatResp.ErrCode, wechatResp.ErrMsg); err != nil {
 		return nil, err
 	}
 
 	if wechatResp.OpenID == "" {
-		return nil, fmt.Errorf("wechat api error: %v", wechatResp)
+		return nil, fmt.Errorf("%w: response has no openid", ErrWechatUnavailable)
 	}
 
 	return &wechatResp, nil
-} 
\ No newline at end of file
+}
+
+func (r *wechatRepositoryImpl) AccessToken(ctx context.Context) (string, error) {
+	return r.tokens.Token(ctx)
+}
+
+func (r *wechatRepositoryImpl) RunTokenRefresher(ctx context.Context) {
+	r.tokens.Run(ctx)
+}
+
+func (r *wechatRepositoryImpl) SendSubscribeMessage(ctx context.Context, msg *model.WechatSubscribeMessage) error {
+	return r.callWithToken(ctx, "/cgi-bin/message/subscribe/send", msg, nil)
+}
+
+func (r *wechatRepositoryImpl) MsgSecCheck(ctx context.Context, openID string, scene int, content string) (string, error) {
+	body := map[string]interface{}{
+		"version": 2,
+		"openid":  openID,
+		"scene":   scene,
+		"content": content,
+	}
+
+	var checkResp model.WechatMsgSecCheckResponse
+	if err := r.callWithToken(ctx, "/wxa/msg_sec_check", body, &checkResp); err != nil {
+		return "", err
+	}
+	return checkResp.Result.Suggest, nil
+}
+
+// fetchAccessToken calls the stable_token API, which hands every caller the same
+// token until it expires, so instances refreshing concurrently do not invalidate each other
+func (r *wechatRepositoryImpl) fetchAccessToken(ctx context.Context, forceRefresh bool) (*model.WechatAccessToken, error) {
+	body := map[string]interface{}{
+		"grant_type":    "client_credential",
+		"appid":         r.cfg.AppID,
+		"secret":        r.cfg.AppSecret,
+		"force_refresh": forceRefresh,
+	}
+
+	var tokenResp model.WechatAccessTokenResponse
+	if err := r.postJSON(ctx, "/cgi-bin/stable_token", nil, body, &tokenResp); err != nil {
+		return nil, err
+	}
+	if err := checkErrCode(tokenResp.ErrCode, tokenResp.ErrMsg); err != nil {
+		return nil, err
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("%w: response has no access_token", ErrWechatUnavailable)
+	}
+
+	return &model.WechatAccessToken{
+		AppID:       r.cfg.AppID,
+		AccessToken: tokenResp.AccessToken,
+		ExpiresAt:   time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// callWithToken POSTs body to an API authenticated by access_token and decodes the
+// response into out. A rejected token is replaced and the call retried once.
+func (r *wechatRepositoryImpl) callWithToken(ctx context.Context, path string, body, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := r.tokens.Token(ctx)
+		if err != nil {
+			return err
+		}
+
+		params := url.Values{}
+		params.Set("access_token", token)
+		var raw json.RawMessage
+		if err := r.postJSON(ctx, path, params, body, &raw); err != nil {
+			return err
+		}
+
+		var result struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return fmt.Errorf("%w: invalid response: %v", ErrWechatUnavailable, err)
+		}
+		if err := checkErrCode(result.ErrCode, result.ErrMsg); err != nil {
+			var wechatErr *WechatError
+			if errors.As(err, &wechatErr) && wechatErr.expiredToken() && attempt == 0 {
+				r.tokens.Invalidate(token)
+				continue
+			}
+			return err
+		}
+
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(raw, out)
+	}
+}
+
+// getJSON performs a GET request against the WeChat API and decodes the JSON response
+func (r *wechatRepositoryImpl) getJSON(ctx context.Context, path string, params url.Values, out interface{}) error {
+	endpoint := strings.TrimRight(r.cfg.APIBaseURL, "/") + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return r.do(req, out)
+}
+
+// postJSON performs a POST request with a JSON body against the WeChat API and decodes the JSON response
+func (r *wechatRepositoryImpl) postJSON(ctx context.Context, path string, params url.Values, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(r.cfg.APIBaseURL, "/") + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return r.do(req, out)
+}
+
+func (r *wechatRepositoryImpl) do(req *http.Request, out interface{}) error {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// Never include the URL, it carries the app secret
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+		// The client's own timeout leaves the request context alone
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return fmt.Errorf("%w: %w", ErrWechatUnavailable, context.DeadlineExceeded)
+		}
+		return fmt.Errorf("%w: %v", ErrWechatUnavailable, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%w: unexpected status %d", ErrWechatUnavailable, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrWechatUnavailable, err)
+	}
+	return nil
+}
+
+func checkErrCode(code int, message string) error {
+	if code == 0 {
+		return nil
+	}
+	return &WechatError{Code: code, Message: message}
+}
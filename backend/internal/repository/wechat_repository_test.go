@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/repository/wechatfake"
+)
+
+// newFakeWechat starts a fake WeChat API and a repository pointed at it
+func newFakeWechat(t *testing.T, appSecret string) (*wechatfake.Server, WechatRepository) {
+	t.Helper()
+	fake := wechatfake.NewServer("wx-test-app", appSecret)
+	t.Cleanup(fake.Close)
+	return fake, NewWechatRepository(fake.Config(), nil, nil)
+}
+
+func TestCode2Session(t *testing.T) {
+	fake, repo := newFakeWechat(t, "test-secret")
+	fake.AddCode("good-code", "openid-1", "session-key-1", "unionid-1")
+
+	resp, err := repo.Code2Session(context.Background(), "good-code")
+	if err != nil {
+		t.Fatalf("Code2Session() error = %v", err)
+	}
+	if resp.OpenID != "openid-1" || resp.SessionKey != "session-key-1" || resp.UnionID != "unionid-1" {
+		t.Errorf("Code2Session() = %+v", resp)
+	}
+
+	// Codes are single use
+	if _, err := repo.Code2Session(context.Background(), "good-code"); !errors.Is(err, ErrWechatInvalidCode) {
+		t.Errorf("reused code: error = %v, want ErrWechatInvalidCode", err)
+	}
+}
+
+func TestCode2SessionErrCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		errcode int
+		want    error
+	}{
+		{name: "invalid code", errcode: wechatfake.ErrCodeInvalidCode, want: ErrWechatInvalidCode},
+		{name: "used code", errcode: wechatfake.ErrCodeCodeUsed, want: ErrWechatInvalidCode},
+		{name: "rate limited", errcode: wechatfake.ErrCodeRateLimited, want: ErrWechatRateLimited},
+		{name: "busy", errcode: wechatfake.ErrCodeBusy, want: ErrWechatBusy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, repo := newFakeWechat(t, "test-secret")
+			fake.AddCode("good-code", "openid-1", "session-key-1", "")
+			fake.FailNext("/sns/jscode2session", tt.errcode)
+
+			_, err := repo.Code2Session(context.Background(), "good-code")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Code2Session() error = %v, want %v", err, tt.want)
+			}
+			var wechatErr *WechatError
+			if !errors.As(err, &wechatErr) || wechatErr.Code != tt.errcode {
+				t.Errorf("Code2Session() error = %v, want errcode %d", err, tt.errcode)
+			}
+		})
+	}
+}
+
+func TestCode2SessionWrongSecret(t *testing.T) {
+	fake := wechatfake.NewServer("wx-test-app", "test-secret")
+	defer fake.Close()
+	fake.AddCode("good-code", "openid-1", "session-key-1", "")
+	cfg := fake.Config()
+	cfg.AppSecret = "other-secret"
+
+	_, err := NewWechatRepository(cfg, nil, nil).Code2Session(context.Background(), "good-code")
+	var wechatErr *WechatError
+	if !errors.As(err, &wechatErr) || wechatErr.Code != wechatfake.ErrCodeInvalidSecret {
+		t.Fatalf("Code2Session() error = %v, want errcode %d", err, wechatfake.ErrCodeInvalidSecret)
+	}
+	for _, sentinel := range []error{ErrWechatInvalidCode, ErrWechatRateLimited, ErrWechatBusy, ErrWechatUnavailable} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("Code2Session() error = %v, should not match %v", err, sentinel)
+		}
+	}
+}
+
+func TestCode2SessionEscapesQuery(t *testing.T) {
+	// The fake only accepts the secret and code if they arrive intact
+	fake, repo := newFakeWechat(t, "s3cr&t=a+b c#%")
+	fake.AddCode("code/with?&=", "openid-1", "session-key-1", "")
+
+	if _, err := repo.Code2Session(context.Background(), "code/with?&="); err != nil {
+		t.Fatalf("Code2Session() error = %v", err)
+	}
+}
+
+func TestCode2SessionClientTimeout(t *testing.T) {
+	fake := wechatfake.NewServer("wx-test-app", "test-secret")
+	defer fake.Close()
+	fake.AddCode("good-code", "openid-1", "session-key-1", "")
+	fake.Delay = 500 * time.Millisecond
+	cfg := fake.Config()
+	cfg.Timeout = 50 * time.Millisecond
+
+	_, err := NewWechatRepository(cfg, nil, nil).Code2Session(context.Background(), "good-code")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrWechatUnavailable) {
+		t.Fatalf("Code2Session() error = %v, want ErrWechatUnavailable and context.DeadlineExceeded", err)
+	}
+}
+
+func TestCode2SessionContext(t *testing.T) {
+	fake, repo := newFakeWechat(t, "test-secret")
+	fake.AddCode("good-code", "openid-1", "session-key-1", "")
+	fake.Delay = 500 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := repo.Code2Session(ctx, "good-code"); err != context.DeadlineExceeded {
+		t.Errorf("expired context: error = %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.Code2Session(ctx, "good-code"); err != context.Canceled {
+		t.Errorf("cancelled context: error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// refreshRetryDelay spaces out refreshes that did not yield a fresh token
+const refreshRetryDelay = 30 * time.Second
+
+// wechatTokenManager caches the app access_token. Tokens are served from memory
+// and refreshed in the background from refreshBefore their expiry; callers only
+// wait for a refresh once the token has expired. Tokens are shared with other
+// instances through the database, and concurrent refreshes are collapsed into a
+// single API call.
+type wechatTokenManager struct {
+	appID         string
+	refreshBefore time.Duration
+	fetch         func(ctx context.Context, forceRefresh bool) (*model.WechatAccessToken, error)
+	store         WechatAccessTokenRepository
+
+	mutex    sync.Mutex
+	current  *model.WechatAccessToken
+	rejected string
+	inflight *tokenRefresh
+	// lastRefresh is when the last refresh finished
+	lastRefresh time.Time
+}
+
+// tokenRefresh is a refresh in progress that other callers can wait on
+type tokenRefresh struct {
+	done  chan struct{}
+	token *model.WechatAccessToken
+	err   error
+}
+
+func newWechatTokenManager(appID string, refreshBefore time.Duration, store WechatAccessTokenRepository, fetch func(ctx context.Context, forceRefresh bool) (*model.WechatAccessToken, error)) *wechatTokenManager {
+	return &wechatTokenManager{
+		appID:         appID,
+		refreshBefore: refreshBefore,
+		fetch:         fetch,
+		store:         store,
+	}
+}
+
+// Token returns a valid access token, refreshing it if needed
+func (m *wechatTokenManager) Token(ctx context.Context) (string, error) {
+	m.mutex.Lock()
+	current := m.current
+	m.mutex.Unlock()
+
+	if m.fresh(current) {
+		return current.AccessToken, nil
+	}
+	if valid(current) {
+		m.mutex.Lock()
+		if time.Since(m.lastRefresh) >= refreshRetryDelay {
+			m.startRefresh()
+		}
+		m.mutex.Unlock()
+		return current.AccessToken, nil
+	}
+
+	token, err := m.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// Invalidate drops the cached token after WeChat rejected it as expired
+func (m *wechatTokenManager) Invalidate(accessToken string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rejected = accessToken
+	if m.current != nil && m.current.AccessToken == accessToken {
+		m.current = nil
+	}
+}
+
+// Run refreshes the token in the background shortly before it expires
+func (m *wechatTokenManager) Run(ctx context.Context) {
+	for {
+		wait := refreshRetryDelay
+		token, err := m.refresh(ctx)
+		if err != nil {
+			log.Printf("Failed to refresh wechat access token: %v", err)
+		} else if until := time.Until(token.ExpiresAt) - m.refreshBefore; until > 0 {
+			wait = until
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// fresh reports whether a token is not due for a refresh yet
+func (m *wechatTokenManager) fresh(token *model.WechatAccessToken) bool {
+	return token != nil && time.Now().Add(m.refreshBefore).Before(token.ExpiresAt)
+}
+
+// valid reports whether a token has not expired
+func valid(token *model.WechatAccessToken) bool {
+	return token != nil && time.Now().Before(token.ExpiresAt)
+}
+
+// refresh loads or fetches a new token, sharing the work with concurrent callers
+func (m *wechatTokenManager) refresh(ctx context.Context) (*model.WechatAccessToken, error) {
+	m.mutex.Lock()
+	if m.fresh(m.current) {
+		token := m.current
+		m.mutex.Unlock()
+		return token, nil
+	}
+	call := m.startRefresh()
+	m.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startRefresh returns the refresh in progress, starting one if there is none.
+// The caller holds the mutex.
+func (m *wechatTokenManager) startRefresh() *tokenRefresh {
+	if m.inflight == nil {
+		m.inflight = &tokenRefresh{done: make(chan struct{})}
+		// The refresh must outlive the request of the caller that started it
+		go m.doRefresh(m.inflight)
+	}
+	return m.inflight
+}
+
+func (m *wechatTokenManager) doRefresh(call *tokenRefresh) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	m.mutex.Lock()
+	rejected := m.rejected
+	current := m.current
+	m.mutex.Unlock()
+
+	call.token, call.err = m.load(ctx, rejected)
+	if call.err != nil && valid(current) && current.AccessToken != rejected {
+		// Keep serving the old token until it expires
+		log.Printf("Failed to refresh wechat access token, keeping the current one: %v", call.err)
+		call.token, call.err = current, nil
+	}
+
+	m.mutex.Lock()
+	m.lastRefresh = time.Now()
+	if call.err == nil {
+		m.current = call.token
+		if m.rejected == rejected {
+			m.rejected = ""
+		}
+	}
+	m.inflight = nil
+	m.mutex.Unlock()
+	close(call.done)
+}
+
+func (m *wechatTokenManager) load(ctx context.Context, rejected string) (*model.WechatAccessToken, error) {
+	// Another instance, or this one before a restart, may already hold a fresh token
+	var stored *model.WechatAccessToken
+	if m.store != nil {
+		var err error
+		stored, err = m.store.Get(ctx, m.appID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to load stored wechat access token: %v", err)
+		}
+		if err != nil || stored.AccessToken == rejected {
+			stored = nil
+		}
+		if m.fresh(stored) {
+			return stored, nil
+		}
+	}
+
+	// A token WeChat rejected would be handed out again unless we force a new one.
+	// stable_token may return a token that is due for a refresh itself; it is still
+	// used, and Token waits refreshRetryDelay before trying again.
+	token, err := m.fetch(ctx, rejected != "")
+	if err != nil {
+		if valid(stored) {
+			log.Printf("Failed to fetch wechat access token, using the stored one: %v", err)
+			return stored, nil
+		}
+		return nil, err
+	}
+
+	if m.store != nil {
+		if err := m.store.Save(ctx, token); err != nil {
+			log.Printf("Failed to store wechat access token: %v", err)
+		}
+	}
+	return token, nil
+}
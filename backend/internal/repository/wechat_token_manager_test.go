@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository/wechatfake"
+)
+
+const stableTokenPath = "/cgi-bin/stable_token"
+
+func newFakeWechatTokens(t *testing.T) (*wechatfake.Server, *wechatRepositoryImpl) {
+	t.Helper()
+	fake, repo := newFakeWechat(t, "test-secret")
+	return fake, repo.(*wechatRepositoryImpl)
+}
+
+// waitForRefresh waits until no refresh is in progress
+func waitForRefresh(t *testing.T, m *wechatTokenManager) {
+	t.Helper()
+	m.mutex.Lock()
+	call := m.inflight
+	m.mutex.Unlock()
+	if call == nil {
+		return
+	}
+	select {
+	case <-call.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh did not finish")
+	}
+}
+
+func TestTokenSingleFlight(t *testing.T) {
+	fake, repo := newFakeWechatTokens(t)
+	// Keep the first refresh in flight while the other callers arrive
+	fake.Delay = 100 * time.Millisecond
+
+	const callers = 20
+	tokens := make([]string, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = repo.AccessToken(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: AccessToken() error = %v", i, errs[i])
+		}
+		if tokens[i] != tokens[0] {
+			t.Errorf("caller %d got %q, want %q", i, tokens[i], tokens[0])
+		}
+	}
+	if got := fake.Requests(stableTokenPath); got != 1 {
+		t.Errorf("stable_token requests = %d, want 1", got)
+	}
+}
+
+func TestTokenCached(t *testing.T) {
+	fake, repo := newFakeWechatTokens(t)
+
+	first, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+	second, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+	if second != first {
+		t.Errorf("AccessToken() = %q, want the cached %q", second, first)
+	}
+	if got := fake.Requests(stableTokenPath); got != 1 {
+		t.Errorf("stable_token requests = %d, want 1", got)
+	}
+}
+
+func TestTokenInsideRefreshMargin(t *testing.T) {
+	fake, repo := newFakeWechatTokens(t)
+	// Every token is issued inside the 5 minute refresh margin
+	fake.TokenTTL = 2 * time.Minute
+
+	first, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+
+	// A refresh just happened, so the valid token is served without another
+	if token, err := repo.AccessToken(context.Background()); err != nil || token != first {
+		t.Fatalf("AccessToken() = %q, %v, want %q", token, err, first)
+	}
+	if got := fake.Requests(stableTokenPath); got != 1 {
+		t.Fatalf("stable_token requests = %d, want 1", got)
+	}
+
+	// Once the retry delay has passed the token is still served right away,
+	// while a refresh runs in the background
+	repo.tokens.mutex.Lock()
+	repo.tokens.lastRefresh = time.Now().Add(-refreshRetryDelay)
+	repo.tokens.mutex.Unlock()
+	fake.Delay = 200 * time.Millisecond
+
+	started := time.Now()
+	if token, err := repo.AccessToken(context.Background()); err != nil || token != first {
+		t.Fatalf("AccessToken() = %q, %v, want %q", token, err, first)
+	}
+	if waited := time.Since(started); waited >= fake.Delay {
+		t.Errorf("AccessToken() waited %s for the refresh", waited)
+	}
+	waitForRefresh(t, repo.tokens)
+	if got := fake.Requests(stableTokenPath); got != 2 {
+		t.Errorf("stable_token requests = %d, want 2", got)
+	}
+}
+
+func TestTokenRefreshAfterInvalidate(t *testing.T) {
+	fake, repo := newFakeWechatTokens(t)
+
+	first, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+	repo.tokens.Invalidate(first)
+
+	// stable_token hands out the same token until it expires, unless forced
+	second, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+	if second == first {
+		t.Errorf("AccessToken() after Invalidate = %q, want a new token", second)
+	}
+	if got := fake.Requests(stableTokenPath); got != 2 {
+		t.Errorf("stable_token requests = %d, want 2", got)
+	}
+}
+
+func TestTokenRejectedByAPI(t *testing.T) {
+	fake, repo := newFakeWechatTokens(t)
+
+	first, err := repo.AccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("AccessToken() error = %v", err)
+	}
+
+	// The token is rejected with 40001 once; the call is retried with a new one
+	fake.FailNext("/cgi-bin/message/subscribe/send", wechatfake.ErrCodeInvalidToken)
+	msg := &model.WechatSubscribeMessage{ToUser: "openid-1", TemplateID: "template-1"}
+	if err := repo.SendSubscribeMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SendSubscribeMessage() error = %v", err)
+	}
+
+	if got := len(fake.Messages()); got != 1 {
+		t.Errorf("messages sent = %d, want 1", got)
+	}
+	if got := fake.Requests(stableTokenPath); got != 2 {
+		t.Errorf("stable_token requests = %d, want 2", got)
+	}
+	if token, _ := repo.AccessToken(context.Background()); token == first {
+		t.Errorf("AccessToken() = %q, want the refreshed token", token)
+	}
+}
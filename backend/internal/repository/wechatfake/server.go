@@ -0,0 +1,308 @@
+// Package wechatfake provides an in-process fake of the WeChat server API for tests
+// and local development. Point config.WeChatConfig.APIBaseURL at Server.URL.
+package wechatfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+)
+
+// WeChat errcodes returned by the fake
+const (
+	ErrCodeBusy           = -1
+	ErrCodeInvalidToken   = 40001
+	ErrCodeInvalidAppID   = 40013
+	ErrCodeInvalidCode    = 40029
+	ErrCodeInvalidSecret  = 40125
+	ErrCodeCodeUsed       = 40163
+	ErrCodeTokenExpired   = 42001
+	ErrCodeRateLimited    = 45011
+	ErrCodeInvalidRequest = 47001
+)
+
+// Server is a fake WeChat API backed by httptest.Server
+type Server struct {
+	*httptest.Server
+
+	AppID     string
+	AppSecret string
+
+	// TokenTTL is the lifetime of issued access tokens
+	TokenTTL time.Duration
+
+	// BlockedWords makes msgSecCheck return "risky" for text containing any of them
+	BlockedWords []string
+
+	// Delay holds back every response, to exercise client timeouts
+	Delay time.Duration
+
+	mutex       sync.Mutex
+	sessions    map[string]model.WechatLoginResponse
+	failures    map[string]int
+	usedCodes   map[string]bool
+	requests    map[string]int
+	token       string
+	tokenExpiry time.Time
+	tokenSeq    int
+	messages    []model.WechatSubscribeMessage
+}
+
+// NewServer starts a fake WeChat API for the given app credentials
+func NewServer(appID, appSecret string) *Server {
+	s := &Server{
+		AppID:     appID,
+		AppSecret: appSecret,
+		sessions:  make(map[string]model.WechatLoginResponse),
+		failures:  make(map[string]int),
+		usedCodes: make(map[string]bool),
+		requests:  make(map[string]int),
+		TokenTTL:  2 * time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sns/jscode2session", s.handleCode2Session)
+	mux.HandleFunc("/cgi-bin/stable_token", s.handleStableToken)
+	mux.HandleFunc("/cgi-bin/message/subscribe/send", s.withToken(s.handleSubscribeSend))
+	mux.HandleFunc("/wxa/msg_sec_check", s.withToken(s.handleMsgSecCheck))
+	s.Server = httptest.NewServer(s.count(mux))
+	return s
+}
+
+// Config returns a WeChat configuration pointing at the fake
+func (s *Server) Config() config.WeChatConfig {
+	return config.WeChatConfig{
+		AppID:              s.AppID,
+		AppSecret:          s.AppSecret,
+		SessionKeySecret:   "wechatfake-session-key-secret",
+		APIBaseURL:         s.URL,
+		Timeout:            2 * time.Second,
+		TokenRefreshBefore: 5 * time.Minute,
+	}
+}
+
+// AddCode registers a login code that exchanges for the given session
+func (s *Server) AddCode(code, openID, sessionKey, unionID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[code] = model.WechatLoginResponse{OpenID: openID, SessionKey: sessionKey, UnionID: unionID}
+}
+
+// FailNext makes the next request to path return errcode
+func (s *Server) FailNext(path string, errcode int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures[path] = errcode
+}
+
+// Requests returns how many requests were made to path
+func (s *Server) Requests(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[path]
+}
+
+// ExpireToken makes the current access token invalid, as if it had been
+// replaced by a call from somewhere else
+func (s *Server) ExpireToken() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tokenExpiry = time.Now()
+}
+
+// ValidToken reports whether r carries the current access token
+func (s *Server) ValidToken(r *http.Request) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.token != "" && r.URL.Query().Get("access_token") == s.token && time.Now().Before(s.tokenExpiry)
+}
+
+// Messages returns the subscribe messages sent so far
+func (s *Server) Messages() []model.WechatSubscribeMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]model.WechatSubscribeMessage(nil), s.messages...)
+}
+
+// withToken rejects requests without the current access token
+func (s *Server) withToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		current := s.token != "" && r.URL.Query().Get("access_token") == s.token
+		expired := !time.Now().Before(s.tokenExpiry)
+		s.mutex.Unlock()
+
+		switch {
+		case !current:
+			writeError(w, ErrCodeInvalidToken)
+		case expired:
+			writeError(w, ErrCodeTokenExpired)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests[r.URL.Path]++
+		errcode, fail := s.failures[r.URL.Path]
+		delete(s.failures, r.URL.Path)
+		delay := s.Delay
+		s.mutex.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fail {
+			writeError(w, errcode)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCode2Session(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("appid") != s.AppID {
+		writeError(w, ErrCodeInvalidAppID)
+		return
+	}
+	if query.Get("secret") != s.AppSecret {
+		writeError(w, ErrCodeInvalidSecret)
+		return
+	}
+	if query.Get("grant_type") != "authorization_code" {
+		writeError(w, ErrCodeInvalidRequest)
+		return
+	}
+
+	code := query.Get("js_code")
+	s.mutex.Lock()
+	session, ok := s.sessions[code]
+	used := s.usedCodes[code]
+	if ok && !used {
+		// Codes are single use, exactly like the real API
+		s.usedCodes[code] = true
+	}
+	s.mutex.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, ErrCodeInvalidCode)
+	case used:
+		writeError(w, ErrCodeCodeUsed)
+	default:
+		writeJSON(w, session)
+	}
+}
+
+func (s *Server) handleStableToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GrantType    string `json:"grant_type"`
+		AppID        string `json:"appid"`
+		Secret       string `json:"secret"`
+		ForceRefresh bool   `json:"force_refresh"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil || req.GrantType != "client_credential" {
+		writeError(w, ErrCodeInvalidRequest)
+		return
+	}
+	if req.AppID != s.AppID {
+		writeError(w, ErrCodeInvalidAppID)
+		return
+	}
+	if req.Secret != s.AppSecret {
+		writeError(w, ErrCodeInvalidSecret)
+		return
+	}
+
+	s.mutex.Lock()
+	// Like the real stable_token API, the same token is returned until it expires
+	if req.ForceRefresh || s.token == "" || !time.Now().Before(s.tokenExpiry) {
+		s.tokenSeq++
+		s.token = fmt.Sprintf("fake-access-token-%d", s.tokenSeq)
+		s.tokenExpiry = time.Now().Add(s.TokenTTL)
+	}
+	token, expiresIn := s.token, int(time.Until(s.tokenExpiry).Seconds())
+	s.mutex.Unlock()
+
+	writeJSON(w, model.WechatAccessTokenResponse{AccessToken: token, ExpiresIn: expiresIn})
+}
+
+func (s *Server) handleSubscribeSend(w http.ResponseWriter, r *http.Request) {
+	var msg model.WechatSubscribeMessage
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&msg) != nil || msg.ToUser == "" || msg.TemplateID == "" {
+		writeError(w, ErrCodeInvalidRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.messages = append(s.messages, msg)
+	s.mutex.Unlock()
+
+	writeError(w, 0)
+}
+
+func (s *Server) handleMsgSecCheck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Version int    `json:"version"`
+		OpenID  string `json:"openid"`
+		Scene   int    `json:"scene"`
+		Content string `json:"content"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil || req.Version != 2 || req.OpenID == "" {
+		writeError(w, ErrCodeInvalidRequest)
+		return
+	}
+
+	var resp model.WechatMsgSecCheckResponse
+	resp.ErrMsg = "ok"
+	resp.Result.Suggest = "pass"
+	for _, word := range s.BlockedWords {
+		if strings.Contains(req.Content, word) {
+			resp.Result.Suggest = "risky"
+			resp.Result.Label = 20001
+		}
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	// WeChat reports errors in the body with a 200 status
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, errcode int) {
+	writeJSON(w, map[string]interface{}{
+		"errcode": errcode,
+		"errmsg":  errorMessages[errcode],
+	})
+}
+
+var errorMessages = map[int]string{
+	0:                     "ok",
+	ErrCodeBusy:           "system error",
+	ErrCodeInvalidToken:   "invalid credential, access_token is invalid or not latest",
+	ErrCodeInvalidAppID:   "invalid appid",
+	ErrCodeInvalidCode:    "invalid code",
+	ErrCodeInvalidSecret:  "invalid appsecret",
+	ErrCodeCodeUsed:       "code been used",
+	ErrCodeTokenExpired:   "access_token expired",
+	ErrCodeRateLimited:    "api minute-quota reach limit",
+	ErrCodeInvalidRequest: "data format error",
+}
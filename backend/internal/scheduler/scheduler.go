@@ -0,0 +1,94 @@
+// Package scheduler decides which queued generation job starts next. Jobs in
+// higher lanes go first; within a lane users take turns, so one user with many
+// jobs cannot hold up everyone else, and each user has a cap on jobs running
+// at once. It only works on a snapshot passed in, so it can run without a database.
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// Scheduler orders a generation queue
+type Scheduler struct {
+	// MaxInFlightPerUser caps the jobs one user has processing at once; 0 means no cap
+	MaxInFlightPerUser int
+}
+
+// New creates a Scheduler
+func New(maxInFlightPerUser int) *Scheduler {
+	return &Scheduler{MaxInFlightPerUser: maxInFlightPerUser}
+}
+
+// Order returns the queued jobs in the order they would start. Within a lane,
+// jobs are served in rounds: each round takes one job from every waiting user,
+// users whose last job started longest ago first. A user's running jobs count
+// as rounds already taken.
+func (s *Scheduler) Order(queue *model.GenerationQueue) []*model.GenerationJob {
+	type entry struct {
+		job         *model.GenerationJob
+		round       int
+		lastStarted time.Time
+	}
+
+	type userLane struct {
+		userID int64
+		lane   model.GenerationLane
+	}
+	taken := make(map[userLane]int)
+
+	entries := make([]entry, len(queue.Queued))
+	for i := range queue.Queued {
+		job := &queue.Queued[i]
+		key := userLane{job.UserID, job.Lane}
+		entries[i] = entry{
+			job:         job,
+			round:       taken[key] + queue.InFlight[job.UserID],
+			lastStarted: queue.LastStarted[job.UserID],
+		}
+		taken[key]++
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if rankA, rankB := a.job.Lane.Rank(), b.job.Lane.Rank(); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.round != b.round {
+			return a.round < b.round
+		}
+		if !a.lastStarted.Equal(b.lastStarted) {
+			return a.lastStarted.Before(b.lastStarted)
+		}
+		return a.job.ID < b.job.ID
+	})
+
+	jobs := make([]*model.GenerationJob, len(entries))
+	for i, e := range entries {
+		jobs[i] = e.job
+	}
+	return jobs
+}
+
+// Next returns the job to start now, or nil if every waiting user is at their cap
+func (s *Scheduler) Next(queue *model.GenerationQueue) *model.GenerationJob {
+	for _, job := range s.Order(queue) {
+		if s.MaxInFlightPerUser <= 0 || queue.InFlight[job.UserID] < s.MaxInFlightPerUser {
+			return job
+		}
+	}
+	return nil
+}
+
+// Positions returns the place of every queued job in line by request ID, 1
+// being next
+func (s *Scheduler) Positions(queue *model.GenerationQueue) map[string]int {
+	order := s.Order(queue)
+	positions := make(map[string]int, len(order))
+	for i, job := range order {
+		positions[job.RequestID] = i + 1
+	}
+	return positions
+}
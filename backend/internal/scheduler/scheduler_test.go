@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+var start = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// queued builds a queue from jobs written as user/lane pairs in submission
+// order; request IDs are "r<ID>"
+func queued(jobs ...interface{}) []model.GenerationJob {
+	var queue []model.GenerationJob
+	for i := 0; i < len(jobs); i += 2 {
+		id := int64(len(queue) + 1)
+		queue = append(queue, model.GenerationJob{
+			ID:        id,
+			RequestID: fmt.Sprintf("r%d", id),
+			UserID:    int64(jobs[i].(int)),
+			Lane:      jobs[i+1].(model.GenerationLane),
+		})
+	}
+	return queue
+}
+
+const (
+	priority = model.GenerationLanePriority
+	standard = model.GenerationLaneStandard
+	trial    = model.GenerationLaneTrial
+)
+
+func requestIDs(jobs []*model.GenerationJob) []string {
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.RequestID
+	}
+	return ids
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		queue model.GenerationQueue
+		want  []string
+	}{
+		{
+			name:  "empty queue",
+			queue: model.GenerationQueue{},
+			want:  []string{},
+		},
+		{
+			name:  "higher lanes first",
+			queue: model.GenerationQueue{Queued: queued(1, trial, 2, standard, 3, priority, 4, standard)},
+			want:  []string{"r3", "r2", "r4", "r1"},
+		},
+		{
+			name:  "users take turns",
+			queue: model.GenerationQueue{Queued: queued(1, standard, 1, standard, 1, standard, 2, standard, 3, standard, 2, standard)},
+			want:  []string{"r1", "r4", "r5", "r2", "r6", "r3"},
+		},
+		{
+			name: "running jobs count as rounds taken",
+			queue: model.GenerationQueue{
+				Queued:   queued(1, standard, 2, standard, 2, standard),
+				InFlight: map[int64]int{1: 2},
+			},
+			want: []string{"r2", "r3", "r1"},
+		},
+		{
+			name: "user who started longest ago goes first within a round",
+			queue: model.GenerationQueue{
+				Queued:      queued(1, standard, 2, standard, 3, standard),
+				LastStarted: map[int64]time.Time{1: start, 2: start.Add(-time.Hour)},
+			},
+			// User 3 never started a job, which is longest ago
+			want: []string{"r3", "r2", "r1"},
+		},
+		{
+			name:  "rounds are counted per lane",
+			queue: model.GenerationQueue{Queued: queued(1, standard, 1, priority, 2, standard)},
+			want:  []string{"r2", "r1", "r3"},
+		},
+	}
+
+	scheduler := New(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestIDs(scheduler.Order(&tt.queue)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxInFlight int
+		queue       model.GenerationQueue
+		want        string
+	}{
+		{
+			name:  "empty queue",
+			queue: model.GenerationQueue{},
+		},
+		{
+			name:  "first in order",
+			queue: model.GenerationQueue{Queued: queued(1, standard, 2, priority)},
+			want:  "r2",
+		},
+		{
+			name:        "skips users at their cap",
+			maxInFlight: 1,
+			queue: model.GenerationQueue{
+				Queued:   queued(1, priority, 2, trial),
+				InFlight: map[int64]int{1: 1},
+			},
+			want: "r2",
+		},
+		{
+			name:        "nothing when every user is at their cap",
+			maxInFlight: 2,
+			queue: model.GenerationQueue{
+				Queued:   queued(1, standard, 1, standard),
+				InFlight: map[int64]int{1: 2},
+			},
+		},
+		{
+			name: "no cap",
+			queue: model.GenerationQueue{
+				Queued:   queued(1, standard),
+				InFlight: map[int64]int{1: 10},
+			},
+			want: "r1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := New(tt.maxInFlight).Next(&tt.queue)
+			got := ""
+			if job != nil {
+				got = job.RequestID
+			}
+			if got != tt.want {
+				t.Errorf("Next() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Claims only show the scheduler the first ready job of each user in each
+// lane; Next must pick the same job as it would from the whole queue
+func TestNextFromCandidates(t *testing.T) {
+	queue := model.GenerationQueue{
+		Queued:      queued(1, standard, 1, standard, 2, trial, 3, standard, 3, standard, 2, standard, 1, priority, 4, trial),
+		InFlight:    map[int64]int{1: 1, 3: 1},
+		LastStarted: map[int64]time.Time{1: start, 3: start.Add(-time.Minute)},
+	}
+
+	for _, maxInFlight := range []int{0, 1, 2} {
+		scheduler := New(maxInFlight)
+		want := scheduler.Next(&queue)
+
+		candidates := model.GenerationQueue{InFlight: queue.InFlight, LastStarted: queue.LastStarted}
+		seen := make(map[string]bool)
+		for _, job := range queue.Queued {
+			key := fmt.Sprintf("%d/%s", job.UserID, job.Lane)
+			if !seen[key] {
+				seen[key] = true
+				candidates.Queued = append(candidates.Queued, job)
+			}
+		}
+		got := scheduler.Next(&candidates)
+
+		if (got == nil) != (want == nil) || got != nil && got.RequestID != want.RequestID {
+			t.Errorf("max in flight %d: Next() from candidates = %v, want %v", maxInFlight, got, want)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	queue := model.GenerationQueue{Queued: queued(1, standard, 1, standard, 2, standard, 3, priority)}
+	want := map[string]int{"r4": 1, "r1": 2, "r3": 3, "r2": 4}
+	if got := New(1).Positions(&queue); !reflect.DeepEqual(got, want) {
+		t.Errorf("Positions() = %v, want %v", got, want)
+	}
+}
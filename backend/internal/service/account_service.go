@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/blobstore"
+)
+
+// exportPageSize is the number of transactions read per query while exporting
+const exportPageSize = 500
+
+// ErrAccountDeleted is returned when acting on an account that has already been deleted
+var ErrAccountDeleted = errors.New("account has been deleted")
+
+// AccountService defines the interface for account deletion and personal data export
+type AccountService interface {
+	// DeleteAccount revokes all sessions, purges stored media and anonymizes the user.
+	// Transactions are kept for accounting and only reference the anonymized user ID.
+	DeleteAccount(ctx context.Context, userID int64) error
+	
+	// ExportData collects the personal data stored for a user
+	ExportData(ctx context.Context, userID int64) (*model.AccountExport, error)
+}
+
+type accountServiceImpl struct {
+	userRepo            repository.UserRepository
+	transactionRepo     repository.TransactionRepository
+	subscriptionRepo    repository.SubscriptionRepository
+	subscriptionService SubscriptionService
+	sessionService      SessionService
+	blobStore           blobstore.Store
+}
+
+// NewAccountService creates a new instance of AccountService
+func NewAccountService(userRepo repository.UserRepository, transactionRepo repository.TransactionRepository, subscriptionRepo repository.SubscriptionRepository, subscriptionService SubscriptionService, sessionService SessionService, blobStore blobstore.Store) AccountService {
+	return &accountServiceImpl{
+		userRepo:            userRepo,
+		transactionRepo:     transactionRepo,
+		subscriptionRepo:    subscriptionRepo,
+		subscriptionService: subscriptionService,
+		sessionService:      sessionService,
+		blobStore:           blobStore,
+	}
+}
+
+func (s *accountServiceImpl) DeleteAccount(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDeleted() {
+		return ErrAccountDeleted
+	}
+
+	// Sessions go first so a failure further down leaves nothing half-deleted
+	// that the user could keep using; logging in again allows a retry.
+	if err := s.sessionService.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.blobStore.DeletePrefix(ctx, blobstore.UserPrefix(userID)); err != nil {
+		return fmt.Errorf("failed to purge media: %w", err)
+	}
+	if err := s.subscriptionRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete subscription grants: %w", err)
+	}
+	if err := s.userRepo.Anonymize(ctx, userID); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	return nil
+}
+
+func (s *accountServiceImpl) ExportData(ctx context.Context, userID int64) (*model.AccountExport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+
+	export := &model.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: model.AccountExportProfile{
+			ID:            user.ID,
+			WechatOpenID:  user.WechatOpenID,
+			WechatUnionID: user.WechatUnionID,
+			Nickname:      user.Nickname,
+			AvatarURL:     user.AvatarURL,
+			Credits:       user.Credits,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Transactions: []model.Transaction{},
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		page, err := s.transactionRepo.GetByUserID(ctx, userID, exportPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transactions: %w", err)
+		}
+		export.Transactions = append(export.Transactions, page...)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	subscriptions, err := s.subscriptionService.GetSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Subscriptions = subscriptions
+	if export.Subscriptions == nil {
+		export.Subscriptions = []model.SubscriptionStatus{}
+	}
+
+	return export, nil
+}
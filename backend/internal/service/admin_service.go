@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minAdminPasswordLength = 12
+
+var (
+	// ErrInvalidAdminCredentials is returned for an unknown username or a wrong password
+	ErrInvalidAdminCredentials = errors.New("invalid username or password")
+
+	// ErrAdminDisabled is returned when a disabled admin tries to authenticate
+	ErrAdminDisabled = errors.New("admin account is disabled")
+
+	// ErrAdminExists is returned when creating an admin with a username that is taken
+	ErrAdminExists = errors.New("admin username already exists")
+
+	// ErrAdminSelfLockout is returned when an admin tries to disable or demote themselves
+	ErrAdminSelfLockout = errors.New("admins cannot disable themselves or remove their own superadmin role")
+
+	adminUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,64}$`)
+)
+
+// AdminService defines the interface for admin accounts and authentication
+type AdminService interface {
+	// Login checks an admin's password and issues an admin access token.
+	// Every attempt is written to the audit log.
+	Login(ctx context.Context, username, password, ip string) (*model.AdminLoginResponse, error)
+	
+	// ParseToken validates an admin access token and returns the current admin account
+	ParseToken(ctx context.Context, token string) (*model.AdminUser, error)
+	
+	// CreateAdmin creates an admin account
+	CreateAdmin(ctx context.Context, req *model.AdminCreateRequest) (*model.AdminUser, error)
+	
+	// ListAdmins lists all admin accounts
+	ListAdmins(ctx context.Context) ([]model.AdminUser, error)
+	
+	// UpdateAdmin changes another admin's roles or disabled status
+	UpdateAdmin(ctx context.Context, actorID, id int64, req *model.AdminUpdateRequest) (*model.AdminUser, error)
+}
+
+type adminServiceImpl struct {
+	cfg          config.AdminConfig
+	jwtCfg       config.JWTConfig
+	keySet       *jwtkeys.KeySet
+	repo         repository.AdminRepository
+	auditService AuditService
+	// dummyHash is compared against when the username is unknown so that
+	// response times do not reveal which usernames exist
+	dummyHash []byte
+}
+
+// NewAdminService creates a new instance of AdminService
+func NewAdminService(cfg config.AdminConfig, jwtCfg config.JWTConfig, keySet *jwtkeys.KeySet, repo repository.AdminRepository, auditService AuditService) AdminService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return &adminServiceImpl{
+		cfg:          cfg,
+		jwtCfg:       jwtCfg,
+		keySet:       keySet,
+		repo:         repo,
+		auditService: auditService,
+		dummyHash:    dummyHash,
+	}
+}
+
+func (s *adminServiceImpl) Login(ctx context.Context, username, password, ip string) (*model.AdminLoginResponse, error) {
+	admin, err := s.repo.GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	if admin == nil {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		s.recordLogin(ctx, nil, username, ip, http.StatusUnauthorized)
+		return nil, ErrInvalidAdminCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)); err != nil {
+		s.recordLogin(ctx, &admin.ID, username, ip, http.StatusUnauthorized)
+		return nil, ErrInvalidAdminCredentials
+	}
+	if admin.IsDisabled() {
+		s.recordLogin(ctx, &admin.ID, username, ip, http.StatusForbidden)
+		return nil, ErrAdminDisabled
+	}
+
+	token, err := s.generateToken(admin.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if err := s.repo.UpdateLastLogin(ctx, admin.ID); err != nil {
+		return nil, fmt.Errorf("failed to update last login: %w", err)
+	}
+	s.recordLogin(ctx, &admin.ID, username, ip, http.StatusOK)
+
+	return &model.AdminLoginResponse{
+		Token:     token,
+		ExpiresIn: int64(s.cfg.TokenExpiry.Seconds()),
+		Admin:     admin,
+	}, nil
+}
+
+func (s *adminServiceImpl) ParseToken(ctx context.Context, tokenString string) (*model.AdminUser, error) {
+	token, err := jwt.Parse(tokenString, s.keySet.Keyfunc,
+		jwt.WithValidMethods(s.keySet.Methods()),
+		jwt.WithIssuer(s.jwtCfg.Issuer),
+		jwt.WithAudience(s.cfg.Audience),
+		jwt.WithLeeway(s.jwtCfg.Leeway),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	adminID, ok := mapClaims["sub"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid admin ID in token")
+	}
+
+	// Roles and status are read on every request so changes apply immediately
+	admin, err := s.repo.GetByID(ctx, int64(adminID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+	if admin.IsDisabled() {
+		return nil, ErrAdminDisabled
+	}
+	return admin, nil
+}
+
+func (s *adminServiceImpl) CreateAdmin(ctx context.Context, req *model.AdminCreateRequest) (*model.AdminUser, error) {
+	if !adminUsernamePattern.MatchString(req.Username) {
+		return nil, &ValidationError{Field: "username", Message: "must be 3-64 letters, digits, '_', '.' or '-'"}
+	}
+	if utf8.RuneCountInString(req.Password) < minAdminPasswordLength {
+		return nil, &ValidationError{Field: "password", Message: fmt.Sprintf("must be at least %d characters", minAdminPasswordLength)}
+	}
+	if err := validateAdminRoles(req.Roles); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByUsername(ctx, req.Username); err == nil {
+		return nil, ErrAdminExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	admin := &model.AdminUser{
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		Roles:        req.Roles,
+	}
+	if err := s.repo.Create(ctx, admin); err != nil {
+		// Another request created the same username since the check above
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrAdminExists
+		}
+		return nil, fmt.Errorf("failed to create admin: %w", err)
+	}
+	return s.repo.GetByID(ctx, admin.ID)
+}
+
+func (s *adminServiceImpl) ListAdmins(ctx context.Context) ([]model.AdminUser, error) {
+	admins, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admins: %w", err)
+	}
+	if admins == nil {
+		admins = []model.AdminUser{}
+	}
+	return admins, nil
+}
+
+func (s *adminServiceImpl) UpdateAdmin(ctx context.Context, actorID, id int64, req *model.AdminUpdateRequest) (*model.AdminUser, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	if req.Roles != nil {
+		if err := validateAdminRoles(req.Roles); err != nil {
+			return nil, err
+		}
+		if actorID == id && !(&model.AdminUser{Roles: req.Roles}).HasRole(model.AdminRoleSuperAdmin) {
+			return nil, ErrAdminSelfLockout
+		}
+		if err := s.repo.UpdateRoles(ctx, id, req.Roles); err != nil {
+			return nil, fmt.Errorf("failed to update roles: %w", err)
+		}
+	}
+
+	if req.Disabled != nil {
+		if actorID == id && *req.Disabled {
+			return nil, ErrAdminSelfLockout
+		}
+		if err := s.repo.SetDisabled(ctx, id, *req.Disabled); err != nil {
+			return nil, fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// generateToken issues an admin access token. It is signed with the same keys
+// as user tokens but carries the admin audience.
+func (s *adminServiceImpl) generateToken(adminID int64) (string, error) {
+	tokenID, err := newRandomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti": tokenID,
+		"sub": adminID,
+		"iss": s.jwtCfg.Issuer,
+		"aud": s.cfg.Audience,
+		"exp": now.Add(s.cfg.TokenExpiry).Unix(),
+		"iat": now.Unix(),
+	}
+	return s.keySet.Sign(claims)
+}
+
+// recordLogin writes a login attempt to the audit log. Failures are logged
+// rather than returned so an audit outage does not lock admins out.
+func (s *adminServiceImpl) recordLogin(ctx context.Context, adminID *int64, username, ip string, status int) {
+	details, _ := json.Marshal(map[string]string{"username": username})
+	entry := &model.AuditLog{
+		AdminID:    adminID,
+		Action:     "admin.login",
+		TargetType: "admin",
+		StatusCode: status,
+		IP:         ip,
+		Details:    details,
+	}
+	if adminID != nil {
+		entry.TargetID = strconv.FormatInt(*adminID, 10)
+	}
+	if err := s.auditService.Record(ctx, entry); err != nil {
+		log.Printf("Failed to audit admin login for %q: %v", username, err)
+	}
+}
+
+func validateAdminRoles(roles []model.AdminRole) error {
+	if len(roles) == 0 {
+		return &ValidationError{Field: "roles", Message: "at least one role is required"}
+	}
+	for _, role := range roles {
+		known := false
+		for _, candidate := range model.AdminRoles {
+			if role == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return &ValidationError{Field: "roles", Message: fmt.Sprintf("unknown role %q", role)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+// AuditService defines the interface for the admin audit trail
+type AuditService interface {
+	// Record appends an entry to the audit log
+	Record(ctx context.Context, entry *model.AuditLog) error
+	
+	// List retrieves audit log entries, newest first
+	List(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLog, error)
+}
+
+type auditServiceImpl struct {
+	repo repository.AuditLogRepository
+}
+
+// NewAuditService creates a new instance of AuditService
+func NewAuditService(repo repository.AuditLogRepository) AuditService {
+	return &auditServiceImpl{repo: repo}
+}
+
+func (s *auditServiceImpl) Record(ctx context.Context, entry *model.AuditLog) error {
+	if err := s.repo.Create(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+func (s *auditServiceImpl) List(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLog, error) {
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	if entries == nil {
+		entries = []model.AuditLog{}
+	}
+	return entries, nil
+}
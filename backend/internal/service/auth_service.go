@@ -2,23 +2,39 @@ package service
 
 import (
 	"context"
+	"errors"
+
 	"github.com/45ai/backend/internal/model"
 )
 
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown or expired
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
 // AuthService defines the interface for authentication business logic
 type AuthService interface {
 	// LoginWithWechat authenticates a user with WeChat code
-	LoginWithWechat(ctx context.Context, code string) (*model.User, string, error)
+	LoginWithWechat(ctx context.Context, code string) (*model.User, *model.TokenPair, error)
 	
 	// GenerateToken generates a new JWT for a given user ID
-	GenerateToken(userID uint) (string, error)
+	GenerateToken(userID int64) (string, error)
 	
 	// ValidateToken validates a JWT token and returns the user ID
 	ValidateToken(ctx context.Context, token string) (int64, error)
 	
-	// RefreshToken refreshes an existing JWT token
-	RefreshToken(ctx context.Context, oldToken string) (string, error)
+	// ParseToken validates a JWT token and returns its claims
+	ParseToken(ctx context.Context, token string) (*AccessClaims, error)
+	
+	// RefreshToken rotates a refresh token and issues a new token pair
+	RefreshToken(ctx context.Context, refreshToken string) (*model.TokenPair, error)
+	
+	// Logout revokes the session the refresh token belongs to
+	Logout(ctx context.Context, refreshToken string) error
 	
 	// GetUserFromToken retrieves user information from a JWT token
 	GetUserFromToken(ctx context.Context, token string) (*model.User, error)
-} 
\ No newline at end of file
+}
@@ -2,101 +2,309 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"net/http"
+	"math"
 	"time"
 
 	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/jwtkeys"
+	"github.com/45ai/backend/pkg/secretbox"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type authServiceImpl struct {
-	cfg        config.JWTConfig
-	userRepo   repository.UserRepository
-	wechatRepo repository.WechatRepository
+	cfg              config.JWTConfig
+	keySet           *jwtkeys.KeySet
+	sessionKeyBox    *secretbox.Box
+	userRepo         repository.UserRepository
+	wechatRepo       repository.WechatRepository
+	refreshTokenRepo repository.RefreshTokenRepository
 }
 
 // NewAuthService creates a new instance of AuthService
-func NewAuthService(cfg config.JWTConfig, userRepo repository.UserRepository, wechatRepo repository.WechatRepository) AuthService {
+func NewAuthService(cfg config.JWTConfig, keySet *jwtkeys.KeySet, sessionKeyBox *secretbox.Box, userRepo repository.UserRepository, wechatRepo repository.WechatRepository, refreshTokenRepo repository.RefreshTokenRepository) AuthService {
 	return &authServiceImpl{
-		cfg:        cfg,
-		userRepo:   userRepo,
-		wechatRepo: wechatRepo,
+		cfg:              cfg,
+		keySet:           keySet,
+		sessionKeyBox:    sessionKeyBox,
+		userRepo:         userRepo,
+		wechatRepo:       wechatRepo,
+		refreshTokenRepo: refreshTokenRepo,
 	}
 }
 
-func (s *authServiceImpl) LoginWithWechat(ctx context.Context, code string) (*model.User, string, error) {
+func (s *authServiceImpl) LoginWithWechat(ctx context.Context, code string) (*model.User, *model.TokenPair, error) {
 	// Exchange code for openid and session_key
-	wechatResp, err := s.wechatRepo.Code2Session(code)
+	wechatResp, err := s.wechatRepo.Code2Session(ctx, code)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to exchange wechat code: %w", err)
+		return nil, nil, fmt.Errorf("failed to exchange wechat code: %w", err)
+	}
+
+	var unionID *string
+	if wechatResp.UnionID != "" {
+		unionID = &wechatResp.UnionID
 	}
 
 	// Find or create user
-	user, err := s.userRepo.GetByWechatOpenID(ctx, wechatResp.OpenID)
+	user, err := s.findWechatUser(ctx, wechatResp.OpenID, unionID)
 	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		}
 		// If user not found, create a new one
 		user = &model.User{
-			WechatOpenID: wechatResp.OpenID,
-			Credits:      0, // Initial credits
+			WechatOpenID:  wechatResp.OpenID,
+			WechatUnionID: unionID,
+			Credits:       0, // Initial credits
 		}
 		if err := s.userRepo.Create(ctx, user); err != nil {
-			return nil, "", fmt.Errorf("failed to create user: %w", err)
+			return nil, nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+	if user.IsBanned() {
+		return nil, nil, ErrUserBanned
+	}
+
+	// Keep the latest session key so encrypted payloads from wx APIs can be decrypted
+	// later; without a session key secret it is not kept
+	var sealedSessionKey string
+	if s.sessionKeyBox != nil {
+		sealedSessionKey, err = s.sessionKeyBox.Seal([]byte(wechatResp.SessionKey))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to seal session key: %w", err)
+		}
+	}
+	// A user found through the UnionID fallback has the OpenID of another app, which
+	// cannot be used for subscribe messages or content checks in this mini program
+	if err := s.userRepo.UpdateWechatSession(ctx, user.ID, wechatResp.OpenID, unionID, sealedSessionKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to store wechat session: %w", err)
+	}
+	user.WechatOpenID = wechatResp.OpenID
+	if user.WechatUnionID == nil {
+		user.WechatUnionID = unionID
+	}
+
+	// Every login starts a new refresh token family
+	familyID, err := newRandomID()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate token family: %w", err)
+	}
+
+	tokens, err := s.issueTokenPair(ctx, user.ID, familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// findWechatUser looks a user up by OpenID, falling back to the UnionID so that
+// accounts created from another app under the same WeChat Open Platform are reused
+func (s *authServiceImpl) findWechatUser(ctx context.Context, openID string, unionID *string) (*model.User, error) {
+	user, err := s.userRepo.GetByWechatOpenID(ctx, openID)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) || unionID == nil {
+		return user, err
+	}
+	return s.userRepo.GetByWechatUnionID(ctx, *unionID)
+}
+
+// RefreshToken rotates a refresh token. Each refresh token can be used exactly once;
+// presenting a rotated token again revokes the whole family, since either the
+// legitimate client or an attacker is holding a stolen copy.
+func (s *authServiceImpl) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
 		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored.FamilyID)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDeleted() {
+		return nil, ErrInvalidRefreshToken
+	}
+	if user.IsBanned() {
+		return nil, ErrUserBanned
 	}
 
-	// Generate JWT token
-	token, err := s.GenerateToken(user.ID)
+	revoked, err := s.refreshTokenRepo.Revoke(ctx, stored.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if !revoked {
+		// Another request rotated this token between our read and our update
+		return nil, s.revokeReusedFamily(ctx, stored.FamilyID)
 	}
 
-	return user, token, nil
+	return s.issueTokenPair(ctx, stored.UserID, stored.FamilyID)
 }
 
-func (s *authServiceImpl) RefreshToken(ctx context.Context, oldToken string) (string, error) {
-	// Future implementation
-	return "", fmt.Errorf("not implemented")
+func (s *authServiceImpl) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Logging out an unknown session is a no-op
+			return nil
+		}
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
 }
 
 func (s *authServiceImpl) GetUserFromToken(ctx context.Context, token string) (*model.User, error) {
-	// Future implementation
-	return nil, fmt.Errorf("not implemented")
+	userID, err := s.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(ctx, userID)
 }
 
-// GenerateToken generates a new JWT for a given user ID
-func (s *authServiceImpl) GenerateToken(userID uint) (string, error) {
+// GenerateToken generates a new JWT for a given user ID, signed with the current signing key
+func (s *authServiceImpl) GenerateToken(userID int64) (string, error) {
+	tokenID, err := newRandomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti": tokenID,
 		"sub": userID,
-		"exp": time.Now().Add(s.cfg.Expiry).Unix(),
-		"iat": time.Now().Unix(),
+		"iss": s.cfg.Issuer,
+		"aud": s.cfg.Audience,
+		"exp": now.Add(s.cfg.Expiry).Unix(),
+		// Milliseconds, so a token issued just after a session revocation is
+		// told apart from the ones it revoked
+		"iat": float64(now.UnixMilli()) / 1000,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.cfg.Secret))
+	return s.keySet.Sign(claims)
 }
 
-// ValidateToken validates a JWT and returns the user ID
+// ValidateToken validates a JWT against any active key and returns the user ID
 func (s *authServiceImpl) ValidateToken(ctx context.Context, tokenString string) (int64, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.cfg.Secret), nil
-	})
-
+	claims, err := s.ParseToken(ctx, tokenString)
 	if err != nil {
 		return 0, err
 	}
+	return claims.UserID, nil
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if userID, ok := claims["sub"].(float64); ok {
-			return int64(userID), nil
-		}
-		return 0, fmt.Errorf("invalid user ID in token")
+// ParseToken validates a JWT against any active key and returns its claims
+func (s *authServiceImpl) ParseToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keySet.Keyfunc,
+		jwt.WithValidMethods(s.keySet.Methods()),
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithAudience(s.cfg.Audience),
+		jwt.WithLeeway(s.cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return 0, fmt.Errorf("invalid token")
-} 
\ No newline at end of file
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	userID, ok := mapClaims["sub"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid user ID in token")
+	}
+
+	claims := &AccessClaims{UserID: int64(userID)}
+	claims.TokenID, _ = mapClaims["jti"].(string)
+	// GetIssuedAt truncates to whole seconds
+	if iat, ok := mapClaims["iat"].(float64); ok {
+		claims.IssuedAt = time.UnixMilli(int64(math.Round(iat * 1000)))
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	return claims, nil
+}
+
+// issueTokenPair creates an access token and stores a new refresh token in the given family
+func (s *authServiceImpl) issueTokenPair(ctx context.Context, userID int64, familyID string) (*model.TokenPair, error) {
+	accessToken, err := s.GenerateToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	stored := &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.cfg.RefreshExpiry),
+	}
+	if err := s.refreshTokenRepo.Create(ctx, stored); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.cfg.Expiry.Seconds()),
+	}, nil
+}
+
+func (s *authServiceImpl) revokeReusedFamily(ctx context.Context, familyID string) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// randomToken returns n random bytes encoded for use in URLs and JSON bodies
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// newRandomID returns a random 32 character hex identifier
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+const categoryNameMaxLength = 100
+
+var (
+	// ErrCategorySlugTaken is returned when another category already has the slug
+	ErrCategorySlugTaken = errors.New("category slug already exists")
+
+	categorySlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+)
+
+// CategoryService defines the interface for template category business logic
+type CategoryService interface {
+	// ListCategories retrieves all categories with their active template counts
+	ListCategories(ctx context.Context) ([]model.TemplateCategory, error)
+	
+	// CreateCategory creates a category
+	CreateCategory(ctx context.Context, req *model.TemplateCategoryRequest) (*model.TemplateCategory, error)
+	
+	// UpdateCategory updates a category
+	UpdateCategory(ctx context.Context, id int, req *model.TemplateCategoryRequest) (*model.TemplateCategory, error)
+	
+	// DeleteCategory deletes a category; its templates stay in their other categories
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+type categoryServiceImpl struct {
+	repo repository.TemplateCategoryRepository
+}
+
+// NewCategoryService creates a new instance of CategoryService
+func NewCategoryService(repo repository.TemplateCategoryRepository) CategoryService {
+	return &categoryServiceImpl{repo: repo}
+}
+
+func (s *categoryServiceImpl) ListCategories(ctx context.Context) ([]model.TemplateCategory, error) {
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	if categories == nil {
+		categories = []model.TemplateCategory{}
+	}
+	return categories, nil
+}
+
+func (s *categoryServiceImpl) CreateCategory(ctx context.Context, req *model.TemplateCategoryRequest) (*model.TemplateCategory, error) {
+	category := &model.TemplateCategory{}
+	if err := s.apply(ctx, category, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+func (s *categoryServiceImpl) UpdateCategory(ctx context.Context, id int, req *model.TemplateCategoryRequest) (*model.TemplateCategory, error) {
+	category, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	if err := s.apply(ctx, category, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+	return category, nil
+}
+
+func (s *categoryServiceImpl) DeleteCategory(ctx context.Context, id int) error {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("failed to get category: %w", err)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	return nil
+}
+
+// apply validates the request and copies it onto the category
+func (s *categoryServiceImpl) apply(ctx context.Context, category *model.TemplateCategory, req *model.TemplateCategoryRequest) error {
+	slug := strings.TrimSpace(req.Slug)
+	name := strings.TrimSpace(req.Name)
+	if !categorySlugPattern.MatchString(slug) {
+		return &ValidationError{Field: "slug", Message: "must be 1-64 lowercase letters, digits or '-'"}
+	}
+	if length := utf8.RuneCountInString(name); length == 0 || length > categoryNameMaxLength {
+		return &ValidationError{Field: "name", Message: fmt.Sprintf("must be 1 to %d characters", categoryNameMaxLength)}
+	}
+
+	existing, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check category slug: %w", err)
+	}
+	if existing != nil && existing.ID != category.ID {
+		return ErrCategorySlugTaken
+	}
+
+	category.Slug = slug
+	category.Name = name
+	category.SortOrder = req.SortOrder
+	return nil
+}
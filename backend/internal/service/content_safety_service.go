@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"io"
+
+	"github.com/45ai/backend/internal/repository"
 )
 
 type ContentSafetyService interface {
 	ValidateImage(ctx context.Context, image io.Reader) (bool, error)
+
+	// ValidateText checks user-written text. openID identifies the author for
+	// providers that weigh the user's history.
+	ValidateText(ctx context.Context, openID string, text string) (bool, error)
 }
 
 type mockContentSafetyService struct{}
@@ -19,4 +26,38 @@ func (s *mockContentSafetyService) ValidateImage(ctx context.Context, image io.R
 	// In a real implementation, this would call a third-party API.
 	// For now, we'll just assume all images are safe.
 	return true, nil
-} 
\ No newline at end of file
+}
+
+func (s *mockContentSafetyService) ValidateText(ctx context.Context, openID string, text string) (bool, error) {
+	return true, nil
+}
+
+// msgSecCheck scene for user profile fields
+const wechatSceneProfile = 1
+
+type wechatContentSafetyService struct {
+	wechatRepo   repository.WechatRepository
+	imageChecker ContentSafetyService
+}
+
+// NewWechatContentSafetyService checks text with WeChat msgSecCheck. WeChat only
+// checks media asynchronously, so images are delegated to imageChecker.
+func NewWechatContentSafetyService(wechatRepo repository.WechatRepository, imageChecker ContentSafetyService) ContentSafetyService {
+	return &wechatContentSafetyService{
+		wechatRepo:   wechatRepo,
+		imageChecker: imageChecker,
+	}
+}
+
+func (s *wechatContentSafetyService) ValidateImage(ctx context.Context, image io.Reader) (bool, error) {
+	return s.imageChecker.ValidateImage(ctx, image)
+}
+
+func (s *wechatContentSafetyService) ValidateText(ctx context.Context, openID string, text string) (bool, error) {
+	suggest, err := s.wechatRepo.MsgSecCheck(ctx, openID, wechatSceneProfile, text)
+	if err != nil {
+		return false, fmt.Errorf("wechat text check failed: %w", err)
+	}
+	// "review" means uncertain; profile text is public, so only a clear pass is accepted
+	return suggest == "pass", nil
+}
@@ -2,13 +2,87 @@ package service
 
 import (
 	"context"
+	"errors"
 	"io"
+	"io/fs"
+	"net"
+	"time"
+
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
 )
 
+var (
+	// ErrGenerationNotFound is returned when a request ID does not belong to the user
+	ErrGenerationNotFound = errors.New("generation not found")
+
+	// ErrGenerationFinished is returned when cancelling a job that has already completed or failed
+	ErrGenerationFinished = errors.New("generation has already finished")
+
+	// ErrGenerationCancelled is returned by ProcessJob when the user cancelled the job
+	ErrGenerationCancelled = errors.New("generation was cancelled")
+
+	// ErrQueueWaitExceeded is returned by ProcessJob when a job waited longer than its queue timeout
+	ErrQueueWaitExceeded = errors.New("generation waited too long in the queue")
+
+	// ErrJobAbandoned is the cause recorded for a job whose worker stopped renewing its lease
+	ErrJobAbandoned = errors.New("generation worker stopped responding")
+
+	// ErrLeaseLost is returned by ProcessJob when the job's lease expired and
+	// another worker claimed or settled it; the job is theirs now
+	ErrLeaseLost = errors.New("generation job was taken over by another worker")
+
+	// ErrPriceChanged is returned when the quoted price no longer applies by the
+	// time the user is charged, such as a first generation made twice at once
+	ErrPriceChanged = errors.New("the price has changed, please try again")
+)
+
+// IsRetryable reports whether a generation failure may go away if tried again:
+// timeouts, ComfyUI server errors and GPU out of memory, storage I/O errors, a
+// busy content safety provider and workers that stopped. Invalid or unsafe
+// images, ineligible users, templates without a ComfyUI workflow and anything
+// unrecognized are permanent.
+func IsRetryable(err error) bool {
+	var validationErr *ValidationError
+	var ineligibleErr *IneligibleError
+	if err == nil || errors.Is(err, ErrImageNotSafe) || errors.Is(err, ErrGenerationCancelled) || errors.As(err, &validationErr) || errors.As(err, &ineligibleErr) ||
+		errors.Is(err, fs.ErrNotExist) || errors.Is(err, context.Canceled) || errors.Is(err, repository.ErrComfyUINoWorkflow) {
+		return false
+	}
+
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, ErrJobAbandoned):
+		return true
+	case errors.Is(err, repository.ErrComfyUIUnavailable), errors.Is(err, repository.ErrComfyUIOutOfMemory):
+		return true
+	case errors.Is(err, repository.ErrWechatBusy), errors.Is(err, repository.ErrWechatRateLimited), errors.Is(err, repository.ErrWechatUnavailable):
+		return true
+	case errors.As(err, &pathErr):
+		return true
+	}
+	return false
+}
+
 // GenerationService defines the interface for image generation business logic
 type GenerationService interface {
-	// GenerateImage processes an image generation request
-	GenerateImage(ctx context.Context, userID int64, templateID int, imageData io.Reader) (*GenerationResult, error)
+	// Submit quotes the price, stores the selfie and queues a job with the requested
+	// variants. The selfie is not kept when the job cannot be queued.
+	Submit(ctx context.Context, userID int64, templateID int, image []byte, opts model.GenerationOptions) (*model.GenerationJob, error)
+	
+	// Regenerate queues a new job reusing the selfie of an earlier one, with new seeds
+	Regenerate(ctx context.Context, userID int64, requestID string, opts model.GenerationOptions) (*model.GenerationJob, error)
+	
+	// ProcessJob renders the variants of a claimed job. Retryable failures put the
+	// job back in the queue after a backoff; once attempts run out, or on a
+	// permanent failure, the credits of failed variants are refunded.
+	ProcessJob(ctx context.Context, job *Job) (*model.GenerationJob, error)
+	
+	// RecoverAbandonedJobs retries processing jobs whose worker stopped renewing
+	// their lease, or fails and refunds them once their attempts run out. It
+	// returns the jobs it failed, so their users can be told.
+	RecoverAbandonedJobs(ctx context.Context) ([]model.GenerationJob, error)
 	
 	// ValidateImage checks if an uploaded image is suitable for generation
 	ValidateImage(ctx context.Context, imageData io.Reader) error
@@ -16,21 +90,19 @@ type GenerationService interface {
 	// CheckContentSafety verifies image content is appropriate
 	CheckContentSafety(ctx context.Context, imageData io.Reader) error
 	
-	// GetGenerationStatus retrieves the status of an ongoing generation
-	GetGenerationStatus(ctx context.Context, requestID string) (*GenerationStatus, error)
-}
-
-// GenerationResult represents the result of an image generation
-type GenerationResult struct {
-	RequestID string   `json:"request_id"`
-	Images    []string `json:"images"`
-	Credits   int      `json:"credits_used"`
+	// Cancel stops a queued or processing job and refunds its credits. Cancelling
+	// a cancelled job again returns it unchanged.
+	Cancel(ctx context.Context, userID int64, requestID string) (*model.GenerationJob, error)
+	
+	// GetGenerationStatus retrieves one of the user's generation jobs, with its place in line while queued
+	GetGenerationStatus(ctx context.Context, userID int64, requestID string) (*model.GenerationJob, error)
+	
+	// ReportBackends publishes this worker's view of the ComfyUI nodes for ListBackends
+	ReportBackends(ctx context.Context, worker string) error
+	
+	// ListBackends returns the state of the ComfyUI nodes as recently reported by each worker
+	ListBackends(ctx context.Context) ([]model.ComfyUINodeStatus, error)
+	
+	// GetLatencyStats reports latency percentiles per template for jobs completed within window
+	GetLatencyStats(ctx context.Context, window time.Duration) (*model.GenerationLatencyReport, error)
 }
-
-// GenerationStatus represents the status of a generation request
-type GenerationStatus struct {
-	RequestID string `json:"request_id"`
-	Status    string `json:"status"` // "processing", "completed", "failed"
-	Progress  int    `json:"progress,omitempty"`
-	Error     string `json:"error,omitempty"`
-} 
\ No newline at end of file
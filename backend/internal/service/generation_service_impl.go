@@ -1,96 +1,657 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
-	"database/sql"
+	"github.com/45ai/backend/pkg/blobstore"
 )
 
+// maxSeed keeps seeds exact when clients parse them as JavaScript numbers
+const maxSeed = 1 << 53
+
+// ErrImageNotSafe is returned when an uploaded image fails content moderation
+var ErrImageNotSafe = errors.New("image content is not safe")
+
 type generationServiceImpl struct {
+	cfg                  config.QueueConfig
+	timeouts             config.GenerationTimeoutConfig
+	comfyUICfg           config.ComfyUIConfig
 	contentSafetyService ContentSafetyService
+	templateService      TemplateService
+	queueService         QueueService
 	userRepo             repository.UserRepository
 	transactionRepo      repository.TransactionRepository
 	templateRepo         repository.TemplateRepository
+	jobRepo              repository.GenerationJobRepository
 	comfyuiRepo          repository.ComfyUIRepository
+	nodeReportRepo       repository.ComfyUINodeReportRepository
+	statsRepo            repository.TemplateStatsRepository
+	blobStore            blobstore.Store
 }
 
 func NewGenerationService(
+	cfg config.QueueConfig,
+	timeouts config.GenerationTimeoutConfig,
+	comfyUICfg config.ComfyUIConfig,
 	contentSafetyService ContentSafetyService,
+	templateService TemplateService,
+	queueService QueueService,
 	userRepo repository.UserRepository,
 	transactionRepo repository.TransactionRepository,
 	templateRepo repository.TemplateRepository,
+	jobRepo repository.GenerationJobRepository,
 	comfyuiRepo repository.ComfyUIRepository,
+	nodeReportRepo repository.ComfyUINodeReportRepository,
+	statsRepo repository.TemplateStatsRepository,
+	blobStore blobstore.Store,
 ) GenerationService {
 	return &generationServiceImpl{
+		cfg:                  cfg,
+		timeouts:             timeouts,
+		comfyUICfg:           comfyUICfg,
 		contentSafetyService: contentSafetyService,
+		templateService:      templateService,
+		queueService:         queueService,
 		userRepo:             userRepo,
 		transactionRepo:      transactionRepo,
 		templateRepo:         templateRepo,
+		jobRepo:              jobRepo,
 		comfyuiRepo:          comfyuiRepo,
+		nodeReportRepo:       nodeReportRepo,
+		statsRepo:            statsRepo,
+		blobStore:            blobStore,
+	}
+}
+
+func (s *generationServiceImpl) Submit(ctx context.Context, userID int64, templateID int, image []byte, opts model.GenerationOptions) (*model.GenerationJob, error) {
+	var timings model.GenerationTimings
+	var err error
+
+	// 1. Check the user can generate before anything is stored for them
+	seeds, eligibility, err := s.checkEligibility(ctx, userID, templateID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Validate the user's uploaded image
+	timings.ValidationMS, err = timeStage(ctx, s.timeouts.Validation, func(ctx context.Context) error {
+		return s.ValidateImage(ctx, bytes.NewReader(image))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Check content safety
+	timings.SafetyMS, err = timeStage(ctx, s.timeouts.Safety, func(ctx context.Context) error {
+		return s.CheckContentSafety(ctx, bytes.NewReader(image))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Keep the selfie so the job can be regenerated without another upload.
+	// It lives under the user's prefix and is purged with the account.
+	requestID, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(image)
+	sourceKey := fmt.Sprintf("%sgenerations/%s/source%s", blobstore.UserPrefix(userID), requestID, previewImageTypes[contentType])
+	timings.UploadMS, err = timeStage(ctx, s.timeouts.Upload, func(ctx context.Context) error {
+		_, err := s.blobStore.Put(ctx, sourceKey, bytes.NewReader(image), contentType)
+		return err
+	})
+	if err != nil {
+		// A timed out upload may still have been written
+		s.deleteSource(sourceKey)
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	// 5. Charge and queue the job. Without a job nothing refers to the selfie.
+	if err := s.enqueue(ctx, userID, templateID, requestID, sourceKey, nil, seeds, eligibility, timings); err != nil {
+		s.deleteSource(sourceKey)
+		return nil, err
 	}
+	return s.GetGenerationStatus(ctx, userID, requestID)
 }
 
-func (s *generationServiceImpl) GenerateImage(ctx context.Context, userID int64, templateID int, imageData io.Reader) (*GenerationResult, error) {
-	// 1. Validate the user's uploaded image
-	if err := s.ValidateImage(ctx, imageData); err != nil {
+func (s *generationServiceImpl) Regenerate(ctx context.Context, userID int64, requestID string, opts model.GenerationOptions) (*model.GenerationJob, error) {
+	parent, err := s.getJob(ctx, userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	seeds, eligibility, err := s.checkEligibility(ctx, userID, parent.TemplateID, opts)
+	if err != nil {
 		return nil, err
 	}
 
-	// 2. Check content safety
-	if err := s.CheckContentSafety(ctx, imageData); err != nil {
+	newRequestID, err := newRandomID()
+	if err != nil {
 		return nil, err
 	}
+	// The selfie still belongs to the parent job, so it is kept if this fails
+	if err := s.enqueue(ctx, userID, parent.TemplateID, newRequestID, parent.SourceKey, &parent.RequestID, seeds, eligibility, model.GenerationTimings{}); err != nil {
+		return nil, err
+	}
+	return s.GetGenerationStatus(ctx, userID, newRequestID)
+}
+
+// checkEligibility picks the seeds of a request and quotes it, returning an
+// *IneligibleError if the user cannot generate with the template
+func (s *generationServiceImpl) checkEligibility(ctx context.Context, userID int64, templateID int, opts model.GenerationOptions) ([]int64, *model.TemplateEligibility, error) {
+	seeds, err := generationSeeds(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Clients may hold on to a template after it was hidden or its availability window closed
+	eligibility, err := s.templateService.GetEligibility(ctx, userID, templateID, len(seeds))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !eligibility.Eligible {
+		return nil, nil, &IneligibleError{Eligibility: eligibility}
+	}
+	return seeds, eligibility, nil
+}
+
+// deleteSource removes a selfie that no job refers to. It runs even when the
+// request was cancelled, since the object would otherwise never be purged.
+func (s *generationServiceImpl) deleteSource(sourceKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeouts.Upload)
+	defer cancel()
+	if err := s.blobStore.Delete(ctx, sourceKey); err != nil {
+		log.Printf("Failed to delete image %s: %v", sourceKey, err)
+	}
+}
 
-	// 3. Check user credits
+// enqueue charges a quoted job and records it as queued, which puts it in line
+// for the workers. timings holds the stages the request already went through.
+func (s *generationServiceImpl) enqueue(ctx context.Context, userID int64, templateID int, requestID, sourceKey string, parentRequestID *string,
+	seeds []int64, eligibility *model.TemplateEligibility, timings model.GenerationTimings) error {
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	template, err := s.templateRepo.GetByIDWithDeleted(ctx, templateID)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+
+	job := &model.GenerationJob{
+		RequestID:         requestID,
+		UserID:            userID,
+		TemplateID:        templateID,
+		ParentRequestID:   parentRequestID,
+		SourceKey:         sourceKey,
+		Status:            model.GenerationStatusQueued,
+		Lane:              generationLane(user, eligibility.TotalCreditCost),
+		Variants:          make([]model.GenerationVariant, len(seeds)),
+		CreditsPerVariant: eligibility.CreditCost,
+		CreditsCharged:    eligibility.TotalCreditCost,
+		Timings:           timings,
+	}
+	// A partial failure always leaves the first variant, so only variants at
+	// the extra price are refunded
+	if len(seeds) > 1 {
+		job.CreditsPerVariant = eligibility.ExtraVariantCreditCost
+	}
+	for i, seed := range seeds {
+		job.Variants[i].Seed = seed
+	}
+	if eligibility.PricingRule != nil {
+		job.PricingRuleID = &eligibility.PricingRule.ID
+	}
+
+	// Every variant is paid for now, so queued jobs can never add up to more than
+	// the balance. Free generations are recorded too, so first-generation pricing
+	// only applies once.
+	description := fmt.Sprintf("Used '%s' template", template.Name)
+	if len(seeds) > 1 {
+		description = fmt.Sprintf("Used '%s' template, %d variants", template.Name, len(seeds))
+	}
+	charge := &model.Transaction{
+		UserID:            userID,
+		Type:              model.TransactionTypeGeneration,
+		Amount:            -job.CreditsCharged,
+		Description:       description,
+		RelatedTemplateID: &template.ID,
+		PricingRuleID:     job.PricingRuleID,
+	}
+	// The charge and the job are written together, so a job is never charged
+	// without being queued
+	err = s.jobRepo.CreateWithCharge(ctx, job, &repository.GenerationCharge{
+		Transaction:     charge,
+		FirstGeneration: eligibility.PricingRule != nil && eligibility.PricingRule.Audience == model.PricingAudienceFirstGeneration,
+		DailyLimit:      eligibility.DailyLimit,
+	})
+	if err != nil {
+		// Another request got in first since eligibility was checked
+		switch {
+		case errors.Is(err, repository.ErrNotFirstGeneration):
+			return ErrPriceChanged
+		case errors.Is(err, repository.ErrInsufficientCredits):
+			eligibility.Reasons = append(eligibility.Reasons, model.EligibilityReason{
+				Code:    model.EligibilityInsufficientCredits,
+				Message: fmt.Sprintf("%d credits are needed", eligibility.TotalCreditCost),
+			})
+		case errors.Is(err, repository.ErrDailyLimitReached):
+			eligibility.Reasons = append(eligibility.Reasons, model.EligibilityReason{
+				Code:    model.EligibilityDailyLimitReached,
+				Message: fmt.Sprintf("At most %d generations are allowed per day", eligibility.DailyLimit),
+			})
+		default:
+			return fmt.Errorf("failed to create generation job: %w", err)
+		}
+		eligibility.Eligible = false
+		return &IneligibleError{Eligibility: eligibility}
+	}
+	return nil
+}
+
+func (s *generationServiceImpl) ProcessJob(ctx context.Context, queued *Job) (*model.GenerationJob, error) {
+	job, err := s.jobRepo.GetByRequestID(ctx, queued.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation job: %w", err)
+	}
+	if job.Status != model.GenerationStatusProcessing {
+		return job, ErrGenerationCancelled
+	}
+	if job.Attempts != queued.Attempt {
+		return job, ErrLeaseLost
+	}
+	clock := newAttemptClock(job)
+
+	// Keep the job from being recovered as abandoned while this worker has it
+	leaseCtx, stopLease := context.WithCancel(ctx)
+	defer stopLease()
+	go s.renewLease(leaseCtx, job)
+
+	// A job that waited too long for a worker is no use to the user any more
+	queuedAt := job.CreatedAt
+	if job.NextAttemptAt != nil {
+		queuedAt = *job.NextAttemptAt
+	}
+	if job.StartedAt != nil && job.StartedAt.After(queuedAt) {
+		wait := job.StartedAt.Sub(queuedAt)
+		job.Timings.QueueWaitMS += wait.Milliseconds()
+		if wait > s.timeouts.QueueWait {
+			return job, s.fail(ctx, job, ErrQueueWaitExceeded, clock.Now())
+		}
+	}
+
+	// Rendering stops as soon as the user cancels; job updates below use ctx so
+	// they still go through
+	renderCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go s.watchCancellation(renderCtx, job.RequestID, stop)
+
+	// 1. Load the selfie; the job was paid for and checked when it was queued
+	image, err := s.loadSource(renderCtx, job)
+	if err != nil {
+		return job, s.retryOrFail(ctx, job, err, clock.Now())
+	}
+
+	// 2. Render each variant that has no images yet; earlier attempts are kept.
+	// A retryable error wins over a permanent one so the job is tried again.
+	var renderErr error
+	renderStarted := time.Now()
+	for i := range job.Variants {
+		variant := &job.Variants[i]
+		if len(variant.Images) > 0 {
+			continue
+		}
+		if renderCtx.Err() != nil {
+			break
+		}
+		variantCtx, cancel := context.WithTimeout(renderCtx, s.timeouts.Render)
+		images, err := s.comfyuiRepo.GenerateImage(variantCtx, job.TemplateID, bytes.NewReader(image), variant.Seed)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to render variant %d of %s (attempt %d): %v", i, job.RequestID, job.Attempts, err)
+			variant.Error = "generation failed"
+			if renderErr == nil || IsRetryable(err) {
+				renderErr = err
+			}
+			continue
+		}
+		variant.Images = images
+		variant.Error = ""
+	}
+	job.Timings.RenderMS += time.Since(renderStarted).Milliseconds()
+	succeeded := 0
+	for _, variant := range job.Variants {
+		if len(variant.Images) > 0 {
+			succeeded++
+		}
+	}
+	if renderCtx.Err() != nil && ctx.Err() == nil {
+		renderErr = ErrGenerationCancelled
+	}
+	if renderErr != nil && (succeeded == 0 || s.canRetry(job, renderErr)) {
+		return job, s.retryOrFail(ctx, job, renderErr, clock.Now())
+	}
+
+	// 3. Complete the job unless the user cancelled it meanwhile, in which case
+	// the cancellation already refunded everything
+	now := clock.Now()
+	job.Status = model.GenerationStatusCompleted
+	job.NextAttemptAt = nil
+	job.CompletedAt = &now
+	job.Timings.TotalMS = totalTime(job, now)
+	completed, err := s.jobRepo.TransitionAttempt(ctx, job, job.Attempts, model.GenerationStatusProcessing)
+	if err != nil {
+		return job, fmt.Errorf("failed to update generation job: %w", err)
+	}
+	if !completed {
+		return job, s.notSettled(ctx, job)
+	}
+
+	// 4. Give back the credits of variants that failed for good
+	if failed := len(job.Variants) - succeeded; failed > 0 {
+		description := fmt.Sprintf("Refund for %d failed variants", failed)
+		s.refundAndSave(ctx, job, failed*job.CreditsPerVariant, description)
+	}
+
+	// 5. Count the use for popularity; the user already has their images
+	if err := s.statsRepo.RecordUse(ctx, job.TemplateID); err != nil {
+		log.Printf("Failed to record use of template %d: %v", job.TemplateID, err)
+	}
+	return job, nil
+}
+
+func (s *generationServiceImpl) RecoverAbandonedJobs(ctx context.Context) ([]model.GenerationJob, error) {
+	jobs, err := s.jobRepo.ListExpired(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list abandoned generation jobs: %w", err)
+	}
+
+	var failed []model.GenerationJob
+	for i := range jobs {
+		job := &jobs[i]
+		// Jobs that were settled or claimed again meanwhile are left alone. The
+		// worker that started the job is gone, so only this host's clock is left.
+		err := s.retryOrFail(ctx, job, ErrJobAbandoned, time.Now())
+		if errors.Is(err, ErrGenerationCancelled) || errors.Is(err, ErrLeaseLost) {
+			continue
+		}
+		switch job.Status {
+		case model.GenerationStatusQueued:
+			log.Printf("Requeued abandoned generation %s (attempt %d)", job.RequestID, job.Attempts)
+		case model.GenerationStatusFailed:
+			log.Printf("Failed abandoned generation %s after %d attempts", job.RequestID, job.Attempts)
+			failed = append(failed, *job)
+		}
+	}
+	return failed, nil
+}
+
+// renewLease extends the lease of a job this worker processes until ctx is done
+func (s *generationServiceImpl) renewLease(ctx context.Context, job *model.GenerationJob) {
+	ticker := time.NewTicker(s.cfg.JobLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.jobRepo.RenewLease(ctx, job.ID, s.cfg.JobLease); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to renew the lease of generation %s: %v", job.RequestID, err)
+			}
+		}
+	}
+}
+
+// canRetry reports whether a job that failed with cause has attempts left
+func (s *generationServiceImpl) canRetry(job *model.GenerationJob, cause error) bool {
+	return IsRetryable(cause) && job.Attempts < s.cfg.MaxAttempts
+}
+
+// retryOrFail puts a job back in the queue after a backoff from now if cause is
+// worth retrying, and fails it otherwise. Either only applies while the job is
+// on the attempt in job.Attempts. It returns cause, or what notSettled returns.
+func (s *generationServiceImpl) retryOrFail(ctx context.Context, job *model.GenerationJob, cause error, now time.Time) error {
+	if errors.Is(cause, ErrGenerationCancelled) {
+		job.Status = model.GenerationStatusCancelled
+		return cause
+	}
+	if !s.canRetry(job, cause) {
+		return s.fail(ctx, job, cause, now)
+	}
+
+	next := now.Add(retryDelay(s.cfg, job.Attempts))
+	job.Status = model.GenerationStatusQueued
+	job.NextAttemptAt = &next
+	requeued, err := s.jobRepo.TransitionAttempt(ctx, job, job.Attempts, model.GenerationStatusProcessing)
+	if err != nil {
+		log.Printf("Failed to requeue generation %s: %v", job.RequestID, err)
+	} else if !requeued {
+		return s.notSettled(ctx, job)
+	}
+	return cause
+}
+
+// fail marks a job on the attempt in job.Attempts as failed at now, refunds what
+// is left of its charge and returns cause, or what notSettled returns
+func (s *generationServiceImpl) fail(ctx context.Context, job *model.GenerationJob, cause error, now time.Time) error {
+	job.Status = model.GenerationStatusFailed
+	job.Error = "generation failed"
+	job.NextAttemptAt = nil
+	job.CompletedAt = &now
+	job.Timings.TotalMS = totalTime(job, now)
+	failed, err := s.jobRepo.TransitionAttempt(ctx, job, job.Attempts, model.GenerationStatusQueued, model.GenerationStatusProcessing)
+	if err != nil {
+		log.Printf("Failed to mark generation %s as failed: %v", job.RequestID, err)
+		return cause
+	}
+	if !failed {
+		return s.notSettled(ctx, job)
+	}
+
+	s.refundAndSave(ctx, job, job.CreditsCharged, "Refund for failed generation")
+	return cause
+}
+
+// notSettled explains why a job could not be moved on from the attempt in
+// job.Attempts: ErrGenerationCancelled if the user cancelled it, ErrLeaseLost if
+// another worker claimed or settled it after the lease expired
+func (s *generationServiceImpl) notSettled(ctx context.Context, job *model.GenerationJob) error {
+	current, err := s.jobRepo.GetByRequestID(ctx, job.RequestID)
+	if err == nil && current.Status != model.GenerationStatusCancelled {
+		return ErrLeaseLost
 	}
-	template, err := s.templateRepo.GetByID(ctx, templateID)
+	job.Status = model.GenerationStatusCancelled
+	return ErrGenerationCancelled
+}
+
+func (s *generationServiceImpl) Cancel(ctx context.Context, userID int64, requestID string) (*model.GenerationJob, error) {
+	job, err := s.getJob(ctx, userID, requestID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template: %w", err)
+		return nil, err
+	}
+	if job.Status == model.GenerationStatusCancelled {
+		return job, nil
 	}
-	if user.Credits < template.CreditCost {
-		return nil, fmt.Errorf("insufficient credits")
+	if job.IsFinished() {
+		return nil, ErrGenerationFinished
 	}
 
-	// 4. Generate image
-	imageURLs, err := s.comfyuiRepo.GenerateImage(ctx, templateID, imageData)
+	// Only one of the user and the worker can move the job out of queued or
+	// processing; whoever does settles the credits. A worker rendering the job
+	// notices within CancelPollInterval and stops.
+	now := time.Now()
+	job.Status = model.GenerationStatusCancelled
+	job.NextAttemptAt = nil
+	job.CompletedAt = &now
+	cancelled, err := s.jobRepo.Transition(ctx, job, model.GenerationStatusQueued, model.GenerationStatusProcessing)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate image: %w", err)
+		return nil, fmt.Errorf("failed to cancel generation job: %w", err)
+	}
+	if !cancelled {
+		return nil, ErrGenerationFinished
 	}
 
-	// 5. Deduct credits and create transaction
-	if err := s.userRepo.UpdateCredits(ctx, userID, -template.CreditCost); err != nil {
-		return nil, fmt.Errorf("failed to deduct credits: %w", err)
+	s.refundAndSave(ctx, job, job.CreditsCharged, "Refund for cancelled generation")
+	return job, nil
+}
+
+// watchCancellation stops a job's render through stop once the user cancels
+// the job; the ComfyUI pool then interrupts the node. It returns when ctx is done.
+func (s *generationServiceImpl) watchCancellation(ctx context.Context, requestID string, stop context.CancelFunc) {
+	ticker := time.NewTicker(s.cfg.CancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, err := s.jobRepo.GetByRequestID(ctx, requestID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to check generation %s for cancellation: %v", requestID, err)
+				}
+				continue
+			}
+			if job.Status != model.GenerationStatusCancelled {
+				continue
+			}
+			stop()
+			return
+		}
+	}
+}
+
+// refundAndSave refunds credits of a job and stores its reduced charge. Errors
+// are logged, since the job has already reached its final state.
+func (s *generationServiceImpl) refundAndSave(ctx context.Context, job *model.GenerationJob, amount int, description string) {
+	if amount <= 0 {
+		return
+	}
+	if err := s.refund(ctx, job, amount, description); err != nil {
+		log.Printf("Failed to refund generation %s: %v", job.RequestID, err)
+		return
+	}
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		log.Printf("Failed to record refund of generation %s: %v", job.RequestID, err)
+	}
+}
+
+// refund gives credits of a job back to its user
+func (s *generationServiceImpl) refund(ctx context.Context, job *model.GenerationJob, amount int, description string) error {
+	if amount <= 0 {
+		return nil
 	}
 	transaction := &model.Transaction{
-		UserID:           userID,
-		Type:             "generation",
-		Amount:           -template.CreditCost,
-		Description:      fmt.Sprintf("Used '%s' template", template.Name),
-		RelatedTemplateID: sql.NullInt64{Int64: int64(template.ID), Valid: true},
+		UserID:            job.UserID,
+		Type:              model.TransactionTypeRefund,
+		Amount:            amount,
+		Description:       description,
+		RelatedTemplateID: &job.TemplateID,
+		PricingRuleID:     job.PricingRuleID,
+	}
+	if err := s.transactionRepo.CreateWithCredits(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to refund credits: %w", err)
 	}
-	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
-		// This is a critical error, as the user has been charged but the transaction was not recorded.
-		// In a real application, this should be handled with more care (e.g., a retry mechanism or a refund).
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	job.CreditsCharged -= amount
+	return nil
+}
+
+// loadSource reads the selfie a job was submitted with
+func (s *generationServiceImpl) loadSource(ctx context.Context, job *model.GenerationJob) ([]byte, error) {
+	source, err := s.blobStore.Get(ctx, job.SourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer source.Close()
+	image, err := io.ReadAll(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
 	}
+	return image, nil
+}
 
-	return &GenerationResult{
-		Images:      imageURLs,
-		CreditsUsed: template.CreditCost,
-	}, nil
+// timeStage runs a stage of a request under its timeout and returns how long it
+// took in milliseconds
+func timeStage(ctx context.Context, timeout time.Duration, stage func(ctx context.Context) error) (int64, error) {
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	started := time.Now()
+	err := stage(stageCtx)
+	return time.Since(started).Milliseconds(), err
+}
+
+// attemptClock tells the time on the database clock that stamps created_at and
+// started_at, by adding the time elapsed on this host since the job was started.
+// Timings then never compare this host's clock with the database's. The claim
+// and reload before the clock starts make it run a few milliseconds behind.
+type attemptClock struct {
+	startedAt time.Time
+	local     time.Time
+}
+
+func newAttemptClock(job *model.GenerationJob) attemptClock {
+	clock := attemptClock{local: time.Now()}
+	if job.StartedAt != nil {
+		clock.startedAt = *job.StartedAt
+	} else {
+		clock.startedAt = clock.local
+	}
+	return clock
+}
+
+func (c attemptClock) Now() time.Time {
+	return c.startedAt.Add(time.Since(c.local))
+}
+
+// totalTime is the end-to-end time in milliseconds of a job finishing at
+// finishedAt, counting the stages before it was queued
+func totalTime(job *model.GenerationJob, finishedAt time.Time) int64 {
+	timings := job.Timings
+	return timings.ValidationMS + timings.SafetyMS + timings.UploadMS + finishedAt.Sub(job.CreatedAt).Milliseconds()
+}
+
+// retryDelay is the backoff before the next attempt: exponential in the
+// attempts made so far, capped, with random jitter of up to half the delay so
+// jobs that failed together are not retried together
+func retryDelay(cfg config.QueueConfig, attempts int) time.Duration {
+	delay := cfg.RetryBaseDelay
+	for i := 1; i < attempts && delay < cfg.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > cfg.RetryMaxDelay {
+		delay = cfg.RetryMaxDelay
+	}
+	return delay - time.Duration(mathrand.Int63n(int64(delay)/2+1))
 }
 
 func (s *generationServiceImpl) ValidateImage(ctx context.Context, imageData io.Reader) error {
-	// For now, we'll just do a basic check for nil.
 	if imageData == nil {
 		return fmt.Errorf("image data is required")
 	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(imageData, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return &ValidationError{Field: "image", Message: "must not be empty"}
+	}
+	if _, ok := previewImageTypes[http.DetectContentType(head[:n])]; !ok {
+		return &ValidationError{Field: "image", Message: "must be a JPEG, PNG or WebP image"}
+	}
 	return nil
 }
 
@@ -100,12 +661,123 @@ func (s *generationServiceImpl) CheckContentSafety(ctx context.Context, imageDat
 		return fmt.Errorf("content safety check failed: %w", err)
 	}
 	if !safe {
-		return fmt.Errorf("image content is not safe")
+		return ErrImageNotSafe
+	}
+	return nil
+}
+
+func (s *generationServiceImpl) GetGenerationStatus(ctx context.Context, userID int64, requestID string) (*model.GenerationJob, error) {
+	job, err := s.getJob(ctx, userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == model.GenerationStatusQueued {
+		if job.QueuePosition, err = s.queueService.Position(ctx, job.RequestID); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+func (s *generationServiceImpl) GetLatencyStats(ctx context.Context, window time.Duration) (*model.GenerationLatencyReport, error) {
+	since := time.Now().Add(-window)
+	stats, err := s.jobRepo.LatencyStats(ctx, since, s.timeouts.SLATarget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latency stats: %w", err)
+	}
+	return &model.GenerationLatencyReport{
+		Since:       since,
+		SLATargetMS: s.timeouts.SLATarget.Milliseconds(),
+		Templates:   stats,
+	}, nil
+}
+
+// backendReportMaxAge is how many report intervals a worker may miss before
+// its view of the nodes is dropped, e.g. because it stopped
+const backendReportMaxAge = 3
+
+func (s *generationServiceImpl) ReportBackends(ctx context.Context, worker string) error {
+	if err := s.nodeReportRepo.Report(ctx, worker, s.comfyuiRepo.Nodes()); err != nil {
+		return fmt.Errorf("failed to report comfyui nodes: %w", err)
+	}
+	if _, err := s.nodeReportRepo.PurgeStale(ctx, backendReportMaxAge*s.comfyUICfg.ReportInterval); err != nil {
+		return fmt.Errorf("failed to purge stale comfyui node reports: %w", err)
 	}
 	return nil
 }
 
-func (s *generationServiceImpl) GetGenerationStatus(ctx context.Context, requestID string) (*GenerationStatus, error) {
-	// To be implemented in a future task
-	return nil, nil
-} 
\ No newline at end of file
+// The API process never renders, so its own pool knows nothing about the
+// renders, failures and ejections of the workers
+func (s *generationServiceImpl) ListBackends(ctx context.Context) ([]model.ComfyUINodeStatus, error) {
+	nodes, err := s.nodeReportRepo.List(ctx, backendReportMaxAge*s.comfyUICfg.ReportInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comfyui node reports: %w", err)
+	}
+	if nodes == nil {
+		nodes = []model.ComfyUINodeStatus{}
+	}
+	return nodes, nil
+}
+
+// getJob retrieves a job if it belongs to the user
+func (s *generationServiceImpl) getJob(ctx context.Context, userID int64, requestID string) (*model.GenerationJob, error) {
+	job, err := s.jobRepo.GetByRequestID(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGenerationNotFound
+		}
+		return nil, fmt.Errorf("failed to get generation job: %w", err)
+	}
+	// Other users' jobs look the same as missing ones
+	if job.UserID != userID {
+		return nil, ErrGenerationNotFound
+	}
+	return job, nil
+}
+
+// generationLane picks the lane of a new job: members first, then paid
+// generations, then free ones
+func generationLane(user *model.User, credits int) model.GenerationLane {
+	switch {
+	case user.IsMember(time.Now()):
+		return model.GenerationLanePriority
+	case credits > 0:
+		return model.GenerationLaneStandard
+	default:
+		return model.GenerationLaneTrial
+	}
+}
+
+// generationSeeds validates the requested variants and fills in random seeds
+func generationSeeds(opts model.GenerationOptions) ([]int64, error) {
+	variants := opts.Variants
+	if variants == 0 {
+		variants = len(opts.Seeds)
+	}
+	if variants == 0 {
+		variants = 1
+	}
+	if variants < 1 || variants > model.MaxGenerationVariants {
+		return nil, &ValidationError{Field: "variants", Message: fmt.Sprintf("must be 1 to %d", model.MaxGenerationVariants)}
+	}
+	if len(opts.Seeds) > variants {
+		return nil, &ValidationError{Field: "seeds", Message: "must not list more seeds than variants"}
+	}
+
+	seeds := make([]int64, variants)
+	for i := range seeds {
+		if i < len(opts.Seeds) {
+			if opts.Seeds[i] < 0 || opts.Seeds[i] >= maxSeed {
+				return nil, &ValidationError{Field: "seeds", Message: fmt.Sprintf("must be between 0 and %d", int64(maxSeed-1))}
+			}
+			seeds[i] = opts.Seeds[i]
+			continue
+		}
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		seeds[i] = int64(binary.BigEndian.Uint64(b[:]) % maxSeed)
+	}
+	return seeds, nil
+}
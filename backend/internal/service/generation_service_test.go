@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/blobstore"
+)
+
+// The fakes embed the interfaces they stand in for, so calling anything a test
+// does not expect panics
+
+type fakeContentSafety struct {
+	ContentSafetyService
+}
+
+func (f *fakeContentSafety) ValidateImage(ctx context.Context, image io.Reader) (bool, error) {
+	return true, nil
+}
+
+type fakeEligibility struct {
+	TemplateService
+	eligible bool
+}
+
+func (f *fakeEligibility) GetEligibility(ctx context.Context, userID int64, templateID int, variants int) (*model.TemplateEligibility, error) {
+	eligibility := &model.TemplateEligibility{
+		TemplateID:             templateID,
+		Eligible:               f.eligible,
+		CreditCost:             10,
+		ExtraVariantCreditCost: 10,
+		Variants:               variants,
+		TotalCreditCost:        10 * variants,
+		Reasons:                []model.EligibilityReason{},
+	}
+	if !f.eligible {
+		eligibility.Reasons = append(eligibility.Reasons, model.EligibilityReason{Code: model.EligibilityInsufficientCredits})
+	}
+	return eligibility, nil
+}
+
+type fakeQueue struct {
+	QueueService
+}
+
+func (f *fakeQueue) Position(ctx context.Context, requestID string) (int, error) {
+	return 1, nil
+}
+
+type fakeUsers struct {
+	repository.UserRepository
+	err error
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.User{ID: id, Credits: 100}, nil
+}
+
+type fakeTemplates struct {
+	repository.TemplateRepository
+}
+
+func (f *fakeTemplates) GetByIDWithDeleted(ctx context.Context, id int) (*model.Template, error) {
+	return &model.Template{ID: id, Name: "Portrait", CreditCost: 10, IsActive: true}, nil
+}
+
+type fakeJobs struct {
+	repository.GenerationJobRepository
+	createErr error
+	jobs      map[string]*model.GenerationJob
+}
+
+func (f *fakeJobs) CreateWithCharge(ctx context.Context, job *model.GenerationJob, charge *repository.GenerationCharge) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.jobs[job.RequestID] = job
+	return nil
+}
+
+func (f *fakeJobs) GetByRequestID(ctx context.Context, requestID string) (*model.GenerationJob, error) {
+	job, ok := f.jobs[requestID]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+// storedFiles lists the objects written to a local blob store
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestSubmitStoresSelfieOnlyForQueuedJobs(t *testing.T) {
+	// The PNG signature is all ValidateImage looks at
+	image := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name      string
+		eligible  bool
+		userErr   error
+		createErr error
+		wantErr   func(error) bool
+		stored    int
+	}{
+		{
+			name:     "queued",
+			eligible: true,
+			wantErr:  func(err error) bool { return err == nil },
+			stored:   1,
+		},
+		{
+			name:    "ineligible",
+			wantErr: func(err error) bool { var e *IneligibleError; return errors.As(err, &e) },
+		},
+		{
+			name:      "credits spent meanwhile",
+			eligible:  true,
+			createErr: repository.ErrInsufficientCredits,
+			wantErr:   func(err error) bool { var e *IneligibleError; return errors.As(err, &e) },
+		},
+		{
+			name:      "daily limit reached meanwhile",
+			eligible:  true,
+			createErr: repository.ErrDailyLimitReached,
+			wantErr:   func(err error) bool { var e *IneligibleError; return errors.As(err, &e) },
+		},
+		{
+			name:      "first generation used meanwhile",
+			eligible:  true,
+			createErr: repository.ErrNotFirstGeneration,
+			wantErr:   func(err error) bool { return errors.Is(err, ErrPriceChanged) },
+		},
+		{
+			name:      "job not created",
+			eligible:  true,
+			createErr: errors.New("connection refused"),
+			wantErr:   func(err error) bool { return err != nil },
+		},
+		{
+			name:     "user lookup fails",
+			eligible: true,
+			userErr:  errors.New("connection refused"),
+			wantErr:  func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := blobstore.NewLocalStore(blobstore.Config{LocalDir: dir, PublicURL: "http://localhost/uploads"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeouts := config.GenerationTimeoutConfig{Validation: time.Second, Safety: time.Second, Upload: time.Second}
+			svc := NewGenerationService(config.QueueConfig{}, timeouts, config.ComfyUIConfig{}, &fakeContentSafety{},
+				&fakeEligibility{eligible: tt.eligible}, &fakeQueue{}, &fakeUsers{err: tt.userErr}, nil, &fakeTemplates{},
+				&fakeJobs{createErr: tt.createErr, jobs: map[string]*model.GenerationJob{}}, nil, nil, nil, store)
+
+			job, err := svc.Submit(context.Background(), 7, 3, image, model.GenerationOptions{Variants: 2})
+			if !tt.wantErr(err) {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if err == nil && job.QueuePosition != 1 {
+				t.Errorf("QueuePosition = %d, want 1", job.QueuePosition)
+			}
+			if files := storedFiles(t, dir); len(files) != tt.stored {
+				t.Errorf("stored files = %v, want %d", files, tt.stored)
+			}
+		})
+	}
+}
+
+// reclaimedJobs holds a job that was claimed for attempt 2 after a worker on
+// attempt 1 lost its lease
+type reclaimedJobs struct {
+	repository.GenerationJobRepository
+	current     model.GenerationJob
+	transitions int
+}
+
+func (f *reclaimedJobs) ListExpired(ctx context.Context) ([]model.GenerationJob, error) {
+	stale := f.current
+	stale.Attempts = 1
+	return []model.GenerationJob{stale}, nil
+}
+
+func (f *reclaimedJobs) TransitionAttempt(ctx context.Context, job *model.GenerationJob, attempt int, from ...model.GenerationJobStatus) (bool, error) {
+	if attempt != f.current.Attempts {
+		return false, nil
+	}
+	f.transitions++
+	return true, nil
+}
+
+func (f *reclaimedJobs) GetByRequestID(ctx context.Context, requestID string) (*model.GenerationJob, error) {
+	job := f.current
+	return &job, nil
+}
+
+func TestRecoverSkipsReclaimedJobs(t *testing.T) {
+	for _, maxAttempts := range []int{1, 3} {
+		t.Run(fmt.Sprintf("max attempts %d", maxAttempts), func(t *testing.T) {
+			repo := &reclaimedJobs{current: model.GenerationJob{ID: 1, RequestID: "r1", UserID: 7, Status: model.GenerationStatusProcessing,
+				Attempts: 2, CreditsCharged: 10}}
+			// A refund would need the transaction repository, which is nil
+			svc := NewGenerationService(config.QueueConfig{MaxAttempts: maxAttempts}, config.GenerationTimeoutConfig{}, config.ComfyUIConfig{},
+				nil, nil, nil, nil, nil, nil, repo, nil, nil, nil, nil)
+
+			failed, err := svc.RecoverAbandonedJobs(context.Background())
+			if err != nil {
+				t.Fatalf("RecoverAbandonedJobs() error = %v", err)
+			}
+			if len(failed) != 0 {
+				t.Errorf("failed jobs = %d, want 0", len(failed))
+			}
+			if repo.transitions != 0 {
+				t.Errorf("transitions = %d, want 0", repo.transitions)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"comfyui server error", &repository.ComfyUIError{StatusCode: 503, Message: "busy"}, true},
+		{"render timeout", fmt.Errorf("render: %w", context.DeadlineExceeded), true},
+		{"abandoned job", ErrJobAbandoned, true},
+		{"template without workflow", fmt.Errorf("%w: template 3", repository.ErrComfyUINoWorkflow), false},
+		{"unsafe image", ErrImageNotSafe, false},
+		{"cancelled", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+	// ErrIdempotencyInProgress is returned when the first request with a key has not finished yet
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotencyService defines the interface for Idempotency-Key handling
+type IdempotencyService interface {
+	// Begin claims a key for a request. It returns a new in-progress record the
+	// caller must Complete or Abandon, or the completed record of an identical
+	// earlier request to replay.
+	Begin(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	
+	// Complete stores the response of a request so later requests replay it
+	Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, contentType string, body []byte) error
+	
+	// Abandon releases a key whose request failed, so a retry runs again
+	Abandon(ctx context.Context, record *model.IdempotencyRecord) error
+	
+	// PurgeExpired removes keys older than the configured TTL
+	PurgeExpired(ctx context.Context) error
+}
+
+type idempotencyServiceImpl struct {
+	cfg  config.IdempotencyConfig
+	repo repository.IdempotencyRepository
+}
+
+// NewIdempotencyService creates a new instance of IdempotencyService
+func NewIdempotencyService(cfg config.IdempotencyConfig, repo repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyServiceImpl{cfg: cfg, repo: repo}
+}
+
+func (s *idempotencyServiceImpl) Begin(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	// A second attempt covers the key being abandoned, or its lock running out,
+	// between Reserve and GetByKey
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.repo.Reserve(ctx, record, s.cfg.TTL, s.cfg.LockTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if reserved {
+			return record, nil
+		}
+
+		existing, err := s.repo.GetByKey(ctx, record.Scope, record.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		if existing.Fingerprint != record.Fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if !existing.IsCompleted() {
+			return nil, ErrIdempotencyInProgress
+		}
+		return existing, nil
+	}
+	return nil, ErrIdempotencyInProgress
+}
+
+func (s *idempotencyServiceImpl) Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, contentType string, body []byte) error {
+	if err := s.repo.Complete(ctx, record.ID, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyServiceImpl) Abandon(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := s.repo.Delete(ctx, record.ID); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyServiceImpl) PurgeExpired(ctx context.Context) error {
+	if _, err := s.repo.PurgeExpired(ctx); err != nil {
+		return fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return nil
+}
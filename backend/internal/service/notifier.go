@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+// Notifier tells users about generation jobs that finished after they left the app
+type Notifier interface {
+	// NotifyGenerationCompleted notifies the user that their images are ready
+	NotifyGenerationCompleted(ctx context.Context, job *Job) error
+	
+	// NotifyGenerationFailed notifies the user that their generation failed
+	NotifyGenerationFailed(ctx context.Context, job *Job, reason string) error
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier creates a Notifier that only logs, for tests and local development
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) NotifyGenerationCompleted(ctx context.Context, job *Job) error {
+	log.Printf("Notify user %d: generation %s completed", job.UserID, job.RequestID)
+	return nil
+}
+
+func (n *logNotifier) NotifyGenerationFailed(ctx context.Context, job *Job, reason string) error {
+	log.Printf("Notify user %d: generation %s failed: %s", job.UserID, job.RequestID, reason)
+	return nil
+}
+
+type wechatNotifier struct {
+	cfg              config.WeChatConfig
+	wechatRepo       repository.WechatRepository
+	subscriptionRepo repository.SubscriptionRepository
+	userRepo         repository.UserRepository
+	templateRepo     repository.TemplateRepository
+}
+
+// NewWechatNotifier creates a Notifier that sends WeChat subscribe messages. A message
+// is only sent when the user has an unused one-time grant for its template.
+func NewWechatNotifier(cfg config.WeChatConfig, wechatRepo repository.WechatRepository, subscriptionRepo repository.SubscriptionRepository, userRepo repository.UserRepository, templateRepo repository.TemplateRepository) Notifier {
+	return &wechatNotifier{
+		cfg:              cfg,
+		wechatRepo:       wechatRepo,
+		subscriptionRepo: subscriptionRepo,
+		userRepo:         userRepo,
+		templateRepo:     templateRepo,
+	}
+}
+
+func (n *wechatNotifier) NotifyGenerationCompleted(ctx context.Context, job *Job) error {
+	return n.send(ctx, job, n.cfg.NotifyTemplateCompleted, "已完成", "")
+}
+
+func (n *wechatNotifier) NotifyGenerationFailed(ctx context.Context, job *Job, reason string) error {
+	return n.send(ctx, job, n.cfg.NotifyTemplateFailed, "生成失败", reason)
+}
+
+func (n *wechatNotifier) send(ctx context.Context, job *Job, templateID, status, reason string) error {
+	if templateID == "" {
+		return nil
+	}
+
+	user, err := n.userRepo.GetByID(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	templateName := "AI写真"
+	if template, err := n.templateRepo.GetByIDWithDeleted(ctx, job.TemplateID); err == nil {
+		templateName = template.Name
+	}
+
+	data := map[string]model.WechatMessageValue{
+		"thing1":  {Value: truncateThing(templateName)},
+		"phrase2": {Value: status},
+		"time3":   {Value: time.Now().Format("2006-01-02 15:04")},
+	}
+	if reason != "" {
+		data["thing4"] = model.WechatMessageValue{Value: truncateThing(reason)}
+	}
+
+	msg := &model.WechatSubscribeMessage{
+		ToUser:           user.WechatOpenID,
+		TemplateID:       templateID,
+		Page:             fmt.Sprintf("%s?request_id=%s", n.cfg.NotifyPage, job.RequestID),
+		MiniprogramState: n.cfg.NotifyState,
+		Lang:             "zh_CN",
+		Data:             data,
+	}
+	grantID, err := n.subscriptionRepo.ConsumeGrant(ctx, job.UserID, templateID)
+	if err != nil {
+		return fmt.Errorf("failed to consume subscription grant: %w", err)
+	}
+	if grantID == 0 {
+		// The user did not opt in, so WeChat would reject the message anyway
+		return nil
+	}
+
+	if err := n.wechatRepo.SendSubscribeMessage(ctx, msg); err != nil {
+		if errors.Is(err, repository.ErrWechatNotSubscribed) {
+			// The user withdrew the subscription in WeChat settings
+			return nil
+		}
+		// WeChat only uses up its side of the grant when a message is delivered
+		if restoreErr := n.subscriptionRepo.RestoreGrant(ctx, grantID); restoreErr != nil {
+			log.Printf("Failed to restore subscription grant %d: %v", grantID, restoreErr)
+		}
+		return fmt.Errorf("failed to send subscribe message: %w", err)
+	}
+	return nil
+}
+
+// truncateThing shortens a value to the 20 characters allowed in "thing" fields
+func truncateThing(value string) string {
+	const maxRunes = 20
+	if utf8.RuneCountInString(value) <= maxRunes {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:maxRunes-1]) + "…"
+}
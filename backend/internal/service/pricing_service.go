@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/pricing"
+	"github.com/45ai/backend/internal/repository"
+)
+
+const pricingRuleNameMaxLength = 100
+
+// PricingService defines the interface for template pricing
+type PricingService interface {
+	// Quote prices a template for a user; userID 0 quotes for an anonymous visitor
+	Quote(ctx context.Context, userID int64, template *model.Template) (*pricing.Quote, error)
+	
+	// ApplyPrices fills in the effective price of each template for a user
+	ApplyPrices(ctx context.Context, userID int64, templates []model.Template) error
+	
+	// ListRules retrieves every pricing rule for the admin API
+	ListRules(ctx context.Context) ([]model.PricingRule, error)
+	
+	// CreateRule creates a pricing rule
+	CreateRule(ctx context.Context, req *model.PricingRuleRequest) (*model.PricingRule, error)
+	
+	// UpdateRule replaces a pricing rule
+	UpdateRule(ctx context.Context, id int, req *model.PricingRuleRequest) (*model.PricingRule, error)
+	
+	// DeleteRule deletes a pricing rule; transactions keep its ID
+	DeleteRule(ctx context.Context, id int) error
+	
+	// SetMembership sets when a user's membership ends; nil ends it now
+	SetMembership(ctx context.Context, userID int64, memberUntil *time.Time) (*model.User, error)
+}
+
+type pricingServiceImpl struct {
+	engine          *pricing.Engine
+	repo            repository.PricingRuleRepository
+	templateRepo    repository.TemplateRepository
+	userRepo        repository.UserRepository
+	transactionRepo repository.TransactionRepository
+}
+
+// NewPricingService creates a new instance of PricingService
+func NewPricingService(cfg config.PricingConfig, repo repository.PricingRuleRepository, templateRepo repository.TemplateRepository, userRepo repository.UserRepository, transactionRepo repository.TransactionRepository) PricingService {
+	return &pricingServiceImpl{
+		engine:          pricing.NewEngine(cfg.NewUserPeriod),
+		repo:            repo,
+		templateRepo:    templateRepo,
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+func (s *pricingServiceImpl) Quote(ctx context.Context, userID int64, template *model.Template) (*pricing.Quote, error) {
+	rules, customer, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quote := s.engine.Quote(template, customer, rules, time.Now())
+	return &quote, nil
+}
+
+func (s *pricingServiceImpl) ApplyPrices(ctx context.Context, userID int64, templates []model.Template) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	rules, customer, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range templates {
+		quote := s.engine.Quote(&templates[i], customer, rules, now)
+		templates[i].EffectiveCreditCost = quote.Price
+		templates[i].PricingRule = quote.AppliedRule()
+	}
+	return nil
+}
+
+// load fetches the active rules and describes the user they are evaluated for
+func (s *pricingServiceImpl) load(ctx context.Context, userID int64) ([]model.PricingRule, pricing.Customer, error) {
+	rules, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return nil, pricing.Customer{}, fmt.Errorf("failed to list pricing rules: %w", err)
+	}
+	if userID == 0 {
+		return rules, pricing.Customer{Anonymous: true}, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, pricing.Customer{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	customer := pricing.Customer{CreatedAt: user.CreatedAt, MemberUntil: user.MemberUntil}
+	for _, rule := range rules {
+		// Only look up history when a rule depends on it
+		if rule.Audience == model.PricingAudienceFirstGeneration {
+			customer.HasGenerated, err = s.transactionRepo.HasTransactionOfType(ctx, userID, model.TransactionTypeGeneration)
+			if err != nil {
+				return nil, pricing.Customer{}, fmt.Errorf("failed to check generation history: %w", err)
+			}
+			break
+		}
+	}
+	return rules, customer, nil
+}
+
+func (s *pricingServiceImpl) ListRules(ctx context.Context) ([]model.PricingRule, error) {
+	rules, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pricing rules: %w", err)
+	}
+	if rules == nil {
+		rules = []model.PricingRule{}
+	}
+	return rules, nil
+}
+
+func (s *pricingServiceImpl) CreateRule(ctx context.Context, req *model.PricingRuleRequest) (*model.PricingRule, error) {
+	rule := &model.PricingRule{}
+	if err := s.applyRequest(ctx, rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create pricing rule: %w", err)
+	}
+	return s.repo.GetByID(ctx, rule.ID)
+}
+
+func (s *pricingServiceImpl) UpdateRule(ctx context.Context, id int, req *model.PricingRuleRequest) (*model.PricingRule, error) {
+	rule, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing rule: %w", err)
+	}
+	if err := s.applyRequest(ctx, rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update pricing rule: %w", err)
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *pricingServiceImpl) DeleteRule(ctx context.Context, id int) error {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("failed to get pricing rule: %w", err)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete pricing rule: %w", err)
+	}
+	return nil
+}
+
+func (s *pricingServiceImpl) SetMembership(ctx context.Context, userID int64, memberUntil *time.Time) (*model.User, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.userRepo.SetMemberUntil(ctx, userID, memberUntil); err != nil {
+		return nil, fmt.Errorf("failed to set membership: %w", err)
+	}
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// applyRequest copies a request onto a rule and validates the result
+func (s *pricingServiceImpl) applyRequest(ctx context.Context, rule *model.PricingRule, req *model.PricingRuleRequest) error {
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.TemplateID = req.TemplateID
+	rule.Audience = req.Audience
+	if rule.Audience == "" {
+		rule.Audience = model.PricingAudienceEveryone
+	}
+	rule.DiscountType = req.DiscountType
+	rule.Value = req.Value
+	rule.StartsAt = req.StartsAt
+	rule.EndsAt = req.EndsAt
+	rule.IsActive = req.IsActive == nil || *req.IsActive
+
+	length := utf8.RuneCountInString(rule.Name)
+	if length == 0 || length > pricingRuleNameMaxLength {
+		return &ValidationError{Field: "name", Message: fmt.Sprintf("must be 1 to %d characters", pricingRuleNameMaxLength)}
+	}
+	if err := pricing.Validate(rule); err != nil {
+		var fieldErr *pricing.FieldError
+		if errors.As(err, &fieldErr) {
+			return &ValidationError{Field: fieldErr.Field, Message: fieldErr.Message}
+		}
+		return err
+	}
+	if rule.TemplateID != nil {
+		if _, err := s.templateRepo.GetByID(ctx, *rule.TemplateID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ValidationError{Field: "template_id", Message: fmt.Sprintf("template %d does not exist", *rule.TemplateID)}
+			}
+			return fmt.Errorf("failed to get template: %w", err)
+		}
+	}
+	return nil
+}
@@ -2,45 +2,129 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/internal/scheduler"
 )
 
 type Job struct {
+	RequestID  string
 	UserID     int64
 	TemplateID int
-	ImageData  []byte
+	// Attempt is the attempt the job was claimed for; the worker may only settle
+	// the job while it is still on that attempt
+	Attempt int
 }
 
+// QueueService hands queued generation jobs to workers. Jobs are queued by
+// creating them in the queued state; the queue is shared by every API and
+// worker instance through the database.
 type QueueService interface {
-	AddJob(ctx context.Context, job *Job) error
+	// GetJob claims the next job to process, or returns nil if none can start now
 	GetJob(ctx context.Context) (*Job, error)
+	
+	// Position returns where a queued job is in line, 1 being next, or 0 if it is
+	// not queued. A job waiting to be retried is placed as if it could start now.
+	// Positions may be a few seconds old.
+	Position(ctx context.Context, requestID string) (int, error)
 }
 
-type inMemoryQueueService struct {
-	queue []*Job
-	mutex sync.Mutex
+// positionCacheTTL is how long queue positions are reused; clients poll the
+// status of their jobs, and every computation reads the whole queue
+const positionCacheTTL = 2 * time.Second
+
+type queueServiceImpl struct {
+	scheduler *scheduler.Scheduler
+	jobRepo   repository.GenerationJobRepository
+	lease     time.Duration
+
+	mutex       sync.RWMutex
+	positions   map[string]int
+	positionsAt time.Time
+
+	loadMutex sync.Mutex
+	load      *positionLoad
 }
 
-func NewInMemoryQueueService() QueueService {
-	return &inMemoryQueueService{
-		queue: make([]*Job, 0),
-	}
+// positionLoad is a read of the queue shared by every poll that needs it
+type positionLoad struct {
+	done      chan struct{}
+	positions map[string]int
+	err       error
 }
 
-func (s *inMemoryQueueService) AddJob(ctx context.Context, job *Job) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	s.queue = append(s.queue, job)
-	return nil
+// NewQueueService creates a QueueService that schedules jobs fairly between users
+func NewQueueService(cfg config.QueueConfig, jobRepo repository.GenerationJobRepository) QueueService {
+	return &queueServiceImpl{
+		scheduler: scheduler.New(cfg.MaxInFlightPerUser),
+		jobRepo:   jobRepo,
+		lease:     cfg.JobLease,
+	}
 }
 
-func (s *inMemoryQueueService) GetJob(ctx context.Context) (*Job, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	if len(s.queue) == 0 {
+func (s *queueServiceImpl) GetJob(ctx context.Context) (*Job, error) {
+	job, err := s.jobRepo.Claim(ctx, s.lease, s.scheduler.Next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim generation job: %w", err)
+	}
+	if job == nil {
 		return nil, nil
 	}
-	job := s.queue[0]
-	s.queue = s.queue[1:]
-	return job, nil
-} 
\ No newline at end of file
+	return &Job{RequestID: job.RequestID, UserID: job.UserID, TemplateID: job.TemplateID, Attempt: job.Attempts}, nil
+}
+
+func (s *queueServiceImpl) Position(ctx context.Context, requestID string) (int, error) {
+	// Jobs queued since the last load are missing, and only queued jobs are asked for
+	s.mutex.RLock()
+	position, ok := s.positions[requestID]
+	fresh := time.Since(s.positionsAt) < positionCacheTTL
+	s.mutex.RUnlock()
+	if ok && fresh {
+		return position, nil
+	}
+
+	positions, err := s.loadPositions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return positions[requestID], nil
+}
+
+// loadPositions reads the queue once for every concurrent poll. Cached
+// positions stay readable meanwhile, and waiting polls give up with their ctx.
+func (s *queueServiceImpl) loadPositions(ctx context.Context) (map[string]int, error) {
+	s.loadMutex.Lock()
+	if load := s.load; load != nil {
+		s.loadMutex.Unlock()
+		select {
+		case <-load.done:
+			return load.positions, load.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	load := &positionLoad{done: make(chan struct{})}
+	s.load = load
+	s.loadMutex.Unlock()
+
+	queue, err := s.jobRepo.GetQueue(ctx)
+	if err != nil {
+		load.err = fmt.Errorf("failed to get generation queue: %w", err)
+	} else {
+		load.positions = s.scheduler.Positions(queue)
+		s.mutex.Lock()
+		s.positions = load.positions
+		s.positionsAt = time.Now()
+		s.mutex.Unlock()
+	}
+
+	s.loadMutex.Lock()
+	s.load = nil
+	s.loadMutex.Unlock()
+	close(load.done)
+	return load.positions, load.err
+}
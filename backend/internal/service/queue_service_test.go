@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+// stallingQueue serves a queue of r1 and r2 once release is closed
+type stallingQueue struct {
+	repository.GenerationJobRepository
+	release chan struct{}
+	loads   atomic.Int32
+}
+
+func (f *stallingQueue) GetQueue(ctx context.Context) (*model.GenerationQueue, error) {
+	f.loads.Add(1)
+	select {
+	case <-f.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &model.GenerationQueue{Queued: []model.GenerationJob{
+		{ID: 1, RequestID: "r1", UserID: 1, Lane: model.GenerationLaneStandard},
+		{ID: 2, RequestID: "r2", UserID: 2, Lane: model.GenerationLaneStandard},
+	}}, nil
+}
+
+func TestPositionSharesLoad(t *testing.T) {
+	repo := &stallingQueue{release: make(chan struct{})}
+	svc := NewQueueService(config.QueueConfig{}, repo)
+
+	var wg sync.WaitGroup
+	positions := make([]int, 10)
+	for i := range positions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			position, err := svc.Position(context.Background(), "r2")
+			if err != nil {
+				t.Errorf("Position() error = %v", err)
+			}
+			positions[i] = position
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	if loads := repo.loads.Load(); loads != 1 {
+		t.Errorf("queue loaded %d times, want 1", loads)
+	}
+	for i, position := range positions {
+		if position != 2 {
+			t.Errorf("poll %d: Position = %d, want 2", i, position)
+		}
+	}
+}
+
+func TestPositionDuringStalledLoad(t *testing.T) {
+	repo := &stallingQueue{release: make(chan struct{})}
+	close(repo.release)
+	svc := NewQueueService(config.QueueConfig{}, repo)
+	if _, err := svc.Position(context.Background(), "r1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A job missing from the cache starts a load that does not finish
+	repo.release = make(chan struct{})
+	defer close(repo.release)
+	go svc.Position(context.Background(), "r3")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan int)
+	go func() {
+		position, _ := svc.Position(context.Background(), "r1")
+		done <- position
+	}()
+	select {
+	case position := <-done:
+		if position != 1 {
+			t.Errorf("Position = %d, want 1", position)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached position waited for the stalled load")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := svc.Position(ctx, "r4"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Position() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUserBanned is returned when a banned user tries to authenticate
+var ErrUserBanned = errors.New("user is banned")
+
+// AccessClaims holds the claims of a validated access token
+type AccessClaims struct {
+	UserID    int64
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// SessionService defines the interface for server-side session revocation
+type SessionService interface {
+	// IsRevoked checks whether an access token has been revoked
+	IsRevoked(ctx context.Context, claims *AccessClaims) (bool, error)
+	
+	// RevokeToken revokes a single access token
+	RevokeToken(ctx context.Context, claims *AccessClaims) error
+	
+	// RevokeAllForUser revokes every access and refresh token of a user
+	RevokeAllForUser(ctx context.Context, userID int64) error
+	
+	// BanUser bans a user and revokes all of their sessions
+	BanUser(ctx context.Context, userID int64) error
+	
+	// UnbanUser lifts a ban; the user has to log in again
+	UnbanUser(ctx context.Context, userID int64) error
+	
+	// PurgeExpired removes denylist entries for tokens that have expired
+	PurgeExpired(ctx context.Context) error
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/cache"
+)
+
+type sessionServiceImpl struct {
+	revocationRepo   repository.RevocationRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	userRepo         repository.UserRepository
+
+	// Lookups are cached for a few seconds so the auth middleware does not hit
+	// the database on every request. Revocations made through this instance
+	// evict the cache immediately; those made elsewhere apply once the entry expires.
+	tokenCache *cache.LRU[string, bool]
+	userCache  *cache.LRU[int64, time.Time]
+}
+
+// NewSessionService creates a new instance of SessionService
+func NewSessionService(cfg config.SessionConfig, revocationRepo repository.RevocationRepository, refreshTokenRepo repository.RefreshTokenRepository, userRepo repository.UserRepository) SessionService {
+	return &sessionServiceImpl{
+		revocationRepo:   revocationRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		userRepo:         userRepo,
+		tokenCache:       cache.NewLRU[string, bool](cfg.RevocationCacheSize, cfg.RevocationCacheTTL),
+		userCache:        cache.NewLRU[int64, time.Time](cfg.RevocationCacheSize, cfg.RevocationCacheTTL),
+	}
+}
+
+func (s *sessionServiceImpl) IsRevoked(ctx context.Context, claims *AccessClaims) (bool, error) {
+	revokedBefore, ok := s.userCache.Get(claims.UserID)
+	if !ok {
+		before, err := s.revocationRepo.GetUserRevokedBefore(ctx, claims.UserID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get session revocation: %w", err)
+		}
+		if before != nil {
+			revokedBefore = *before
+		}
+		s.userCache.Add(claims.UserID, revokedBefore)
+	}
+	// iat has millisecond precision, so only a token issued in the same
+	// millisecond as the revocation is treated as revoked
+	if !revokedBefore.IsZero() && !claims.IssuedAt.After(revokedBefore) {
+		return true, nil
+	}
+
+	if claims.TokenID == "" {
+		return false, nil
+	}
+	revoked, ok := s.tokenCache.Get(claims.TokenID)
+	if !ok {
+		var err error
+		revoked, err = s.revocationRepo.IsTokenRevoked(ctx, claims.TokenID)
+		if err != nil {
+			return false, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		s.tokenCache.Add(claims.TokenID, revoked)
+	}
+	return revoked, nil
+}
+
+func (s *sessionServiceImpl) RevokeToken(ctx context.Context, claims *AccessClaims) error {
+	if claims.TokenID == "" {
+		return fmt.Errorf("token has no jti claim")
+	}
+	if err := s.revocationRepo.RevokeToken(ctx, claims.TokenID, claims.UserID, claims.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	s.tokenCache.Add(claims.TokenID, true)
+	return nil
+}
+
+func (s *sessionServiceImpl) RevokeAllForUser(ctx context.Context, userID int64) error {
+	if err := s.revocationRepo.RevokeUserSessions(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	s.userCache.Remove(userID)
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *sessionServiceImpl) BanUser(ctx context.Context, userID int64) error {
+	if err := s.userRepo.SetBanned(ctx, userID, true); err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+	return s.RevokeAllForUser(ctx, userID)
+}
+
+func (s *sessionServiceImpl) UnbanUser(ctx context.Context, userID int64) error {
+	if err := s.userRepo.SetBanned(ctx, userID, false); err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+	return nil
+}
+
+func (s *sessionServiceImpl) PurgeExpired(ctx context.Context) error {
+	if _, err := s.revocationRepo.DeleteExpired(ctx, time.Now()); err != nil {
+		return fmt.Errorf("failed to purge revoked tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
+	"github.com/45ai/backend/internal/repository"
+)
+
+// Notification events users can subscribe to
+const (
+	EventGenerationCompleted = "generation_completed"
+	EventGenerationFailed    = "generation_failed"
+)
+
+// SubscriptionService defines the interface for managing subscribe message grants
+type SubscriptionService interface {
+	// GetSubscriptions lists the notification templates and the user's unused grants
+	GetSubscriptions(ctx context.Context, userID int64) ([]model.SubscriptionStatus, error)
+	
+	// RecordGrants stores the templates the user accepted in wx.requestSubscribeMessage
+	RecordGrants(ctx context.Context, userID int64, results map[string]string) ([]model.SubscriptionStatus, error)
+}
+
+type subscriptionServiceImpl struct {
+	cfg  config.WeChatConfig
+	repo repository.SubscriptionRepository
+}
+
+// NewSubscriptionService creates a new instance of SubscriptionService
+func NewSubscriptionService(cfg config.WeChatConfig, repo repository.SubscriptionRepository) SubscriptionService {
+	return &subscriptionServiceImpl{cfg: cfg, repo: repo}
+}
+
+func (s *subscriptionServiceImpl) GetSubscriptions(ctx context.Context, userID int64) ([]model.SubscriptionStatus, error) {
+	statuses := s.templates()
+	for i := range statuses {
+		available, err := s.repo.CountAvailable(ctx, userID, statuses[i].TemplateID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count subscription grants: %w", err)
+		}
+		statuses[i].Available = available
+	}
+	return statuses, nil
+}
+
+func (s *subscriptionServiceImpl) RecordGrants(ctx context.Context, userID int64, results map[string]string) ([]model.SubscriptionStatus, error) {
+	for _, status := range s.templates() {
+		// Ignore templates we never send, and anything the user did not accept
+		if results[status.TemplateID] != "accept" {
+			continue
+		}
+		if err := s.repo.CreateGrant(ctx, userID, status.TemplateID); err != nil {
+			return nil, fmt.Errorf("failed to record subscription grant: %w", err)
+		}
+	}
+	return s.GetSubscriptions(ctx, userID)
+}
+
+// templates returns the configured notification templates
+func (s *subscriptionServiceImpl) templates() []model.SubscriptionStatus {
+	var statuses []model.SubscriptionStatus
+	if s.cfg.NotifyTemplateCompleted != "" {
+		statuses = append(statuses, model.SubscriptionStatus{Event: EventGenerationCompleted, TemplateID: s.cfg.NotifyTemplateCompleted})
+	}
+	if s.cfg.NotifyTemplateFailed != "" {
+		statuses = append(statuses, model.SubscriptionStatus{Event: EventGenerationFailed, TemplateID: s.cfg.NotifyTemplateFailed})
+	}
+	return statuses
+}
@@ -2,20 +2,73 @@ package service
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
+
 	"github.com/45ai/backend/internal/model"
 )
 
+// ErrTemplateNameTaken is returned when another template already has the name
+var ErrTemplateNameTaken = errors.New("template name already exists")
+
+// IneligibleError is returned when a user cannot use a template right now
+type IneligibleError struct {
+	Eligibility *model.TemplateEligibility
+}
+
+func (e *IneligibleError) Error() string {
+	codes := make([]string, len(e.Eligibility.Reasons))
+	for i, reason := range e.Eligibility.Reasons {
+		codes[i] = reason.Code
+	}
+	return "not eligible to use template: " + strings.Join(codes, ", ")
+}
+
 // TemplateService defines the interface for template business logic
 type TemplateService interface {
-	// GetAllTemplates retrieves all active templates
-	GetAllTemplates(ctx context.Context) (*model.TemplateListResponse, error)
+	// GetAllTemplates retrieves a page of active templates matching the filter,
+	// priced for the user; userID 0 prices for an anonymous visitor
+	GetAllTemplates(ctx context.Context, userID int64, filter model.TemplateFilter) (*model.TemplateListResponse, error)
 	
-	// GetTemplateByID retrieves a specific template
-	GetTemplateByID(ctx context.Context, id int) (*model.Template, error)
+	// GetTemplateByID retrieves a specific template priced for the user
+	GetTemplateByID(ctx context.Context, userID int64, id int) (*model.Template, error)
 	
-	// ValidateTemplateForUser checks if a user can use a template
+	// GetEligibility reports whether a user can render a number of variants of a template and why not
+	GetEligibility(ctx context.Context, userID int64, templateID int, variants int) (*model.TemplateEligibility, error)
+	
+	// ValidateTemplateForUser checks if a user can render one variant of a template,
+	// returning an *IneligibleError with the reasons if not
 	ValidateTemplateForUser(ctx context.Context, userID int64, templateID int) error
 	
-	// GetTemplateRequirements returns the requirements for using a template
-	GetTemplateRequirements(ctx context.Context, templateID int) (credits int, err error)
-} 
\ No newline at end of file
+	// GetTemplateRequirements returns the credits a user pays to use a template,
+	// after pricing rules
+	GetTemplateRequirements(ctx context.Context, userID int64, templateID int) (credits int, err error)
+	
+	// ListTemplates retrieves every template, including inactive ones, for the admin API
+	ListTemplates(ctx context.Context) (*model.TemplateListResponse, error)
+	
+	// CreateTemplate creates a template
+	CreateTemplate(ctx context.Context, req *model.TemplateCreateRequest) (*model.Template, error)
+	
+	// UpdateTemplate applies a partial update to a template
+	UpdateTemplate(ctx context.Context, id int, req *model.TemplateUpdateRequest) (*model.Template, error)
+	
+	// SetTemplateActive shows or hides a template in the gallery
+	SetTemplateActive(ctx context.Context, id int, isActive bool) (*model.Template, error)
+	
+	// SetTemplateSchedule replaces the availability window of a template
+	SetTemplateSchedule(ctx context.Context, id int, req *model.TemplateScheduleRequest) (*model.Template, error)
+	
+	// DeleteTemplate soft-deletes a template
+	DeleteTemplate(ctx context.Context, id int) error
+	
+	// ReorderTemplates sets the gallery order; templates not listed keep their position value
+	ReorderTemplates(ctx context.Context, ids []int) (*model.TemplateListResponse, error)
+	
+	// UploadPreview stores a new preview image and points the template at it
+	UploadPreview(ctx context.Context, id int, image io.Reader) (*model.Template, error)
+	
+	// RefreshUsageStats recomputes recent usage and trending scores and drops expired hourly usage
+	RefreshUsageStats(ctx context.Context) error
+}
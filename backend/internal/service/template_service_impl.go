@@ -1,41 +1,439 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/blobstore"
+)
+
+const (
+	templateNameMaxLength = 100
+	templateTagMaxLength  = 50
+	templateMaxTags       = 20
 )
 
+// previewImageTypes maps accepted preview content types to file extensions
+var previewImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
 type templateServiceImpl struct {
-	repo repository.TemplateRepository
+	cfg            config.TemplateConfig
+	repo           repository.TemplateRepository
+	categoryRepo   repository.TemplateCategoryRepository
+	statsRepo      repository.TemplateStatsRepository
+	userRepo       repository.UserRepository
+	jobRepo        repository.GenerationJobRepository
+	pricingService PricingService
+	blobStore      blobstore.Store
 }
 
-func NewTemplateService(repo repository.TemplateRepository) TemplateService {
-	return &templateServiceImpl{repo: repo}
+func NewTemplateService(
+	cfg config.TemplateConfig,
+	repo repository.TemplateRepository,
+	categoryRepo repository.TemplateCategoryRepository,
+	statsRepo repository.TemplateStatsRepository,
+	userRepo repository.UserRepository,
+	jobRepo repository.GenerationJobRepository,
+	pricingService PricingService,
+	blobStore blobstore.Store,
+) TemplateService {
+	return &templateServiceImpl{
+		cfg:            cfg,
+		repo:           repo,
+		categoryRepo:   categoryRepo,
+		statsRepo:      statsRepo,
+		userRepo:       userRepo,
+		jobRepo:        jobRepo,
+		pricingService: pricingService,
+		blobStore:      blobStore,
+	}
 }
 
-func (s *templateServiceImpl) GetAllTemplates(ctx context.Context) (*model.TemplateListResponse, error) {
-	templates, err := s.repo.GetAll(ctx)
+func (s *templateServiceImpl) GetAllTemplates(ctx context.Context, userID int64, filter model.TemplateFilter) (*model.TemplateListResponse, error) {
+	templates, total, err := s.repo.Search(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+	if templates == nil {
+		templates = []model.Template{}
+	}
+	if err := s.pricingService.ApplyPrices(ctx, userID, templates); err != nil {
+		return nil, err
+	}
 
 	return &model.TemplateListResponse{
 		Templates: templates,
-		Total:     len(templates),
+		Total:     total,
 	}, nil
 }
 
-func (s *templateServiceImpl) GetTemplateByID(ctx context.Context, id int) (*model.Template, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *templateServiceImpl) GetTemplateByID(ctx context.Context, userID int64, id int) (*model.Template, error) {
+	template, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	templates := []model.Template{*template}
+	if err := s.pricingService.ApplyPrices(ctx, userID, templates); err != nil {
+		return nil, err
+	}
+	return &templates[0], nil
+}
+
+func (s *templateServiceImpl) GetEligibility(ctx context.Context, userID int64, templateID int, variants int) (*model.TemplateEligibility, error) {
+	// Clients may hold on to a template after it was deleted
+	template, err := s.repo.GetByIDWithDeleted(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	quote, err := s.pricingService.Quote(ctx, userID, template)
+	if err != nil {
+		return nil, err
+	}
+
+	eligibility := &model.TemplateEligibility{
+		TemplateID:             template.ID,
+		ListCreditCost:         quote.ListPrice,
+		CreditCost:             quote.Price,
+		ExtraVariantCreditCost: quote.ExtraVariantPrice,
+		Variants:               variants,
+		TotalCreditCost:        quote.Total(variants),
+		PricingRule:            quote.AppliedRule(),
+		Credits:                user.Credits,
+		DailyLimit:             s.cfg.DailyGenerationLimit,
+		Reasons:                []model.EligibilityReason{},
+	}
+	deny := func(code, message string) {
+		eligibility.Reasons = append(eligibility.Reasons, model.EligibilityReason{Code: code, Message: message})
+	}
+
+	now := time.Now()
+	switch {
+	case template.IsDeleted():
+		deny(model.EligibilityTemplateDeleted, "This style has been removed")
+	case !template.IsActive:
+		deny(model.EligibilityTemplateInactive, "This style is not available")
+	case template.AvailableFrom != nil && now.Before(*template.AvailableFrom):
+		deny(model.EligibilityTemplateNotStarted, "This style is not available yet")
+	case template.AvailableUntil != nil && !now.Before(*template.AvailableUntil):
+		deny(model.EligibilityTemplateEnded, "This style is no longer available")
+	}
+	if user.IsBanned() {
+		deny(model.EligibilityUserBanned, "This account is banned")
+	}
+	if user.Credits < eligibility.TotalCreditCost {
+		deny(model.EligibilityInsufficientCredits, fmt.Sprintf("%d credits are needed, %d available", eligibility.TotalCreditCost, user.Credits))
+	}
+	if s.cfg.DailyGenerationLimit > 0 {
+		// Each variant is a generation; enqueue checks again while charging
+		used, err := s.jobRepo.CountVariantsToday(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count generations: %w", err)
+		}
+		eligibility.UsedToday = used
+		if used+variants > s.cfg.DailyGenerationLimit {
+			deny(model.EligibilityDailyLimitReached, fmt.Sprintf("At most %d generations are allowed per day, %d used", s.cfg.DailyGenerationLimit, used))
+		}
+	}
+
+	eligibility.Eligible = len(eligibility.Reasons) == 0
+	return eligibility, nil
 }
 
 func (s *templateServiceImpl) ValidateTemplateForUser(ctx context.Context, userID int64, templateID int) error {
-	// To be implemented in a future task
+	eligibility, err := s.GetEligibility(ctx, userID, templateID, 1)
+	if err != nil {
+		return err
+	}
+	if !eligibility.Eligible {
+		return &IneligibleError{Eligibility: eligibility}
+	}
+	return nil
+}
+
+func (s *templateServiceImpl) GetTemplateRequirements(ctx context.Context, userID int64, templateID int) (credits int, err error) {
+	template, err := s.repo.GetByID(ctx, templateID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get template: %w", err)
+	}
+	quote, err := s.pricingService.Quote(ctx, userID, template)
+	if err != nil {
+		return 0, err
+	}
+	return quote.Price, nil
+}
+
+func (s *templateServiceImpl) ListTemplates(ctx context.Context) (*model.TemplateListResponse, error) {
+	templates, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	if templates == nil {
+		templates = []model.Template{}
+	}
+	return &model.TemplateListResponse{Templates: templates, Total: len(templates)}, nil
+}
+
+func (s *templateServiceImpl) CreateTemplate(ctx context.Context, req *model.TemplateCreateRequest) (*model.Template, error) {
+	template := &model.Template{
+		Name:            strings.TrimSpace(req.Name),
+		Description:     strings.TrimSpace(req.Description),
+		PreviewImageURL: strings.TrimSpace(req.PreviewImageURL),
+		CreditCost:      req.CreditCost,
+		IsActive:        req.IsActive,
+		AvailableFrom:   req.AvailableFrom,
+		AvailableUntil:  req.AvailableUntil,
+	}
+	if req.SortOrder != nil {
+		template.SortOrder = *req.SortOrder
+	}
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCategories(ctx, req.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, template, req.CategoryIDs, tags); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return s.repo.GetByID(ctx, template.ID)
+}
+
+func (s *templateServiceImpl) UpdateTemplate(ctx context.Context, id int, req *model.TemplateUpdateRequest) (*model.Template, error) {
+	template, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	if req.Name != nil {
+		template.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		template.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.PreviewImageURL != nil {
+		template.PreviewImageURL = strings.TrimSpace(*req.PreviewImageURL)
+	}
+	if req.CreditCost != nil {
+		template.CreditCost = *req.CreditCost
+	}
+	if req.SortOrder != nil {
+		template.SortOrder = *req.SortOrder
+	}
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	var tags *[]string
+	if req.Tags != nil {
+		normalized, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		tags = &normalized
+	}
+	if req.CategoryIDs != nil {
+		if err := s.validateCategories(ctx, *req.CategoryIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, template, req.CategoryIDs, tags); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *templateServiceImpl) SetTemplateActive(ctx context.Context, id int, isActive bool) (*model.Template, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if err := s.repo.SetActive(ctx, id, isActive); err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *templateServiceImpl) SetTemplateSchedule(ctx context.Context, id int, req *model.TemplateScheduleRequest) (*model.Template, error) {
+	template, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	template.AvailableFrom = req.AvailableFrom
+	template.AvailableUntil = req.AvailableUntil
+	if err := s.validateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, template, nil, nil); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *templateServiceImpl) DeleteTemplate(ctx context.Context, id int) error {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	return nil
+}
+
+func (s *templateServiceImpl) ReorderTemplates(ctx context.Context, ids []int) (*model.TemplateListResponse, error) {
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, &ValidationError{Field: "ids", Message: fmt.Sprintf("template %d is listed twice", id)}
+		}
+		seen[id] = true
+	}
+
+	if err := s.repo.Reorder(ctx, ids); err != nil {
+		return nil, fmt.Errorf("failed to reorder templates: %w", err)
+	}
+	return s.ListTemplates(ctx)
+}
+
+func (s *templateServiceImpl) UploadPreview(ctx context.Context, id int, image io.Reader) (*model.Template, error) {
+	template, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	// Trust the bytes, not the client's Content-Type
+	buffered := bufio.NewReader(image)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	contentType := http.DetectContentType(head)
+	ext, ok := previewImageTypes[contentType]
+	if !ok {
+		return nil, &ValidationError{Field: "image", Message: "must be a JPEG, PNG or WebP image"}
+	}
+
+	// A new key per upload so CDN and client caches never serve the old preview
+	suffix, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("templates/%d/preview-%s%s", id, suffix[:12], ext)
+	url, err := s.blobStore.Put(ctx, key, buffered, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store preview: %w", err)
+	}
+
+	template.PreviewImageURL = url
+	if err := s.repo.Update(ctx, template, nil, nil); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// validateCategories checks that every category exists
+func (s *templateServiceImpl) validateCategories(ctx context.Context, categoryIDs []int) error {
+	for _, id := range categoryIDs {
+		if _, err := s.categoryRepo.GetByID(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return &ValidationError{Field: "category_ids", Message: fmt.Sprintf("category %d does not exist", id)}
+			}
+			return fmt.Errorf("failed to get category: %w", err)
+		}
+	}
 	return nil
 }
 
-func (s *templateServiceImpl) GetTemplateRequirements(ctx context.Context, templateID int) (credits int, err error) {
-	// To be implemented in a future task
-	return 0, nil
-} 
\ No newline at end of file
+// normalizeTags trims, deduplicates and checks tags
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > templateTagMaxLength {
+			return nil, &ValidationError{Field: "tags", Message: fmt.Sprintf("tags must be at most %d characters", templateTagMaxLength)}
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > templateMaxTags {
+		return nil, &ValidationError{Field: "tags", Message: fmt.Sprintf("at most %d tags are allowed", templateMaxTags)}
+	}
+	return normalized, nil
+}
+
+// validateTemplate checks template fields and name uniqueness. Concurrent
+// writes can still race past the name check; the unique index catches those.
+func (s *templateServiceImpl) validateTemplate(ctx context.Context, template *model.Template) error {
+	length := utf8.RuneCountInString(template.Name)
+	if length == 0 || length > templateNameMaxLength {
+		return &ValidationError{Field: "name", Message: fmt.Sprintf("must be 1 to %d characters", templateNameMaxLength)}
+	}
+	if template.CreditCost < 0 {
+		return &ValidationError{Field: "credit_cost", Message: "must not be negative"}
+	}
+	if template.SortOrder < 0 {
+		return &ValidationError{Field: "sort_order", Message: "must not be negative"}
+	}
+	if template.AvailableFrom != nil && template.AvailableUntil != nil && !template.AvailableUntil.After(*template.AvailableFrom) {
+		return &ValidationError{Field: "available_until", Message: "must be after available_from"}
+	}
+
+	existing, err := s.repo.GetByName(ctx, template.Name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check template name: %w", err)
+	}
+	if existing != nil && existing.ID != template.ID {
+		return ErrTemplateNameTaken
+	}
+	return nil
+}
+
+func (s *templateServiceImpl) RefreshUsageStats(ctx context.Context) error {
+	if err := s.statsRepo.Refresh(ctx, s.cfg.TrendingHalfLife); err != nil {
+		return fmt.Errorf("failed to refresh template stats: %w", err)
+	}
+	if err := s.statsRepo.PruneUsage(ctx, s.cfg.UsageRetention); err != nil {
+		return fmt.Errorf("failed to prune template usage: %w", err)
+	}
+	return nil
+}
@@ -1 +1,13 @@
- 
\ No newline at end of file
+package service
+
+import (
+	"context"
+
+	"github.com/45ai/backend/internal/model"
+)
+
+// TransactionService defines the interface for credit transaction business logic
+type TransactionService interface {
+	// GetTransactionsByUserID retrieves a page of transactions for a user
+	GetTransactionsByUserID(ctx context.Context, userID int64, limit, offset int) ([]model.Transaction, error)
+}
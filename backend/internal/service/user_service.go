@@ -2,23 +2,162 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 
+	"github.com/45ai/backend/internal/config"
 	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
+	"github.com/45ai/backend/pkg/secretbox"
+	"github.com/45ai/backend/pkg/wxcrypt"
+)
+
+var (
+	// ErrWechatSessionMissing is returned when no session key is stored for the user
+	ErrWechatSessionMissing = errors.New("no wechat session, please log in again")
+
+	// ErrInvalidWechatData is returned when an encrypted payload fails verification
+	ErrInvalidWechatData = errors.New("invalid wechat encrypted data")
 )
 
 type UserService interface {
 	GetUserByID(ctx context.Context, id int64) (*model.User, error)
+
+	// UpdateProfile applies a partial profile update after validation and moderation
+	UpdateProfile(ctx context.Context, userID int64, req *model.UserUpdateRequest) (*model.User, error)
+
+	// SyncWechatProfile decrypts a wx.getUserProfile payload and stores the nickname and avatar
+	SyncWechatProfile(ctx context.Context, userID int64, req *model.WechatProfileSyncRequest) (*model.User, error)
 }
 
 type userServiceImpl struct {
-	repo repository.UserRepository
+	repo                 repository.UserRepository
+	cfg                  config.UserConfig
+	wechatCfg            config.WeChatConfig
+	sessionKeyBox        *secretbox.Box
+	contentSafetyService ContentSafetyService
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userServiceImpl{repo: repo}
+func NewUserService(repo repository.UserRepository, cfg config.UserConfig, wechatCfg config.WeChatConfig, sessionKeyBox *secretbox.Box, contentSafetyService ContentSafetyService) UserService {
+	return &userServiceImpl{
+		repo:                 repo,
+		cfg:                  cfg,
+		wechatCfg:            wechatCfg,
+		sessionKeyBox:        sessionKeyBox,
+		contentSafetyService: contentSafetyService,
+	}
 }
 
 func (s *userServiceImpl) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	return s.repo.GetByID(ctx, id)
-} 
\ No newline at end of file
+}
+
+func (s *userServiceImpl) UpdateProfile(ctx context.Context, userID int64, req *model.UserUpdateRequest) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.applyProfile(ctx, user, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// applyProfile validates and moderates profile changes and applies them to user.
+// Every source of a nickname or avatar, including WeChat, goes through here.
+func (s *userServiceImpl) applyProfile(ctx context.Context, user *model.User, req *model.UserUpdateRequest) error {
+	if req.Nickname != nil {
+		nickname, err := validateNickname(*req.Nickname)
+		if err != nil {
+			return err
+		}
+		if nickname != user.Nickname {
+			safe, err := s.contentSafetyService.ValidateText(ctx, user.WechatOpenID, nickname)
+			if err != nil {
+				return fmt.Errorf("failed to moderate nickname: %w", err)
+			}
+			if !safe {
+				return &ValidationError{Field: "nickname", Message: "contains prohibited content"}
+			}
+		}
+		user.Nickname = nickname
+	}
+
+	if req.AvatarURL != nil {
+		avatarURL, err := validateAvatarURL(*req.AvatarURL, s.cfg.AvatarAllowedHosts)
+		if err != nil {
+			return err
+		}
+		user.AvatarURL = avatarURL
+	}
+	return nil
+}
+
+func (s *userServiceImpl) SyncWechatProfile(ctx context.Context, userID int64, req *model.WechatProfileSyncRequest) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	sealed, err := s.repo.GetWechatSessionKey(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get wechat session: %w", err)
+	}
+	if sealed == "" {
+		return nil, ErrWechatSessionMissing
+	}
+	sessionKey, err := s.sessionKeyBox.Open(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wechat session key: %w", err)
+	}
+
+	// rawData and signature are optional, but when sent they must match
+	if req.RawData != "" || req.Signature != "" {
+		if !wxcrypt.VerifySignature(req.RawData, string(sessionKey), req.Signature) {
+			return nil, ErrInvalidWechatData
+		}
+	}
+
+	plaintext, err := wxcrypt.Decrypt(string(sessionKey), req.EncryptedData, req.IV)
+	if err != nil {
+		return nil, ErrInvalidWechatData
+	}
+
+	var info model.WechatUserInfo
+	if err := json.Unmarshal(plaintext, &info); err != nil {
+		return nil, ErrInvalidWechatData
+	}
+	// The watermark proves the payload was issued for our mini program, and the
+	// OpenID (when present) that it belongs to this user
+	if info.Watermark.AppID != s.wechatCfg.AppID {
+		return nil, ErrInvalidWechatData
+	}
+	if info.OpenID != "" && info.OpenID != user.WechatOpenID {
+		return nil, ErrInvalidWechatData
+	}
+
+	profile := &model.UserUpdateRequest{}
+	if info.NickName != "" {
+		profile.Nickname = &info.NickName
+	}
+	if info.AvatarURL != "" {
+		profile.AvatarURL = &info.AvatarURL
+	}
+	if err := s.applyProfile(ctx, user, profile); err != nil {
+		return nil, err
+	}
+	if info.UnionID != "" && user.WechatUnionID == nil {
+		user.WechatUnionID = &info.UnionID
+	}
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationError describes a field of user input that was rejected
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+const (
+	nicknameMinLength = 1
+	nicknameMaxLength = 20
+)
+
+// validateNickname trims and checks a nickname. Letters of any script (so
+// Chinese names work), digits, spaces and a few separators are allowed.
+func validateNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+
+	length := utf8.RuneCountInString(nickname)
+	if length < nicknameMinLength || length > nicknameMaxLength {
+		return "", &ValidationError{Field: "nickname", Message: fmt.Sprintf("must be %d to %d characters", nicknameMinLength, nicknameMaxLength)}
+	}
+
+	for _, r := range nickname {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case r == ' ', r == '_', r == '-', r == '.', r == '·':
+		default:
+			return "", &ValidationError{Field: "nickname", Message: fmt.Sprintf("contains unsupported character %q", r)}
+		}
+	}
+
+	return nickname, nil
+}
+
+// validateAvatarURL checks that an avatar is an https URL on an allowed host.
+// An empty value clears the avatar.
+func validateAvatarURL(avatarURL string, allowedHosts []string) (string, error) {
+	avatarURL = strings.TrimSpace(avatarURL)
+	if avatarURL == "" {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil {
+		return "", &ValidationError{Field: "avatar_url", Message: "must be an https URL"}
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return avatarURL, nil
+		}
+	}
+	return "", &ValidationError{Field: "avatar_url", Message: "host is not allowed"}
+}
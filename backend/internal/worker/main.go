@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"time"
-	"bytes"
 
 	"github.com/45ai/backend/internal/config"
+	"github.com/45ai/backend/internal/model"
 	"github.com/45ai/backend/internal/repository"
 	"github.com/45ai/backend/internal/service"
+	"github.com/45ai/backend/pkg/blobstore"
 	"github.com/45ai/backend/pkg/database"
+	"github.com/45ai/backend/pkg/fieldcrypt"
 )
 
 func main() {
@@ -26,16 +31,50 @@ func main() {
 	}
 	defer db.Close()
 
+	piiCipher, err := fieldcrypt.New(cfg.PII)
+	if err != nil {
+		log.Fatal("Failed to initialize PII encryption:", err)
+	}
+
+	blobStore, err := blobstore.NewLocalStore(cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.DB)
+	userRepo := repository.NewUserRepository(db.DB, piiCipher)
 	transactionRepo := repository.NewTransactionRepository(db.DB)
 	templateRepo := repository.NewTemplateRepository(db.DB)
-	comfyuiRepo := repository.NewMockComfyUIRepository()
+	categoryRepo := repository.NewTemplateCategoryRepository(db.DB)
+	templateStatsRepo := repository.NewTemplateStatsRepository(db.DB)
+	comfyuiRepo := repository.NewComfyUIRepository(cfg.ComfyUI, nil)
+	wechatRepo := repository.NewWechatRepository(cfg.WeChat, nil, repository.NewWechatAccessTokenRepository(db.DB))
+	subscriptionRepo := repository.NewSubscriptionRepository(db.DB)
+	generationJobRepo := repository.NewGenerationJobRepository(db.DB)
+	comfyuiNodeReportRepo := repository.NewComfyUINodeReportRepository(db.DB)
 
 	// Initialize services
 	contentSafetyService := service.NewMockContentSafetyService()
-	generationService := service.NewGenerationService(contentSafetyService, userRepo, transactionRepo, templateRepo, comfyuiRepo)
-	queueService := service.NewInMemoryQueueService()
+	pricingService := service.NewPricingService(cfg.Pricing, repository.NewPricingRuleRepository(db.DB), templateRepo, userRepo, transactionRepo)
+	templateService := service.NewTemplateService(cfg.Template, templateRepo, categoryRepo, templateStatsRepo, userRepo, generationJobRepo, pricingService, blobStore)
+	queueService := service.NewQueueService(cfg.Queue, generationJobRepo)
+	generationService := service.NewGenerationService(cfg.Queue, cfg.Timeouts, cfg.ComfyUI, contentSafetyService, templateService, queueService, userRepo, transactionRepo,
+		templateRepo, generationJobRepo, comfyuiRepo, comfyuiNodeReportRepo, templateStatsRepo, blobStore)
+
+	// Tell users about finished jobs through WeChat when the mini program is configured
+	notifier := service.NewLogNotifier()
+	if cfg.WeChat.AppID != "" {
+		notifier = service.NewWechatNotifier(cfg.WeChat, wechatRepo, subscriptionRepo, userRepo, templateRepo)
+	}
+
+	// Route renders away from unhealthy ComfyUI nodes
+	go comfyuiRepo.RunHealthChecks(context.Background())
+
+	// Publish this worker's view of the nodes for the admin API
+	go reportBackends(context.Background(), generationService, workerName(), cfg.ComfyUI.ReportInterval)
+
+	// Take back jobs from workers that stopped
+	go recoverAbandonedJobs(context.Background(), generationService, notifier, cfg.Queue.JobLease)
 
 	log.Println("Worker starting...")
 
@@ -43,18 +82,87 @@ func main() {
 		job, err := queueService.GetJob(context.Background())
 		if err != nil {
 			log.Printf("Failed to get job from queue: %v", err)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		if job == nil {
+			// Wait for a second before checking for new jobs; while jobs are
+			// queued the next one is claimed right away
+			time.Sleep(1 * time.Second)
 			continue
 		}
 
-		if job != nil {
-			log.Printf("Processing job for user %d", job.UserID)
-			_, err := generationService.GenerateImage(context.Background(), job.UserID, job.TemplateID, bytes.NewReader(job.ImageData))
-			if err != nil {
-				log.Printf("Failed to process job: %v", err)
+		log.Printf("Processing job %s for user %d", job.RequestID, job.UserID)
+		result, err := generationService.ProcessJob(context.Background(), job)
+		switch {
+		case errors.Is(err, service.ErrGenerationCancelled):
+			log.Printf("Job %s was cancelled", job.RequestID)
+		case errors.Is(err, service.ErrLeaseLost):
+			log.Printf("Job %s was taken over by another worker", job.RequestID)
+		case result != nil && result.Status == model.GenerationStatusQueued:
+			// Users only hear about the outcome, not about each retry
+			log.Printf("Job %s will be retried at %s: %v", job.RequestID, result.NextAttemptAt.Format(time.RFC3339), err)
+		case err != nil:
+			log.Printf("Failed to process job: %v", err)
+			if err := notifier.NotifyGenerationFailed(context.Background(), job, "生成失败，请重试"); err != nil {
+				log.Printf("Failed to notify user %d: %v", job.UserID, err)
 			}
+		default:
+			if err := notifier.NotifyGenerationCompleted(context.Background(), job); err != nil {
+				log.Printf("Failed to notify user %d: %v", job.UserID, err)
+			}
+		}
+	}
+} 
+
+// workerName identifies this worker in ComfyUI node reports
+func workerName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// reportBackends periodically publishes the worker's ComfyUI pool state
+func reportBackends(ctx context.Context, generationService service.GenerationService, worker string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := generationService.ReportBackends(ctx, worker); err != nil {
+			log.Printf("Failed to report ComfyUI nodes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		// Wait for a second before checking for new jobs
-		time.Sleep(1 * time.Second)
+// recoverAbandonedJobs periodically retries or fails jobs whose worker stopped
+// renewing their lease, telling users about the jobs that failed
+func recoverAbandonedJobs(ctx context.Context, generationService service.GenerationService, notifier service.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			failed, err := generationService.RecoverAbandonedJobs(ctx)
+			if err != nil {
+				log.Printf("Failed to recover abandoned jobs: %v", err)
+			}
+			for _, job := range failed {
+				queued := &service.Job{RequestID: job.RequestID, UserID: job.UserID, TemplateID: job.TemplateID}
+				if err := notifier.NotifyGenerationFailed(ctx, queued, "生成失败，请重试"); err != nil {
+					log.Printf("Failed to notify user %d: %v", job.UserID, err)
+				}
+			}
+		}
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,18 @@
+-- Create refresh_tokens table
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    family_id CHAR(32) NOT NULL COMMENT 'Shared by every token rotated from the same login',
+    token_hash CHAR(64) NOT NULL COMMENT 'SHA-256 of the opaque token, the token itself is never stored',
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    UNIQUE INDEX idx_token_hash (token_hash),
+    INDEX idx_family_id (family_id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at),
+    
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) 
+        REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Drop revoked_tokens table
+DROP TABLE IF EXISTS revoked_tokens;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- Create revoked_tokens table
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti CHAR(32) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL COMMENT 'Row can be purged once the token would have expired anyway',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
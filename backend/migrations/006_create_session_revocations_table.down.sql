@@ -0,0 +1,2 @@
+-- Drop session_revocations table
+DROP TABLE IF EXISTS session_revocations;
\ No newline at end of file
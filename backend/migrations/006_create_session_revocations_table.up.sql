@@ -0,0 +1,9 @@
+-- Create session_revocations table
+CREATE TABLE IF NOT EXISTS session_revocations (
+    user_id BIGINT PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL COMMENT 'Access tokens issued at or before this time are rejected',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    CONSTRAINT fk_session_revocations_user FOREIGN KEY (user_id) 
+        REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
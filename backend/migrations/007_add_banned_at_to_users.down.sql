@@ -0,0 +1,2 @@
+-- Remove banned_at from users
+ALTER TABLE users DROP COLUMN banned_at;
\ No newline at end of file
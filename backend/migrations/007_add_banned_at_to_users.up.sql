@@ -0,0 +1,3 @@
+-- Add banned_at to users
+ALTER TABLE users
+    ADD COLUMN banned_at TIMESTAMP NULL DEFAULT NULL AFTER credits;
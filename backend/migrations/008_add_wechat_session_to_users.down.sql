@@ -0,0 +1,5 @@
+-- Remove WeChat UnionID and session key from users
+ALTER TABLE users
+    DROP INDEX idx_wechat_unionid,
+    DROP COLUMN wechat_session_key,
+    DROP COLUMN wechat_unionid;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Add WeChat UnionID and encrypted session key to users
+ALTER TABLE users
+    ADD COLUMN wechat_unionid VARCHAR(255) NULL DEFAULT NULL AFTER wechat_openid,
+    ADD COLUMN wechat_session_key VARCHAR(255) NULL DEFAULT NULL COMMENT 'AES-GCM sealed, never stored in plaintext' AFTER wechat_unionid,
+    ADD UNIQUE INDEX idx_wechat_unionid (wechat_unionid);
@@ -0,0 +1,2 @@
+-- Drop wechat_access_tokens table
+DROP TABLE IF EXISTS wechat_access_tokens;
\ No newline at end of file
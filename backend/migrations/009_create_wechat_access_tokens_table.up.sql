@@ -0,0 +1,7 @@
+-- Create wechat_access_tokens table
+CREATE TABLE IF NOT EXISTS wechat_access_tokens (
+    app_id VARCHAR(64) PRIMARY KEY,
+    access_token VARCHAR(512) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
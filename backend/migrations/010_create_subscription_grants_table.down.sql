@@ -0,0 +1,2 @@
+-- Drop subscription_grants table
+DROP TABLE IF EXISTS subscription_grants;
\ No newline at end of file
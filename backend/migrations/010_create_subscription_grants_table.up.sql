@@ -0,0 +1,13 @@
+-- Create subscription_grants table
+CREATE TABLE IF NOT EXISTS subscription_grants (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    template_id VARCHAR(128) NOT NULL COMMENT 'WeChat subscribe message template ID',
+    granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    consumed_at TIMESTAMP NULL DEFAULT NULL COMMENT 'Each one-time grant allows exactly one message',
+    
+    INDEX idx_user_template (user_id, template_id, consumed_at),
+    
+    CONSTRAINT fk_subscription_grants_user FOREIGN KEY (user_id) 
+        REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Remove deleted_at from users
+ALTER TABLE users DROP COLUMN deleted_at;
\ No newline at end of file
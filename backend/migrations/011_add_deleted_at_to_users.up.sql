@@ -0,0 +1,3 @@
+-- Add deleted_at to users
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL AFTER banned_at;
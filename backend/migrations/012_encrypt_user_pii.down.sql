@@ -0,0 +1,10 @@
+-- Remove blind indexes; run cmd/encrypt-pii -decrypt first or the narrowed columns will not fit
+ALTER TABLE users
+    DROP INDEX idx_wechat_openid_bidx,
+    DROP INDEX idx_wechat_unionid_bidx,
+    DROP COLUMN wechat_openid_bidx,
+    DROP COLUMN wechat_unionid_bidx,
+    MODIFY COLUMN wechat_openid VARCHAR(255) NOT NULL,
+    MODIFY COLUMN wechat_unionid VARCHAR(255) NULL DEFAULT NULL,
+    MODIFY COLUMN nickname VARCHAR(255),
+    MODIFY COLUMN avatar_url VARCHAR(1024);
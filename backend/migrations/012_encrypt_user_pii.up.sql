@@ -0,0 +1,10 @@
+-- Widen PII columns for ciphertext and add blind indexes for lookups
+ALTER TABLE users
+    MODIFY COLUMN wechat_openid VARCHAR(512) NOT NULL,
+    MODIFY COLUMN wechat_unionid VARCHAR(512) NULL DEFAULT NULL,
+    MODIFY COLUMN nickname VARCHAR(2048),
+    MODIFY COLUMN avatar_url VARCHAR(4096),
+    ADD COLUMN wechat_openid_bidx CHAR(64) NULL DEFAULT NULL COMMENT 'HMAC-SHA256 blind index of wechat_openid' AFTER wechat_openid,
+    ADD COLUMN wechat_unionid_bidx CHAR(64) NULL DEFAULT NULL COMMENT 'HMAC-SHA256 blind index of wechat_unionid' AFTER wechat_unionid,
+    ADD UNIQUE INDEX idx_wechat_openid_bidx (wechat_openid_bidx),
+    ADD UNIQUE INDEX idx_wechat_unionid_bidx (wechat_unionid_bidx);
@@ -0,0 +1,2 @@
+-- Drop admin_users table
+DROP TABLE IF EXISTS admin_users;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Drop admin_audit_logs table
+DROP TABLE IF EXISTS admin_audit_logs;
\ No newline at end of file
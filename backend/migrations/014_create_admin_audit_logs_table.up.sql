@@ -0,0 +1,16 @@
+-- Create admin_audit_logs table
+CREATE TABLE IF NOT EXISTS admin_audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    admin_id BIGINT NULL COMMENT 'NULL for failed logins with an unknown username',
+    action VARCHAR(128) NOT NULL COMMENT 'e.g. user.ban or POST /api/v1/admin/templates',
+    target_type VARCHAR(64) NULL DEFAULT NULL,
+    target_id VARCHAR(64) NULL DEFAULT NULL,
+    status_code INT NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    details JSON NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    INDEX idx_admin_id_created_at (admin_id, created_at),
+    INDEX idx_target (target_type, target_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- Remove ordering, soft delete and update tracking from templates
+ALTER TABLE templates
+    DROP INDEX idx_gallery,
+    DROP COLUMN sort_order,
+    DROP COLUMN updated_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,6 @@
+-- Add manual ordering, soft delete and update tracking to templates
+ALTER TABLE templates
+    ADD COLUMN sort_order INT NOT NULL DEFAULT 0 AFTER is_active,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at,
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL AFTER updated_at,
+    ADD INDEX idx_gallery (deleted_at, is_active, sort_order);
@@ -0,0 +1,2 @@
+-- Drop template_categories table
+DROP TABLE IF EXISTS template_categories;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Drop template_tags table
+DROP TABLE IF EXISTS template_tags;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Drop template_category_links table
+DROP TABLE IF EXISTS template_category_links;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- Create template_category_links table
+CREATE TABLE IF NOT EXISTS template_category_links (
+    template_id INT NOT NULL,
+    category_id INT NOT NULL,
+    
+    PRIMARY KEY (template_id, category_id),
+    INDEX idx_category_id (category_id),
+    
+    CONSTRAINT fk_template_category_links_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id) ON DELETE CASCADE,
+    CONSTRAINT fk_template_category_links_category FOREIGN KEY (category_id) 
+        REFERENCES template_categories(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Drop template_tag_links table
+DROP TABLE IF EXISTS template_tag_links;
\ No newline at end of file
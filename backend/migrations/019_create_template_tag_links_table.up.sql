@@ -0,0 +1,13 @@
+-- Create template_tag_links table
+CREATE TABLE IF NOT EXISTS template_tag_links (
+    template_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    
+    PRIMARY KEY (template_id, tag_id),
+    INDEX idx_tag_id (tag_id),
+    
+    CONSTRAINT fk_template_tag_links_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id) ON DELETE CASCADE,
+    CONSTRAINT fk_template_tag_links_tag FOREIGN KEY (tag_id) 
+        REFERENCES template_tags(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
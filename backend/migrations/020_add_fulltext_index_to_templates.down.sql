@@ -0,0 +1,2 @@
+-- Remove the template full-text index
+ALTER TABLE templates DROP INDEX ft_name_description;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Add an ngram full-text index so Chinese template names are searchable
+ALTER TABLE templates ADD FULLTEXT INDEX ft_name_description (name, description) WITH PARSER ngram;
@@ -0,0 +1,2 @@
+-- Drop template_usage_hourly table
+DROP TABLE IF EXISTS template_usage_hourly;
\ No newline at end of file
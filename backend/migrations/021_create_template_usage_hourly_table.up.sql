@@ -0,0 +1,12 @@
+-- Create template_usage_hourly table
+CREATE TABLE IF NOT EXISTS template_usage_hourly (
+    template_id INT NOT NULL,
+    hour DATETIME NOT NULL COMMENT 'Truncated to the hour, always written and compared with NOW() in SQL',
+    uses INT NOT NULL DEFAULT 0,
+    
+    PRIMARY KEY (template_id, hour),
+    INDEX idx_hour (hour),
+    
+    CONSTRAINT fk_template_usage_hourly_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Drop template_stats table
+DROP TABLE IF EXISTS template_stats;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- Create template_stats table
+CREATE TABLE IF NOT EXISTS template_stats (
+    template_id INT PRIMARY KEY,
+    uses_total INT NOT NULL DEFAULT 0 COMMENT 'Incremented on every generation',
+    uses_24h INT NOT NULL DEFAULT 0 COMMENT 'Rolled up from template_usage_hourly',
+    uses_7d INT NOT NULL DEFAULT 0 COMMENT 'Rolled up from template_usage_hourly',
+    trending_score DOUBLE NOT NULL DEFAULT 0 COMMENT 'Uses with exponential time decay',
+    refreshed_at TIMESTAMP NULL DEFAULT NULL,
+    
+    INDEX idx_uses_total (uses_total),
+    INDEX idx_trending_score (trending_score),
+    
+    CONSTRAINT fk_template_stats_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- Backfill hourly usage from generation transactions recorded before usage tracking
+INSERT IGNORE INTO template_usage_hourly (template_id, hour, uses)
+SELECT related_template_id, DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00'), COUNT(*)
+FROM transactions
+WHERE type = 'generation' AND related_template_id IS NOT NULL
+GROUP BY related_template_id, DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00');
@@ -0,0 +1,2 @@
+-- Backfilled rows cannot be told apart from tracked ones, so this is a no-op
+DO 0;
\ No newline at end of file
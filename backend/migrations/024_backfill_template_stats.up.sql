@@ -0,0 +1,6 @@
+-- Backfill total usage from generation transactions; recent counts are filled in by the first rollup
+INSERT IGNORE INTO template_stats (template_id, uses_total)
+SELECT related_template_id, COUNT(*)
+FROM transactions
+WHERE type = 'generation' AND related_template_id IS NOT NULL
+GROUP BY related_template_id;
@@ -0,0 +1,4 @@
+-- Remove the availability window from templates
+ALTER TABLE templates
+    DROP COLUMN available_until,
+    DROP COLUMN available_from;
@@ -0,0 +1,4 @@
+-- Add an optional availability window to templates for seasonal styles
+ALTER TABLE templates
+    ADD COLUMN available_from TIMESTAMP NULL DEFAULT NULL AFTER sort_order,
+    ADD COLUMN available_until TIMESTAMP NULL DEFAULT NULL AFTER available_from;
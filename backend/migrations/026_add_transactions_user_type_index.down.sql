@@ -0,0 +1,2 @@
+-- Remove the per-user daily generation index
+ALTER TABLE transactions DROP INDEX idx_user_type_created_at;
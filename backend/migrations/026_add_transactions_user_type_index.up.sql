@@ -0,0 +1,2 @@
+-- Index for counting a user's generations per day
+ALTER TABLE transactions ADD INDEX idx_user_type_created_at (user_id, type, created_at);
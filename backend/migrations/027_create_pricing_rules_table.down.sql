@@ -0,0 +1,2 @@
+-- Drop pricing_rules table
+DROP TABLE IF EXISTS pricing_rules;
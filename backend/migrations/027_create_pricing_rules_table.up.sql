@@ -0,0 +1,19 @@
+-- Create pricing_rules table
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    template_id INT NULL COMMENT 'NULL applies the rule to every template',
+    audience ENUM('everyone', 'first_generation', 'new_user', 'member') NOT NULL DEFAULT 'everyone',
+    discount_type ENUM('percent', 'fixed_price') NOT NULL,
+    value INT NOT NULL COMMENT 'Percent off, or the price in credits',
+    starts_at TIMESTAMP NULL DEFAULT NULL,
+    ends_at TIMESTAMP NULL DEFAULT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    INDEX idx_active_ends_at (is_active, ends_at),
+    
+    CONSTRAINT fk_pricing_rules_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
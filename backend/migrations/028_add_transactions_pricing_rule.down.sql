@@ -0,0 +1,4 @@
+-- Remove the pricing rule from transactions
+ALTER TABLE transactions
+    DROP INDEX idx_pricing_rule_id,
+    DROP COLUMN pricing_rule_id;
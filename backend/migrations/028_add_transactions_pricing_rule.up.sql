@@ -0,0 +1,4 @@
+-- Record the pricing rule applied to a generation; kept when the rule is deleted
+ALTER TABLE transactions
+    ADD COLUMN pricing_rule_id INT NULL DEFAULT NULL AFTER related_template_id,
+    ADD INDEX idx_pricing_rule_id (pricing_rule_id);
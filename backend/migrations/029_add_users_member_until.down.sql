@@ -0,0 +1,2 @@
+-- Remove membership expiry from users
+ALTER TABLE users DROP COLUMN member_until;
@@ -0,0 +1,2 @@
+-- Add membership expiry to users for member pricing
+ALTER TABLE users ADD COLUMN member_until TIMESTAMP NULL DEFAULT NULL AFTER credits;
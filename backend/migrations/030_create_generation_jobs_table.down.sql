@@ -0,0 +1,2 @@
+-- Drop generation_jobs table
+DROP TABLE IF EXISTS generation_jobs;
@@ -0,0 +1,26 @@
+-- Create generation_jobs table
+CREATE TABLE IF NOT EXISTS generation_jobs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id CHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    template_id INT NOT NULL,
+    parent_request_id CHAR(32) NULL COMMENT 'Job whose selfie was reused by a regenerate',
+    source_key VARCHAR(255) NOT NULL COMMENT 'Blob store key of the uploaded selfie',
+    status ENUM('queued', 'processing', 'completed', 'failed') NOT NULL DEFAULT 'queued',
+    variants JSON NOT NULL COMMENT 'Seed and outputs of each requested variant',
+    credits_per_variant INT NOT NULL COMMENT 'Quoted when the job was submitted',
+    credits_charged INT NOT NULL DEFAULT 0,
+    pricing_rule_id INT NULL,
+    error VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    
+    UNIQUE INDEX idx_request_id (request_id),
+    INDEX idx_user_created_at (user_id, created_at),
+    
+    CONSTRAINT fk_generation_jobs_user FOREIGN KEY (user_id) 
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_generation_jobs_template FOREIGN KEY (template_id) 
+        REFERENCES templates(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,17 @@
+-- Create idempotency_keys table
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    scope VARCHAR(64) NOT NULL COMMENT 'Who sent the key, e.g. user:42',
+    idempotency_key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL COMMENT 'SHA-256 of the method, route and body',
+    status_code INT NULL COMMENT 'NULL while the first request is still running',
+    content_type VARCHAR(255) NULL,
+    response_body MEDIUMBLOB NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    
+    UNIQUE INDEX idx_scope_key (scope, idempotency_key),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Drop rate_limit_buckets table
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,8 @@
+-- Create rate_limit_buckets table for rate limits shared between API instances
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key VARCHAR(191) NOT NULL PRIMARY KEY COMMENT 'policy:ip:addr or policy:user:id',
+    tokens DOUBLE NOT NULL,
+    updated_at DATETIME(6) NULL,
+    
+    INDEX idx_updated_at (updated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
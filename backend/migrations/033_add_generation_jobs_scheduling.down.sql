@@ -0,0 +1,6 @@
+-- Remove generation_jobs scheduling columns
+ALTER TABLE generation_jobs
+    DROP INDEX idx_user_started_at,
+    DROP INDEX idx_status_lane,
+    DROP COLUMN started_at,
+    DROP COLUMN lane;
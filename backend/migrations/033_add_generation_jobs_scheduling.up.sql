@@ -0,0 +1,6 @@
+-- Add lanes and start times to generation_jobs for fair scheduling
+ALTER TABLE generation_jobs
+    ADD COLUMN lane ENUM('priority', 'standard', 'trial') NOT NULL DEFAULT 'standard' AFTER status,
+    ADD COLUMN started_at TIMESTAMP NULL DEFAULT NULL AFTER updated_at,
+    ADD INDEX idx_status_lane (status, lane),
+    ADD INDEX idx_user_started_at (user_id, started_at);
@@ -0,0 +1,3 @@
+-- Remove the refund transaction type; fails while refund rows exist
+ALTER TABLE transactions
+    MODIFY COLUMN type ENUM('purchase', 'generation') NOT NULL;
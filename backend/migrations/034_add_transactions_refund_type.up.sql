@@ -0,0 +1,3 @@
+-- Allow refund transactions for generations that fail after credits were taken
+ALTER TABLE transactions
+    MODIFY COLUMN type ENUM('purchase', 'generation', 'refund') NOT NULL;
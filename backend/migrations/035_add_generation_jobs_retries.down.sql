@@ -0,0 +1,4 @@
+-- Remove generation_jobs retry columns
+ALTER TABLE generation_jobs
+    DROP COLUMN next_attempt_at,
+    DROP COLUMN attempts;
@@ -0,0 +1,4 @@
+-- Track attempts and retry backoff of generation jobs
+ALTER TABLE generation_jobs
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0 AFTER credits_charged,
+    ADD COLUMN next_attempt_at TIMESTAMP NULL DEFAULT NULL COMMENT 'A queued job waiting to be retried is not started before this' AFTER attempts;
@@ -0,0 +1,3 @@
+-- Remove the cancelled status; fails while cancelled jobs exist
+ALTER TABLE generation_jobs
+    MODIFY COLUMN status ENUM('queued', 'processing', 'completed', 'failed') NOT NULL DEFAULT 'queued';
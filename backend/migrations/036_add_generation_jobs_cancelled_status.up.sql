@@ -0,0 +1,3 @@
+-- Allow generation jobs to be cancelled by their user
+ALTER TABLE generation_jobs
+    MODIFY COLUMN status ENUM('queued', 'processing', 'completed', 'failed', 'cancelled') NOT NULL DEFAULT 'queued';
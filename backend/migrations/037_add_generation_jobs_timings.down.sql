@@ -0,0 +1,9 @@
+-- Remove generation_jobs stage timings
+ALTER TABLE generation_jobs
+    DROP INDEX idx_status_completed_at,
+    DROP COLUMN total_ms,
+    DROP COLUMN render_ms,
+    DROP COLUMN queue_wait_ms,
+    DROP COLUMN upload_ms,
+    DROP COLUMN safety_ms,
+    DROP COLUMN validation_ms;
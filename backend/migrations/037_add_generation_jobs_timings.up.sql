@@ -0,0 +1,9 @@
+-- Record how long each stage of a generation job took, in milliseconds
+ALTER TABLE generation_jobs
+    ADD COLUMN validation_ms INT NOT NULL DEFAULT 0 AFTER completed_at,
+    ADD COLUMN safety_ms INT NOT NULL DEFAULT 0 AFTER validation_ms,
+    ADD COLUMN upload_ms INT NOT NULL DEFAULT 0 AFTER safety_ms,
+    ADD COLUMN queue_wait_ms INT NOT NULL DEFAULT 0 COMMENT 'Summed over attempts' AFTER upload_ms,
+    ADD COLUMN render_ms INT NOT NULL DEFAULT 0 COMMENT 'Summed over attempts' AFTER queue_wait_ms,
+    ADD COLUMN total_ms INT NOT NULL DEFAULT 0 COMMENT 'From the request to the job finishing' AFTER render_ms,
+    ADD INDEX idx_status_completed_at (status, completed_at);
@@ -0,0 +1,4 @@
+-- Remove the unique active template name index
+ALTER TABLE templates
+    DROP INDEX idx_active_name,
+    DROP COLUMN active_name;
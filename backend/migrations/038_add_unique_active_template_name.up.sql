@@ -0,0 +1,4 @@
+-- Enforce unique names among templates that have not been deleted
+ALTER TABLE templates
+    ADD COLUMN active_name VARCHAR(255) AS (IF(deleted_at IS NULL, name, NULL)) STORED AFTER deleted_at,
+    ADD UNIQUE INDEX idx_active_name (active_name);
@@ -0,0 +1,4 @@
+-- Remove the idempotency key lease
+ALTER TABLE idempotency_keys
+    DROP COLUMN locked_until,
+    MODIFY COLUMN path VARCHAR(255) NOT NULL;
@@ -0,0 +1,4 @@
+-- Let a key held by a request that never finished be reclaimed, and keep query strings in path
+ALTER TABLE idempotency_keys
+    MODIFY COLUMN path VARCHAR(2048) NOT NULL,
+    ADD COLUMN locked_until TIMESTAMP NULL COMMENT 'Until when the first request holds the key; NULL once completed' AFTER response_body;
@@ -0,0 +1,5 @@
+-- Remove the generation_jobs lease
+ALTER TABLE generation_jobs
+    DROP INDEX idx_status_user_lane,
+    DROP INDEX idx_status_locked_until,
+    DROP COLUMN locked_until;
@@ -0,0 +1,5 @@
+-- Lease processing jobs to their worker so jobs of a dead worker can be recovered, and index claim candidates
+ALTER TABLE generation_jobs
+    ADD COLUMN locked_until TIMESTAMP NULL DEFAULT NULL COMMENT 'A processing job whose worker stops renewing this is recovered' AFTER next_attempt_at,
+    ADD INDEX idx_status_locked_until (status, locked_until),
+    ADD INDEX idx_status_user_lane (status, user_id, lane, next_attempt_at);
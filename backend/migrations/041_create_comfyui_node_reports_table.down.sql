@@ -0,0 +1,2 @@
+-- Drop comfyui_node_reports table
+DROP TABLE IF EXISTS comfyui_node_reports;
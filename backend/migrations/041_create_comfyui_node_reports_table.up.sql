@@ -0,0 +1,10 @@
+-- Create comfyui_node_reports table, where workers publish their view of the ComfyUI pool
+CREATE TABLE IF NOT EXISTS comfyui_node_reports (
+    worker VARCHAR(255) NOT NULL COMMENT 'Host and process ID of the reporting worker',
+    url VARCHAR(255) NOT NULL,
+    status JSON NOT NULL COMMENT 'The worker''s state of the node, as returned by GET /admin/comfyui/nodes',
+    reported_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    
+    PRIMARY KEY (worker, url),
+    INDEX idx_reported_at (reported_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- Store generation job times to the second again
+ALTER TABLE generation_jobs
+    MODIFY COLUMN created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    MODIFY COLUMN started_at TIMESTAMP NULL DEFAULT NULL,
+    MODIFY COLUMN next_attempt_at TIMESTAMP NULL DEFAULT NULL COMMENT 'A queued job waiting to be retried is not started before this',
+    MODIFY COLUMN completed_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,6 @@
+-- Store generation job times to the millisecond, so queue_wait_ms and total_ms are not rounded to whole seconds
+ALTER TABLE generation_jobs
+    MODIFY COLUMN created_at TIMESTAMP(3) NULL DEFAULT CURRENT_TIMESTAMP(3),
+    MODIFY COLUMN started_at TIMESTAMP(3) NULL DEFAULT NULL,
+    MODIFY COLUMN next_attempt_at TIMESTAMP(3) NULL DEFAULT NULL COMMENT 'A queued job waiting to be retried is not started before this',
+    MODIFY COLUMN completed_at TIMESTAMP(3) NULL DEFAULT NULL;
@@ -0,0 +1,3 @@
+-- Store session revocation times to the second again
+ALTER TABLE session_revocations
+    MODIFY COLUMN revoked_before TIMESTAMP NOT NULL COMMENT 'Access tokens issued at or before this time are rejected';
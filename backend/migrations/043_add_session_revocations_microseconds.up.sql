@@ -0,0 +1,3 @@
+-- Store session revocation times to the microsecond, so tokens issued within the same second as a revocation are not rejected
+ALTER TABLE session_revocations
+    MODIFY COLUMN revoked_before TIMESTAMP(6) NOT NULL COMMENT 'Access tokens issued at or before this time are rejected';
@@ -0,0 +1,136 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store persists binary objects such as uploads and generated images.
+// Keys are slash separated paths; per-user media lives under UserPrefix.
+type Store interface {
+	// Put stores an object and returns its public URL
+	Put(ctx context.Context, key string, data io.Reader, contentType string) (string, error)
+	
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	
+	// DeletePrefix removes every object whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+	
+	// URL returns the URL of an object; private objects get a signed, expiring URL
+	URL(key string) string
+}
+
+// UserPrefix returns the key prefix under which a user's media is stored
+func UserPrefix(userID int64) string {
+	return fmt.Sprintf("users/%d/", userID)
+}
+
+// Config holds blob store configuration
+type Config struct {
+	LocalDir  string
+	PublicURL string
+	// SigningKey signs URLs of private objects, which are valid for SignedURLTTL
+	SigningKey   string
+	SignedURLTTL time.Duration
+}
+
+type localStore struct {
+	dir       string
+	publicURL string
+	signer    *Signer
+}
+
+// NewLocalStore creates a Store on the local filesystem. Objects are expected to
+// be served from dir at publicURL.
+func NewLocalStore(cfg Config) (Store, error) {
+	if err := os.MkdirAll(cfg.LocalDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &localStore{dir: cfg.LocalDir, publicURL: strings.TrimRight(cfg.PublicURL, "/"), signer: NewSigner(cfg)}, nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, data io.Reader, contentType string) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return s.URL(key), nil
+}
+
+func (s *localStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if !strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("prefix %q must end with a slash", prefix)
+	}
+	p, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
+
+func (s *localStore) URL(key string) string {
+	u := s.publicURL + "/" + strings.TrimLeft(key, "/")
+	if IsPrivate(key) {
+		u += "?" + s.signer.Sign(key).Encode()
+	}
+	return u
+}
+
+// path maps a key to a file inside the storage directory
+func (s *localStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
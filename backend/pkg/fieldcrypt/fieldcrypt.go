@@ -0,0 +1,216 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// prefix marks values produced by Encrypt. Anything else is treated as
+// plaintext written before encryption was enabled.
+const prefix = "enc"
+
+const dataKeySize = 32
+
+var encoding = base64.RawURLEncoding
+
+// ErrUnknownKeyVersion is returned when a value was encrypted with a master key
+// that is not configured
+var ErrUnknownKeyVersion = errors.New("unknown master key version")
+
+// MasterKey is a versioned key-encryption key
+type MasterKey struct {
+	Version int
+	Secret  string
+}
+
+// Config holds field encryption configuration
+type Config struct {
+	MasterKeys []MasterKey
+	// CurrentVersion selects the master key used for new values
+	CurrentVersion int
+	// BlindIndexKey keys the HMAC used for equality lookups. Changing it
+	// invalidates every stored index.
+	BlindIndexKey string
+}
+
+// Cipher encrypts individual database fields using envelope encryption: every
+// value gets a fresh AES-256-GCM data key, which is wrapped by the current
+// master key and stored alongside the ciphertext as
+// enc.<version>.<wrapped data key>.<nonce || ciphertext>.
+type Cipher struct {
+	masters        map[int]cipher.AEAD
+	currentVersion int
+	indexKey       []byte
+}
+
+// New creates a Cipher. Master key secrets are stretched to 256 bits with SHA-256.
+func New(cfg Config) (*Cipher, error) {
+	if len(cfg.MasterKeys) == 0 {
+		return nil, fmt.Errorf("at least one master key is required")
+	}
+	if cfg.BlindIndexKey == "" {
+		return nil, fmt.Errorf("blind index key is required")
+	}
+
+	c := &Cipher{masters: make(map[int]cipher.AEAD), currentVersion: cfg.CurrentVersion}
+	for _, key := range cfg.MasterKeys {
+		if key.Secret == "" {
+			return nil, fmt.Errorf("master key %d: secret is required", key.Version)
+		}
+		if _, ok := c.masters[key.Version]; ok {
+			return nil, fmt.Errorf("duplicate master key version %d", key.Version)
+		}
+		derived := sha256.Sum256([]byte(key.Secret))
+		aead, err := newAEAD(derived[:])
+		if err != nil {
+			return nil, err
+		}
+		c.masters[key.Version] = aead
+	}
+	if _, ok := c.masters[c.currentVersion]; !ok {
+		return nil, fmt.Errorf("current master key version %d is not configured", c.currentVersion)
+	}
+
+	indexKey := sha256.Sum256([]byte(cfg.BlindIndexKey))
+	c.indexKey = indexKey[:]
+	return c, nil
+}
+
+// Encrypt encrypts a field value. The field name is bound to the ciphertext so
+// values cannot be moved between columns. Empty values are left empty.
+func (c *Cipher) Encrypt(plaintext, field string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(c.masters[c.currentVersion], dataKey, []byte(field))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		prefix,
+		strconv.Itoa(c.currentVersion),
+		encoding.EncodeToString(wrappedKey),
+		encoding.EncodeToString(ciphertext),
+	}, "."), nil
+}
+
+// Decrypt reverses Encrypt. Values that were never encrypted are returned unchanged.
+func (c *Cipher) Decrypt(value, field string) (string, error) {
+	version, wrappedKey, ciphertext, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+
+	master, ok := c.masters[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+	dataKey, err := open(master, wrappedKey, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, ciphertext, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencrypt reports whether a stored value is plaintext or was encrypted
+// with a master key other than the current one
+func (c *Cipher) NeedsReencrypt(value string) bool {
+	if value == "" {
+		return false
+	}
+	version, _, _, ok := parse(value)
+	return !ok || version != c.currentVersion
+}
+
+// BlindIndex returns a deterministic keyed hash of a field value for equality
+// lookups. The field name is mixed in so equal values in different columns do
+// not share an index.
+func (c *Cipher) BlindIndex(value, field string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	_, _, _, ok := parse(value)
+	return ok
+}
+
+func parse(value string) (version int, wrappedKey, ciphertext []byte, ok bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 4 || parts[0] != prefix {
+		return 0, nil, nil, false
+	}
+	version, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, nil, nil, false
+	}
+	if wrappedKey, err = encoding.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, false
+	}
+	if ciphertext, err = encoding.DecodeString(parts[3]); err != nil {
+		return 0, nil, nil, false
+	}
+	return version, wrappedKey, ciphertext, true
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
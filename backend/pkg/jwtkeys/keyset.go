@@ -0,0 +1,158 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// KeyConfig describes a single JWT key
+type KeyConfig struct {
+	ID        string
+	Algorithm string
+	Secret    string // Shared secret for HS256
+	File      string // PEM file for RS256 and EdDSA, either a private or a public key
+}
+
+// Key is a loaded JWT key. Keys loaded from a public key file can only verify.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySet holds every key accepted for verification and the one key used for signing
+type KeySet struct {
+	keys    map[string]*Key
+	methods []string
+	signing *Key
+}
+
+// NewKeySet loads the configured keys and selects the signing key
+func NewKeySet(configs []KeyConfig, signingKeyID string) (*KeySet, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("at least one JWT key is required")
+	}
+
+	ks := &KeySet{keys: make(map[string]*Key, len(configs))}
+	seenMethods := make(map[string]bool)
+	for _, cfg := range configs {
+		if _, ok := ks.keys[cfg.ID]; ok {
+			return nil, fmt.Errorf("duplicate JWT key id %q", cfg.ID)
+		}
+		key, err := loadKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %q: %w", cfg.ID, err)
+		}
+		ks.keys[cfg.ID] = key
+		if !seenMethods[key.Method.Alg()] {
+			seenMethods[key.Method.Alg()] = true
+			ks.methods = append(ks.methods, key.Method.Alg())
+		}
+	}
+
+	signing, ok := ks.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKeyID)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+// Sign signs the claims with the signing key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.signKey)
+}
+
+// Keyfunc resolves the verification key from the token's kid header
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, fmt.Errorf("token has no kid header")
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	// A key is only valid for the algorithm it was configured with
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for key %q", token.Header["alg"], kid)
+	}
+	return key.verifyKey, nil
+}
+
+// Methods returns the algorithms of all configured keys
+func (ks *KeySet) Methods() []string {
+	return ks.methods
+}
+
+func loadKey(cfg KeyConfig) (*Key, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("key id is required")
+	}
+
+	switch cfg.Algorithm {
+	case AlgorithmHS256:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("HS256 keys require a secret")
+		}
+		secret := []byte(cfg.Secret)
+		return &Key{ID: cfg.ID, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+
+	case AlgorithmRS256:
+		pem, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			return &Key{ID: cfg.ID, Method: jwt.SigningMethodRS256, signKey: private, verifyKey: &private.PublicKey}, nil
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("file is neither an RSA private nor public key")
+		}
+		return &Key{ID: cfg.ID, Method: jwt.SigningMethodRS256, verifyKey: public}, nil
+
+	case AlgorithmEdDSA:
+		pem, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			signer, ok := private.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("unsupported EdDSA private key type %T", private)
+			}
+			return &Key{ID: cfg.ID, Method: jwt.SigningMethodEdDSA, signKey: signer, verifyKey: signer.Public()}, nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("file is neither an Ed25519 private nor public key")
+		}
+		return &Key{ID: cfg.ID, Method: jwt.SigningMethodEdDSA, verifyKey: public}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops buckets that have refilled
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	bucket
+	limit Limit
+}
+
+type memoryStore struct {
+	buckets   map[string]*memoryEntry
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// NewMemoryStore creates a Store that keeps buckets in process memory. Limits
+// are per instance, so with N instances a client gets up to N times the limit.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.buckets[key] = entry
+	}
+	var result Result
+	entry.bucket, result = take(entry.bucket, limit, now)
+	entry.limit = limit
+	return result, nil
+}
+
+// sweep drops full buckets; a missing bucket starts full, so nothing is lost
+func (s *memoryStore) sweep(now time.Time) {
+	for key, entry := range s.buckets {
+		if entry.full(entry.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MySQLStore keeps buckets in the rate_limit_buckets table so every API
+// instance shares the same limits
+type MySQLStore struct {
+	db *sql.DB
+}
+
+// NewMySQLStore creates a Store backed by MySQL
+func NewMySQLStore(db *sql.DB) *MySQLStore {
+	return &MySQLStore{db: db}
+}
+
+// Take locks the key's row for the duration of the update. Time is taken from
+// the database so instances with skewed clocks agree.
+func (s *MySQLStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	// A new bucket starts full; updated_at NULL marks it as never used
+	query := `INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at) VALUES (?, 0, NULL)
+		ON DUPLICATE KEY UPDATE bucket_key = bucket_key`
+	if _, err := tx.ExecContext(ctx, query, key); err != nil {
+		return Result{}, err
+	}
+
+	var b bucket
+	var updatedAt sql.NullTime
+	var now time.Time
+	query = "SELECT tokens, updated_at, NOW(6) FROM rate_limit_buckets WHERE bucket_key = ? FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, query, key).Scan(&b.tokens, &updatedAt, &now); err != nil {
+		return Result{}, err
+	}
+	if updatedAt.Valid {
+		b.updatedAt = updatedAt.Time
+	}
+
+	b, result := take(b, limit, now)
+	query = "UPDATE rate_limit_buckets SET tokens = ?, updated_at = ? WHERE bucket_key = ?"
+	if _, err := tx.ExecContext(ctx, query, b.tokens, b.updatedAt, key); err != nil {
+		return Result{}, err
+	}
+	return result, tx.Commit()
+}
+
+// PurgeIdle deletes buckets unused for longer than idle. With idle at least as
+// long as the longest policy period, every deleted bucket had refilled anyway.
+func (s *MySQLStore) PurgeIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	query := "DELETE FROM rate_limit_buckets WHERE updated_at IS NULL OR updated_at < NOW(6) - INTERVAL ? SECOND"
+	result, err := s.db.ExecContext(ctx, query, int64(idle.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,127 @@
+// Package ratelimit implements token bucket rate limiting. Buckets are kept in
+// a Store, either in memory for a single instance or in MySQL when several API
+// instances share limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key types select what a policy counts requests by
+const (
+	KeyByIP   = "ip"
+	KeyByUser = "user"
+)
+
+// Limit allows Burst requests at once, refilled evenly over Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// IsZero reports whether the limit is unset, which disables limiting
+func (l Limit) IsZero() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+// rate returns how many tokens are added per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// String formats the limit as burst/period, e.g. "10/1m0s"
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// ParseLimit parses a limit written as burst/period, e.g. "10/1m". An empty
+// string or "0" is a zero limit.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected burst/period", value)
+	}
+	burst, err := strconv.Atoi(parts[0])
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, burst must be a positive number", value)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, period must be a positive duration", value)
+	}
+	return Limit{Burst: burst, Period: period}, nil
+}
+
+// Policy is a named limit applied to a group of routes
+type Policy struct {
+	// Name separates the buckets of different policies
+	Name string
+	// KeyBy is KeyByIP or KeyByUser; user policies fall back to the IP for anonymous requests
+	KeyBy string
+	Limit Limit
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed; zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from buckets
+type Store interface {
+	// Take removes one token from the bucket for key, if there is one
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state of one key
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills a bucket up to now and removes one token from it if possible
+func take(b bucket, limit Limit, now time.Time) (bucket, Result) {
+	rate := limit.rate()
+	burst := float64(limit.Burst)
+
+	if b.updatedAt.IsZero() {
+		b.tokens = burst
+	} else if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+	}
+	b.updatedAt = now
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+	return b, result
+}
+
+// full reports whether a bucket has refilled completely by now, so it can be forgotten
+func (b bucket) full(limit Limit, now time.Time) bool {
+	return b.tokens+now.Sub(b.updatedAt).Seconds()*limit.rate() >= float64(limit.Burst)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTakeBurst(t *testing.T) {
+	// One token per second
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+
+	var b bucket
+	for i := 0; i < 3; i++ {
+		var result Result
+		b, result = take(b, limit, start)
+		if !result.Allowed {
+			t.Fatalf("request %d was not allowed", i+1)
+		}
+		if want := 2 - i; result.Remaining != want {
+			t.Errorf("request %d: Remaining = %d, want %d", i+1, result.Remaining, want)
+		}
+		if want := time.Duration(i+1) * time.Second; result.Reset != want {
+			t.Errorf("request %d: Reset = %s, want %s", i+1, result.Reset, want)
+		}
+		if result.RetryAfter != 0 {
+			t.Errorf("request %d: RetryAfter = %s, want 0", i+1, result.RetryAfter)
+		}
+	}
+
+	b, result := take(b, limit, start)
+	if result.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	if result.Limit != 3 || result.Remaining != 0 {
+		t.Errorf("Limit, Remaining = %d, %d, want 3, 0", result.Limit, result.Remaining)
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %s, want 1s", result.RetryAfter)
+	}
+	if result.Reset != 3*time.Second {
+		t.Errorf("Reset = %s, want 3s", result.Reset)
+	}
+	if b.tokens != 0 {
+		t.Errorf("a denied request took a token: tokens = %v", b.tokens)
+	}
+}
+
+func TestTakeRefill(t *testing.T) {
+	limit := Limit{Burst: 4, Period: 2 * time.Second}
+	empty := bucket{tokens: 0, updatedAt: start}
+
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{name: "nothing refilled", elapsed: 0, retryAfter: 500 * time.Millisecond, reset: 2 * time.Second},
+		{name: "part of a token", elapsed: 200 * time.Millisecond, retryAfter: 300 * time.Millisecond, reset: 1800 * time.Millisecond},
+		{name: "one token", elapsed: 500 * time.Millisecond, allowed: true, remaining: 0, reset: 2 * time.Second},
+		{name: "several tokens", elapsed: time.Second, allowed: true, remaining: 1, reset: 1500 * time.Millisecond},
+		{name: "refill stops at the burst", elapsed: time.Hour, allowed: true, remaining: 3, reset: 500 * time.Millisecond},
+		{name: "clock going backwards refills nothing", elapsed: -time.Second, retryAfter: 500 * time.Millisecond, reset: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start.Add(tt.elapsed)
+			b, result := take(empty, limit, now)
+			if result.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v", result.Allowed, tt.allowed)
+			}
+			if result.Remaining != tt.remaining {
+				t.Errorf("Remaining = %d, want %d", result.Remaining, tt.remaining)
+			}
+			if !closeTo(result.RetryAfter, tt.retryAfter) {
+				t.Errorf("RetryAfter = %s, want %s", result.RetryAfter, tt.retryAfter)
+			}
+			if !closeTo(result.Reset, tt.reset) {
+				t.Errorf("Reset = %s, want %s", result.Reset, tt.reset)
+			}
+			if !b.updatedAt.Equal(now) {
+				t.Errorf("updatedAt = %s, want %s", b.updatedAt, now)
+			}
+		})
+	}
+}
+
+func TestTakeAfterRetryAfter(t *testing.T) {
+	limit := Limit{Burst: 10, Period: time.Minute}
+	b := bucket{tokens: 0.25, updatedAt: start}
+
+	b, result := take(b, limit, start)
+	if result.Allowed {
+		t.Fatal("request was allowed without a full token")
+	}
+	// 0.75 tokens at 1 token per 6s
+	if !closeTo(result.RetryAfter, 4500*time.Millisecond) {
+		t.Fatalf("RetryAfter = %s, want 4.5s", result.RetryAfter)
+	}
+
+	if _, result := take(b, limit, start.Add(result.RetryAfter)); !result.Allowed {
+		t.Error("request after RetryAfter was not allowed")
+	}
+}
+
+func TestBucketFull(t *testing.T) {
+	limit := Limit{Burst: 2, Period: 2 * time.Second}
+	b := bucket{tokens: 0.5, updatedAt: start}
+	if b.full(limit, start.Add(time.Second)) {
+		t.Error("bucket is full before it refilled")
+	}
+	if !b.full(limit, start.Add(1500*time.Millisecond)) {
+		t.Error("bucket is not full after it refilled")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "10/1m", want: Limit{Burst: 10, Period: time.Minute}},
+		{value: " 5/30s ", want: Limit{Burst: 5, Period: 30 * time.Second}},
+		{value: "", want: Limit{}},
+		{value: "0", want: Limit{}},
+		{value: "10", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "10/0s", wantErr: true},
+		{value: "10/soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// closeTo allows for floating point error in durations computed from rates
+func closeTo(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Microsecond && diff < time.Microsecond
+}
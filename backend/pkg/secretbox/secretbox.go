@@ -0,0 +1,56 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Box seals small secrets with AES-256-GCM
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a Box whose key is derived from the given secret
+func New(secret string) (*Box, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("secret is required")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext and returns base64(nonce || ciphertext)
+func (b *Box) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func (b *Box) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed value: %w", err)
+	}
+	if len(data) < b.aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	return b.aead.Open(nil, nonce, ciphertext, nil)
+}
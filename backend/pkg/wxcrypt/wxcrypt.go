@@ -0,0 +1,57 @@
+// Package wxcrypt implements WeChat mini program encrypted data handling.
+// See https://developers.weixin.qq.com/miniprogram/dev/framework/open-ability/signature.html
+package wxcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// VerifySignature checks signature == sha1(rawData + sessionKey)
+func VerifySignature(rawData, sessionKey, signature string) bool {
+	sum := sha1.Sum([]byte(rawData + sessionKey))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) == 1
+}
+
+// Decrypt decrypts encryptedData with AES-128-CBC using the base64 encoded session key and iv
+func Decrypt(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != 16 {
+		return nil, fmt.Errorf("invalid session key")
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted data")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, ciphertext)
+
+	return unpad(plaintext)
+}
+
+func unpad(data []byte) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	if !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return data[:len(data)-padding], nil
+}